@@ -10,8 +10,7 @@ import (
 	"snapsec-agent/internal/cpulimit"
 	"snapsec-agent/internal/service"
 	"snapsec-agent/internal/vulnscan"
-	"snapsec-agent/internal/vulnscan/nuclei"
-	"snapsec-agent/internal/vulnscan/trivy"
+	"snapsec-agent/internal/vulnscan/plugins"
 	"snapsec-agent/pkg/api"
 )
 
@@ -40,6 +39,7 @@ func main() {
 		pluginCfg := vulnscan.PluginConfig{
 			BinDir:      "./bin",
 			TemplateDir: "./templates",
+			CacheDir:    "./cache",
 		}
 
 		manager := vulnscan.NewScanManager(pluginCfg, func(findings []vulnscan.NormalizedFinding) {
@@ -63,12 +63,19 @@ func main() {
 			}
 		})
 
-		if err := manager.RegisterPlugin("nuclei", &nuclei.NucleiScanner{}); err != nil {
-			log.Fatalf("Failed to initialize nuclei plugin: %v", err)
+		if err := plugins.Apply(manager, cfg.Scanners); err != nil {
+			log.Printf("Warning: %v", err)
 		}
 
-		if err := manager.RegisterPlugin("trivy", &trivy.TrivyScanner{}); err != nil {
-			log.Fatalf("Failed to initialize trivy plugin: %v", err)
+		// An explicitly requested tool runs even if the config does not enable it.
+		if *toolFlag != "" && !manager.HasPlugin(*toolFlag) {
+			plugin, err := plugins.New(*toolFlag)
+			if err != nil {
+				log.Fatalf("Failed to initialize %s plugin: %v", *toolFlag, err)
+			}
+			if err := manager.RegisterPlugin(*toolFlag, plugin); err != nil {
+				log.Fatalf("Failed to initialize %s plugin: %v", *toolFlag, err)
+			}
 		}
 
 		manager.RunCLI(*toolFlag, *targetFlag, *resumeFlag)
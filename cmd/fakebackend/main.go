@@ -0,0 +1,97 @@
+// Command fakebackend is a minimal stand-in for the AIM backend, for
+// exercising the agent locally. It accepts every agent endpoint, logs a
+// one-line summary of each request and optionally saves the bodies.
+//
+//	go run ./cmd/fakebackend -addr :8080 -dump ./fake-backend
+//
+// Point the agent's backend_url at http://localhost:8080.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "Address to listen on")
+	dump := flag.String("dump", "", "Directory to save request bodies to")
+	flag.Parse()
+
+	if *dump != "" {
+		if err := os.MkdirAll(*dump, 0755); err != nil {
+			log.Fatalf("Failed to create dump directory: %v", err)
+		}
+	}
+
+	var seq uint64
+	handle := func(endpoint string, reply interface{}) {
+		http.HandleFunc(endpoint, func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Printf("%s %s", endpoint, summarize(body))
+
+			if *dump != "" {
+				n := atomic.AddUint64(&seq, 1)
+				name := fmt.Sprintf("%s-%06d%s.json", time.Now().Format("150405"), n, filepath.Base(endpoint))
+				if err := os.WriteFile(filepath.Join(*dump, name), body, 0644); err != nil {
+					log.Printf("Failed to save request: %v", err)
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(reply)
+		})
+	}
+
+	configuration := map[string]interface{}{"configuration": map[string]interface{}{}}
+	handle("/register", map[string]string{"agent_id": "fake-agent"})
+	handle("/heartbeat", configuration)
+	handle("/results", configuration)
+	handle("/vulnerabilities", configuration)
+	handle("/scan-results", map[string]string{})
+	handle("/events", map[string]string{})
+
+	log.Printf("Fake backend listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// summarize describes a request body: the agent ID, how many items "data"
+// holds (or its top-level keys), and the dropped-events count if any.
+func summarize(body []byte) string {
+	var req struct {
+		AgentID string          `json:"agent_id"`
+		Data    json.RawMessage `json:"data"`
+		Dropped uint64          `json:"dropped"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return fmt.Sprintf("(%d bytes, not JSON)", len(body))
+	}
+
+	s := fmt.Sprintf("agent=%s bytes=%d", req.AgentID, len(body))
+	var list []json.RawMessage
+	var object map[string]json.RawMessage
+	switch {
+	case json.Unmarshal(req.Data, &list) == nil:
+		s += fmt.Sprintf(" items=%d", len(list))
+		if len(list) > 0 {
+			s += " first=" + string(list[0])
+		}
+	case json.Unmarshal(req.Data, &object) == nil:
+		s += fmt.Sprintf(" keys=%d", len(object))
+	}
+	if req.Dropped > 0 {
+		s += fmt.Sprintf(" dropped=%d", req.Dropped)
+	}
+	return s
+}
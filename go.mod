@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/kardianos/service v1.2.4
 	github.com/shirou/gopsutil/v3 v3.24.5
+	golang.org/x/sys v0.34.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -16,5 +17,4 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	golang.org/x/sys v0.34.0 // indirect
 )
@@ -5,24 +5,32 @@ import (
 	"log"
 	"os"
 	"snapsec-agent/internal/config"
+	"snapsec-agent/internal/events"
 	"snapsec-agent/internal/modules"
 	"snapsec-agent/internal/modules/devices"
+	"snapsec-agent/internal/modules/eol"
 	"snapsec-agent/internal/modules/hardware"
 	"snapsec-agent/internal/modules/host"
+	"snapsec-agent/internal/modules/logins"
 	"snapsec-agent/internal/modules/network"
 	"snapsec-agent/internal/modules/packages"
+	"snapsec-agent/internal/modules/persistence"
+	"snapsec-agent/internal/modules/ports"
 	"snapsec-agent/internal/modules/processes"
 	"snapsec-agent/internal/modules/security"
 	"snapsec-agent/internal/modules/services"
 	"snapsec-agent/internal/modules/users"
 	"snapsec-agent/internal/modules/classification"
+	"snapsec-agent/internal/modules/containers"
 	"snapsec-agent/pkg/api"
 	"time"
 	"runtime"
 	"snapsec-agent/internal/updater"
 	"snapsec-agent/internal/vulnscan"
-	"snapsec-agent/internal/vulnscan/nuclei"
+	"snapsec-agent/internal/vulnscan/plugins"
+	"reflect"
 	"encoding/json"
+	"path/filepath"
 )
 
 type Agent struct {
@@ -31,12 +39,23 @@ type Agent struct {
 	api         *api.Client
 	modules     []modules.Module
 	stop          chan struct{}
+	cpeMappings   string // backend CPE mapping table, see packages.SaveCPEMappings
+	eolDataset    string // backend EOL dataset, see eol.SaveDataset
 	scanManager   *vulnscan.ScanManager
+	events        *events.Collector
 	KillHandler   func()
 	UpdateHandler func() error
 }
 
 func NewAgent(cfg *config.Config, configPath string) *Agent {
+	pluginCfg := vulnscan.PluginConfig{
+		BinDir:      "./bin",
+		TemplateDir: "./templates",
+		CacheDir:    "./cache",
+	}
+	cpeMappings := filepath.Join(pluginCfg.CacheDir, "cpe-mappings.json")
+	eolDataset := filepath.Join(pluginCfg.CacheDir, eol.DatasetFile)
+
 	agent := &Agent{
 		cfg:        cfg,
 		configPath: configPath,
@@ -45,23 +64,25 @@ func NewAgent(cfg *config.Config, configPath string) *Agent {
 			&host.HostModule{},
 			&hardware.HardwareModule{},
 			&network.NetworkModule{},
-			&processes.ProcessesModule{},
-			&packages.PackagesModule{},
+			&processes.ProcessesModule{RedactPatterns: cfg.CmdlineRedact},
+			&packages.PackagesModule{Roots: cfg.PackageRoots, BinaryPaths: cfg.BinaryPaths, CPEMappingsPath: cpeMappings},
 			&services.ServicesModule{},
 			&devices.DevicesModule{},
 			&users.UsersModule{},
 			&security.SecurityModule{},
 			&classification.ClassificationModule{},
+			&containers.ContainersModule{},
+			&ports.PortsModule{},
+			&persistence.PersistenceModule{},
+			&logins.LoginsModule{},
+			&eol.EOLModule{DatasetPath: eolDataset},
 		},
-		stop: make(chan struct{}),
+		stop:        make(chan struct{}),
+		cpeMappings: cpeMappings,
+		eolDataset:  eolDataset,
 	}
 	
 	// Initialize VulnScanManager
-	pluginCfg := vulnscan.PluginConfig{
-		BinDir:      "./bin",
-		TemplateDir: "./templates",
-	}
-	
 	agent.scanManager = vulnscan.NewScanManager(pluginCfg, func(findings []vulnscan.NormalizedFinding) {
 		if len(findings) > 0 {
 			if _, err := agent.api.SendVulnerabilities(agent.cfg.AgentID, findings); err != nil {
@@ -70,13 +91,37 @@ func NewAgent(cfg *config.Config, configPath string) *Agent {
 		}
 	})
 	
-	// Register Nuclei
-	if err := agent.scanManager.RegisterPlugin("nuclei", &nuclei.NucleiScanner{}); err != nil {
-		log.Printf("Failed to initialize nuclei plugin: %v", err)
-	}
-	
+	agent.scanManager.SetJobReporter(func(result vulnscan.ScanResult) {
+		if err := agent.api.SendScanResult(agent.cfg.AgentID, result); err != nil {
+			log.Printf("Failed to report scan job %s: %v", result.JobID, err)
+		}
+	})
 	agent.scanManager.SetScanInterval(cfg.VulnScanInterval)
 	agent.scanManager.UpdateTargets(cfg.IncludeDirs, cfg.ExcludeDirs)
+
+	// Failures are kept in the plugin status, which is pushed with the inventory.
+	if err := plugins.Apply(agent.scanManager, cfg.Scanners); err != nil {
+		log.Printf("Some scanner plugins are unavailable: %v", err)
+	}
+
+	// Event collectors stream to /events between asset pushes.
+	agent.events = events.NewCollector(events.Config{
+		BufferSize:   cfg.Events.BufferSize,
+		PushInterval: time.Duration(cfg.Events.PushInterval) * time.Second,
+		SpoolPath:    filepath.Join(pluginCfg.CacheDir, "events.json"),
+	}, func(batch []events.Event, dropped uint64) error {
+		return agent.api.SendEvents(agent.cfg.AgentID, batch, dropped)
+	})
+	if cfg.Events.Process && runtime.GOOS == "linux" {
+		redactor := processes.NewRedactor(cfg.CmdlineRedact)
+		agent.events.AddSource(&events.ProcConnector{Redactor: redactor}, &events.ProcPoller{Redactor: redactor})
+	}
+	if cfg.Events.Auth && runtime.GOOS == "linux" {
+		agent.events.AddSource(
+			&events.AuthLog{CheckpointPath: filepath.Join(pluginCfg.CacheDir, "auth-log.json")},
+			&events.AuthJournal{CheckpointPath: filepath.Join(pluginCfg.CacheDir, "auth-journal.json")},
+		)
+	}
 	return agent
 }
 
@@ -141,7 +186,7 @@ func (a *Agent) RegisterOnly() error {
 		inventory = nil
 	}
 
-	agentID, err := a.api.Register(hostname, osName, config.Version, ipAddress, architecture, arch, inventory)
+	agentID, err := a.api.Register(hostname, osName, config.Version, ipAddress, architecture, arch, inventory, a.scanManager.Schemas())
 	if err != nil {
 		return err
 	}
@@ -168,6 +213,7 @@ func (a *Agent) Start() error {
 	}
 
 	a.scanManager.Start()
+	a.events.Start()
 
 	// 2. Start Heartbeat and Results Reporting Loops
 	hbTicker := time.NewTicker(time.Duration(a.cfg.HeartbeatInterval) * time.Second)
@@ -258,6 +304,7 @@ func (a *Agent) Start() error {
 func (a *Agent) Stop() {
 	close(a.stop)
 	a.scanManager.Stop()
+	a.events.Stop()
 }
 
 func (a *Agent) syncConfiguration(resp *api.ResultsResponse) bool {
@@ -292,6 +339,62 @@ func (a *Agent) syncConfiguration(resp *api.ResultsResponse) bool {
 		}
 	}
 
+	// Update enabled scanner plugins and their scan profiles if they differ
+	if resp.Configuration.Scanners != nil {
+		var scanners []config.ScannerConfig
+		b, err := json.Marshal(resp.Configuration.Scanners)
+		if err == nil {
+			err = json.Unmarshal(b, &scanners)
+		}
+		if err != nil {
+			log.Printf("Ignoring invalid scanner configuration: %v", err)
+		} else if !reflect.DeepEqual(a.cfg.Scanners, scanners) {
+			a.cfg.Scanners = scanners
+			if err := plugins.Apply(a.scanManager, a.cfg.Scanners); err != nil {
+				log.Printf("Some scanner plugins are unavailable: %v", err)
+			}
+			changed = true
+		}
+	}
+
+	// Sync backend-managed template bundles. A nil list means the backend did
+	// not send any, while an empty list asks us to drop all stored bundles.
+	if resp.Configuration.TemplateBundles != nil {
+		var bundles []vulnscan.TemplateBundle
+		for _, rawBundle := range resp.Configuration.TemplateBundles {
+			b, err := json.Marshal(rawBundle)
+			if err == nil {
+				var bundle vulnscan.TemplateBundle
+				if err := json.Unmarshal(b, &bundle); err == nil {
+					bundles = append(bundles, bundle)
+				}
+			}
+		}
+		a.scanManager.SyncTemplates(bundles)
+	}
+
+	// The backend's CPE mapping table takes effect at the next inventory.
+	if resp.Configuration.CPEMappings != nil {
+		b, err := json.Marshal(resp.Configuration.CPEMappings)
+		if err == nil {
+			err = packages.SaveCPEMappings(a.cpeMappings, b)
+		}
+		if err != nil {
+			log.Printf("Ignoring CPE mappings: %v", err)
+		}
+	}
+
+	// Likewise the EOL dataset, used by the eol module and scanner plugin.
+	if resp.Configuration.EOLDataset != nil {
+		b, err := json.Marshal(resp.Configuration.EOLDataset)
+		if err == nil {
+			err = eol.SaveDataset(a.eolDataset, b)
+		}
+		if err != nil {
+			log.Printf("Ignoring EOL dataset: %v", err)
+		}
+	}
+
 	// Trigger manual scan jobs if any
 	if len(resp.Configuration.ScanJobs) > 0 {
 		var jobs []vulnscan.ScanJob
@@ -363,6 +466,9 @@ func (a *Agent) gatherAll() (map[string]interface{}, error) {
 		"version": config.Version,
 	}
 
+	// Scanner plugin status, so hosts whose scanners failed to start are visible
+	payload["scanners"] = a.scanManager.PluginStatuses()
+
 	for _, m := range a.modules {
 		data, err := m.Gather()
 		if err != nil {
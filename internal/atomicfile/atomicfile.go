@@ -0,0 +1,35 @@
+package atomicfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path with data, readable by the owner only. The
+// data goes to a temporary file that is then renamed over path, so a crash
+// leaves either the old or the new content. Missing parent directories are
+// created.
+func Write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// WriteIfChanged is Write, skipped when path already holds data.
+func WriteIfChanged(path string, data []byte) error {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+	return Write(path, data)
+}
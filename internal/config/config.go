@@ -10,14 +10,68 @@ import (
 var Version = "dev" // Overridden by ldflags during build
 
 type Config struct {
-	BackendURL        string `yaml:"backend_url"`
-	APIKey            string `yaml:"api_key"`
-	AgentID           string `yaml:"agent_id,omitempty"`
-	HeartbeatInterval int    `yaml:"heartbeat_interval"` // in seconds
-	AssetPushInterval int      `yaml:"asset_push_interval"` // in seconds
-	VulnScanInterval  int      `yaml:"vuln_scan_interval"` // in seconds
-	IncludeDirs       []string `yaml:"include_dirs,omitempty"`
-	ExcludeDirs       []string `yaml:"exclude_dirs,omitempty"`
+	BackendURL        string          `yaml:"backend_url"`
+	APIKey            string          `yaml:"api_key"`
+	AgentID           string          `yaml:"agent_id,omitempty"`
+	HeartbeatInterval int             `yaml:"heartbeat_interval"`  // in seconds
+	AssetPushInterval int             `yaml:"asset_push_interval"` // in seconds
+	VulnScanInterval  int             `yaml:"vuln_scan_interval"`  // in seconds
+	IncludeDirs       []string        `yaml:"include_dirs,omitempty"`
+	ExcludeDirs       []string        `yaml:"exclude_dirs,omitempty"`
+	Scanners          []ScannerConfig `yaml:"scanners,omitempty"`
+	PackageRoots      []string        `yaml:"package_roots,omitempty"`  // walked for application dependencies
+	BinaryPaths       []string        `yaml:"binary_paths,omitempty"`   // searched for Go executables
+	CmdlineRedact     []string        `yaml:"cmdline_redact,omitempty"` // extra secret-name patterns for process command lines
+	Events            EventsConfig    `yaml:"events,omitempty"`
+}
+
+// EventsConfig enables the event collectors, which stream host events to the
+// backend between inventory pushes.
+type EventsConfig struct {
+	Process      bool `yaml:"process,omitempty"`       // process exec/exit (Linux)
+	Auth         bool `yaml:"auth,omitempty"`          // logins, sudo, su and account changes from the auth log or journal (Linux)
+	BufferSize   int  `yaml:"buffer_size,omitempty"`   // events held while the backend is unreachable
+	PushInterval int  `yaml:"push_interval,omitempty"` // in seconds
+}
+
+// ScannerConfig enables a vulnerability scanner plugin and describes the scans
+// it runs on the schedule. Options apply to every job of the plugin, including
+// jobs pushed by the backend. The same shape is accepted from the backend's
+// AgentConfiguration, hence the json tags.
+type ScannerConfig struct {
+	Name     string            `yaml:"name" json:"name"`
+	Enabled  bool              `yaml:"enabled" json:"enabled"`
+	Options  map[string]string `yaml:"options,omitempty" json:"options,omitempty"`
+	Profiles []ScanProfile     `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+}
+
+// ScanProfile is one scheduled scan of a plugin. Empty Targets fall back to
+// include_dirs and a zero Interval falls back to vuln_scan_interval.
+type ScanProfile struct {
+	Name     string            `yaml:"name" json:"name"`
+	Mode     string            `yaml:"mode,omitempty" json:"mode,omitempty"`
+	Tags     string            `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Targets  []string          `yaml:"targets,omitempty" json:"targets,omitempty"`
+	Interval int               `yaml:"interval,omitempty" json:"interval,omitempty"` // in seconds
+	Options  map[string]string `yaml:"options,omitempty" json:"options,omitempty"`
+}
+
+// DefaultScanners is used when the config file has no scanners section. It
+// keeps what the agent ran before scanners were configurable: a nuclei scan of
+// local files for secrets and misconfigurations. Trivy, java and eol are
+// opt-in through the scanners section or the backend configuration.
+func DefaultScanners() []ScannerConfig {
+	return []ScannerConfig{
+		{
+			Name:    "nuclei",
+			Enabled: true,
+			Profiles: []ScanProfile{{
+				Name:    "local-files",
+				Tags:    "secrets,keys,tokens,credentials,misconfiguration",
+				Options: map[string]string{"protocol": "file"},
+			}},
+		},
+	}
 }
 
 func GetDefaultConfigPath() string {
@@ -50,6 +104,10 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.VulnScanInterval = 86400 // Default to 24 hours
 	}
 
+	if cfg.Scanners == nil {
+		cfg.Scanners = DefaultScanners()
+	}
+
 	return &cfg, nil
 }
 
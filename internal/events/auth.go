@@ -0,0 +1,179 @@
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Authentication events parsed from syslog's auth facility, either the
+// /var/log/auth.log (Debian) or /var/log/secure (RHEL) file or the journal.
+
+// AuthEvent describes an ssh_login, sudo, su, login or account_change
+// event. User is the account that authenticated or acted; TargetUser the
+// account it became (sudo, su) or changed (account_change, where User is
+// left empty).
+type AuthEvent struct {
+	Program    string `json:"program"`
+	PID        int32  `json:"pid,omitempty"`
+	User       string `json:"user,omitempty"`
+	TargetUser string `json:"target_user,omitempty"`
+	SourceIP   string `json:"source_ip,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	Method     string `json:"method,omitempty"` // ssh: password, publickey, keyboard-interactive/pam, ...
+	Success    bool   `json:"success"`
+	TTY        string `json:"tty,omitempty"`
+	Command    string `json:"command,omitempty"` // sudo
+	Action     string `json:"action,omitempty"`  // account_change: user_add, user_delete, user_modify, group_add, group_delete, group_modify, password_change
+	Message    string `json:"message"`
+}
+
+var (
+	sshAccepted = regexp.MustCompile(`^Accepted (\S+) for (\S+) from (\S+) port (\d+)`)
+	sshFailed   = regexp.MustCompile(`^Failed (\S+) for (?:invalid user )?(.*?) from (\S+) port (\d+)`)
+
+	// alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/bin/ls
+	// alice : 3 incorrect password attempts ; TTY=pts/0 ; ... ; COMMAND=/bin/ls
+	sudoCommand = regexp.MustCompile(`^(\S+) : (?:(.*?) ; )?TTY=(\S+) ; PWD=.*? ; USER=(\S+) ; (?:.*? ; )?COMMAND=(.*)$`)
+
+	suSuccess    = regexp.MustCompile(`^\(to (\S+)\) (\S+) on (\S+)`)
+	suSuccessOld = regexp.MustCompile(`^Successful su for (\S+) by (\S+)`)
+	suFailed     = regexp.MustCompile(`^FAILED SU \(to (\S+)\) (\S+) on (\S+)`)
+
+	loginSuccess = regexp.MustCompile(`^(?:ROOT )?LOGIN ON (\S+) BY (\S+)(?: FROM (\S+))?`)
+	loginRoot    = regexp.MustCompile(`^ROOT LOGIN\s+(?:ON|on) '(\S+)'(?: FROM '(\S*)')?`)
+	loginFailed  = regexp.MustCompile(`^FAILED LOGIN \(\d+\) on '(\S+)'(?: FROM '(\S*)')? FOR '(.*?)'`)
+
+	accountNew      = regexp.MustCompile(`^new (user|group): name=([^,]+)`)
+	accountGroupAdd = regexp.MustCompile(`^group added to \S+: name=([^,]+)`)
+	accountDelete   = regexp.MustCompile(`^delete user '([^']+)'`)
+	accountGroupDel = regexp.MustCompile(`^(?:group '([^']+)' removed$|removed group '([^']+)')`)
+	accountQuoted   = regexp.MustCompile(`'([^']+)'`)
+	passwdChanged   = regexp.MustCompile(`password changed for (\S+)`)
+)
+
+// parseAuthMessage turns one syslog message into an event, or returns false
+// for messages that are not one of the recognised authentication events.
+// Only one message of each attempt is recognised (e.g. sshd's "Failed
+// password", not the PAM failure logged with it), so that failure counts
+// are not doubled.
+func parseAuthMessage(program string, pid int32, msg string, ts time.Time, source string) (Event, bool) {
+	msg = strings.TrimSpace(msg) // sudo indents its messages
+	a := AuthEvent{Program: program, PID: pid, Message: msg}
+	var typ string
+
+	switch program {
+	case "sshd", "sshd-session":
+		if m := sshAccepted.FindStringSubmatch(msg); m != nil {
+			typ, a.Method, a.User, a.SourceIP, a.Success = "ssh_login", m[1], m[2], m[3], true
+			a.Port, _ = strconv.Atoi(m[4])
+		} else if m := sshFailed.FindStringSubmatch(msg); m != nil {
+			typ, a.Method, a.User, a.SourceIP = "ssh_login", m[1], m[2], m[3]
+			a.Port, _ = strconv.Atoi(m[4])
+		}
+
+	case "sudo":
+		if m := sudoCommand.FindStringSubmatch(msg); m != nil {
+			typ, a.User, a.TTY, a.TargetUser, a.Command = "sudo", m[1], m[3], m[4], m[5]
+			a.Success = m[2] == ""
+		}
+
+	case "su", "su-l":
+		if m := suSuccess.FindStringSubmatch(msg); m != nil {
+			typ, a.TargetUser, a.User, a.TTY, a.Success = "su", m[1], m[2], m[3], true
+		} else if m := suSuccessOld.FindStringSubmatch(msg); m != nil {
+			typ, a.TargetUser, a.User, a.Success = "su", m[1], m[2], true
+		} else if m := suFailed.FindStringSubmatch(msg); m != nil {
+			typ, a.TargetUser, a.User, a.TTY = "su", m[1], m[2], m[3]
+		}
+
+	case "login":
+		if m := loginSuccess.FindStringSubmatch(msg); m != nil {
+			typ, a.TTY, a.User, a.SourceIP, a.Success = "login", m[1], m[2], m[3], true
+		} else if m := loginRoot.FindStringSubmatch(msg); m != nil {
+			typ, a.TTY, a.SourceIP, a.User, a.Success = "login", m[1], m[2], "root", true
+		} else if m := loginFailed.FindStringSubmatch(msg); m != nil {
+			typ, a.TTY, a.SourceIP, a.User = "login", m[1], m[2], m[3]
+		}
+
+	case "useradd", "groupadd":
+		if m := accountNew.FindStringSubmatch(msg); m != nil {
+			typ, a.Action, a.TargetUser, a.Success = "account_change", m[1]+"_add", m[2], true
+		} else if m := accountGroupAdd.FindStringSubmatch(msg); m != nil {
+			typ, a.Action, a.TargetUser, a.Success = "account_change", "group_add", m[1], true
+		}
+
+	case "userdel", "groupdel":
+		if m := accountDelete.FindStringSubmatch(msg); m != nil {
+			typ, a.Action, a.TargetUser, a.Success = "account_change", "user_delete", m[1], true
+		} else if m := accountGroupDel.FindStringSubmatch(msg); m != nil && program == "groupdel" {
+			typ, a.Action, a.TargetUser, a.Success = "account_change", "group_delete", m[1]+m[2], true
+		}
+
+	case "usermod", "groupmod", "gpasswd":
+		if m := accountQuoted.FindStringSubmatch(msg); m != nil {
+			action := "user_modify"
+			if program != "usermod" {
+				action = "group_modify"
+			}
+			typ, a.Action, a.TargetUser, a.Success = "account_change", action, m[1], true
+		}
+
+	case "passwd", "chpasswd":
+		if m := passwdChanged.FindStringSubmatch(msg); m != nil {
+			typ, a.Action, a.TargetUser, a.Success = "account_change", "password_change", m[1], true
+		}
+	}
+
+	if typ == "" {
+		return Event{}, false
+	}
+	return Event{Type: typ, Timestamp: ts.UTC().Format(time.RFC3339Nano), Source: source, Auth: &a}, true
+}
+
+// parseSyslogLine splits a syslog file line in the traditional
+// ("Oct 19 12:34:56 host sshd[123]: ...") or RFC 3339 format
+// ("2026-10-19T12:34:56.123456+00:00 host sshd[123]: ...").
+func parseSyslogLine(line string, now time.Time) (ts time.Time, program string, pid int32, msg string, ok bool) {
+	var rest string
+	if len(line) > 16 && line[3] == ' ' && line[15] == ' ' {
+		// The traditional format has no year; a date ahead of now is
+		// from last year.
+		t, err := time.ParseInLocation("Jan _2 15:04:05 2006", line[:15]+" "+strconv.Itoa(now.Year()), time.Local)
+		if err != nil {
+			return
+		}
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		ts, rest = t, line[16:]
+	} else {
+		stamp, r, found := strings.Cut(line, " ")
+		if !found {
+			return
+		}
+		t, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			return
+		}
+		ts, rest = t, r
+	}
+
+	// Skip the host name.
+	_, rest, found := strings.Cut(rest, " ")
+	if !found {
+		return
+	}
+	tag, msg, found := strings.Cut(rest, ": ")
+	if !found {
+		return
+	}
+	program = tag
+	if i := strings.IndexByte(tag, '['); i >= 0 && strings.HasSuffix(tag, "]") {
+		program = tag[:i]
+		n, _ := strconv.Atoi(tag[i+1 : len(tag)-1])
+		pid = int32(n)
+	}
+	return ts, program, pid, msg, true
+}
@@ -0,0 +1,368 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"snapsec-agent/internal/atomicfile"
+)
+
+// DefaultAuthLogPaths are the syslog auth files of Debian-like and RHEL-like
+// distributions. Hosts with neither log to the journal only.
+var DefaultAuthLogPaths = []string{"/var/log/auth.log", "/var/log/secure"}
+
+const (
+	authReadSize       = 64 << 10
+	journalRestartWait = 10 * time.Second
+)
+
+// authLogPosition is the checkpoint of AuthLog: the first byte of the file
+// not yet emitted. The inode finds the file again after it was rotated.
+type authLogPosition struct {
+	File   string `json:"file"`
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// AuthLog follows the syslog auth file. It resumes from its checkpoint,
+// including the rest of a file rotated to <file>.1 meanwhile; without one
+// it starts at the end of the file rather than replay the history.
+type AuthLog struct {
+	Paths          []string // candidates, the first existing one is followed; default DefaultAuthLogPaths
+	CheckpointPath string
+
+	mu      sync.Mutex
+	pos     authLogPosition
+	saved   authLogPosition
+	started bool
+}
+
+func (l *AuthLog) Name() string {
+	return "auth_log"
+}
+
+func (l *AuthLog) Run(stop <-chan struct{}, emit func(Event)) error {
+	paths := l.Paths
+	if len(paths) == 0 {
+		paths = DefaultAuthLogPaths
+	}
+	var path string
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			path = p
+			break
+		}
+	}
+	if path == "" {
+		return errors.New("no auth log file")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var saved authLogPosition
+	if err := readCheckpoint(l.CheckpointPath, &saved); err != nil {
+		log.Printf("Ignoring auth log checkpoint: %v", err)
+	}
+	l.mu.Lock()
+	l.saved = saved
+	l.pos = authLogPosition{File: path, Inode: inode(fi), Offset: fi.Size()}
+	switch {
+	case saved.File != path:
+		// First run: start at the end.
+	case saved.Inode == l.pos.Inode && saved.Offset <= fi.Size():
+		l.pos.Offset = saved.Offset
+	default:
+		// Rotated since the checkpoint. Finish the old file if it is
+		// still around, then read the new one from the start.
+		l.pos.Offset = 0
+	}
+	l.started = true
+	l.mu.Unlock()
+
+	if saved.File == path && saved.Inode != l.pos.Inode {
+		if old, err := os.Open(path + ".1"); err == nil {
+			if ofi, err := old.Stat(); err == nil && inode(ofi) == saved.Inode {
+				l.mu.Lock()
+				l.pos = saved
+				l.mu.Unlock()
+				l.drain(old, emit)
+				l.mu.Lock()
+				l.pos = authLogPosition{File: path, Inode: inode(fi)}
+				l.mu.Unlock()
+			}
+			old.Close()
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		l.drain(f, emit)
+
+		select {
+		case <-stop:
+			f.Close()
+			return nil
+		case <-ticker.C:
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue // between rotation's rename and create
+		}
+		l.mu.Lock()
+		rotated := inode(fi) != l.pos.Inode
+		if !rotated && fi.Size() < l.pos.Offset {
+			l.pos.Offset = 0 // truncated in place (copytruncate)
+		}
+		l.mu.Unlock()
+		if !rotated {
+			continue
+		}
+
+		// Whatever was written before the rename is still in the old file.
+		l.drain(f, emit)
+		nf, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		nfi, err := nf.Stat()
+		if err != nil {
+			nf.Close()
+			continue
+		}
+		f.Close()
+		f = nf
+		l.mu.Lock()
+		l.pos = authLogPosition{File: path, Inode: inode(nfi)}
+		l.mu.Unlock()
+	}
+}
+
+// drain emits the events of the complete lines after the current offset.
+// A trailing partial line is left for the next call.
+func (l *AuthLog) drain(f *os.File, emit func(Event)) {
+	buf := make([]byte, authReadSize)
+	for {
+		l.mu.Lock()
+		offset := l.pos.Offset
+		l.mu.Unlock()
+
+		n, _ := f.ReadAt(buf, offset)
+		end := bytes.LastIndexByte(buf[:n], '\n')
+		lines := buf[:max(end, 0)]
+		if end < 0 {
+			if n < len(buf) {
+				return
+			}
+			end, lines = n-1, nil // a line longer than the buffer is skipped
+		}
+
+		now := time.Now()
+		l.mu.Lock()
+		for _, line := range bytes.Split(lines, []byte("\n")) {
+			ts, program, pid, msg, ok := parseSyslogLine(string(line), now)
+			if !ok {
+				continue
+			}
+			if e, ok := parseAuthMessage(program, pid, msg, ts, l.Name()); ok {
+				emit(e)
+			}
+		}
+		l.pos.Offset = offset + int64(end) + 1
+		l.mu.Unlock()
+	}
+}
+
+func (l *AuthLog) Checkpoint(delivered func() bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.started || l.pos == l.saved || !delivered() {
+		return
+	}
+	if err := writeCheckpoint(l.CheckpointPath, l.pos); err != nil {
+		log.Printf("Failed to save auth log checkpoint: %v", err)
+		return
+	}
+	l.saved = l.pos
+}
+
+// AuthJournal follows the auth and authpriv facilities of the systemd
+// journal through journalctl, for hosts without an auth log file. Its
+// checkpoint is the journal cursor.
+type AuthJournal struct {
+	CheckpointPath string
+
+	mu      sync.Mutex
+	cursor  string
+	saved   string
+	started bool
+}
+
+func (j *AuthJournal) Name() string {
+	return "journal"
+}
+
+func (j *AuthJournal) Run(stop <-chan struct{}, emit func(Event)) error {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return err
+	}
+	var saved struct {
+		Cursor string `json:"cursor"`
+	}
+	if err := readCheckpoint(j.CheckpointPath, &saved); err != nil {
+		log.Printf("Ignoring journal checkpoint: %v", err)
+	}
+	j.mu.Lock()
+	j.cursor, j.saved, j.started = saved.Cursor, saved.Cursor, true
+	j.mu.Unlock()
+
+	for {
+		err := j.follow(stop, emit)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		log.Printf("journalctl exited: %v; restarting in %v", err, journalRestartWait)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(journalRestartWait):
+		}
+	}
+}
+
+// follow runs journalctl from the cursor until it exits or stop is closed.
+func (j *AuthJournal) follow(stop <-chan struct{}, emit func(Event)) error {
+	args := []string{"--follow", "--output=json", "--no-pager"}
+	j.mu.Lock()
+	if j.cursor != "" {
+		args = append(args, "--after-cursor="+j.cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	j.mu.Unlock()
+	args = append(args, "SYSLOG_FACILITY=4", "SYSLOG_FACILITY=10")
+
+	cmd := exec.Command("journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, authReadSize), 1<<20)
+	for scanner.Scan() {
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		program := journalField(entry, "SYSLOG_IDENTIFIER")
+		pid, _ := strconv.Atoi(journalField(entry, "_PID"))
+		usec, _ := strconv.ParseInt(journalField(entry, "__REALTIME_TIMESTAMP"), 10, 64)
+
+		j.mu.Lock()
+		if e, ok := parseAuthMessage(program, int32(pid), journalField(entry, "MESSAGE"), time.UnixMicro(usec), j.Name()); ok {
+			emit(e)
+		}
+		j.cursor = journalField(entry, "__CURSOR")
+		j.mu.Unlock()
+	}
+	stdout.Close()
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// journalField returns a field of a journal JSON entry. journalctl writes
+// fields that are not valid UTF-8 as arrays of bytes.
+func journalField(entry map[string]json.RawMessage, name string) string {
+	raw, ok := entry[name]
+	if !ok {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+	}
+	return string(b)
+}
+
+func (j *AuthJournal) Checkpoint(delivered func() bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.started || j.cursor == j.saved || !delivered() {
+		return
+	}
+	if err := writeCheckpoint(j.CheckpointPath, map[string]string{"cursor": j.cursor}); err != nil {
+		log.Printf("Failed to save journal checkpoint: %v", err)
+		return
+	}
+	j.saved = j.cursor
+}
+
+// readCheckpoint loads a source's saved position into v; a missing file
+// leaves v untouched.
+func readCheckpoint(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeCheckpoint saves a source's position, replacing the previous one
+// atomically.
+func writeCheckpoint(path string, v interface{}) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, data)
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxBatch bounds the events sent in one request.
+const maxBatch = 500
+
+// Config tunes a Collector. Zero values select the defaults.
+type Config struct {
+	BufferSize   int           // events held while the backend is unreachable (default 10000)
+	PushInterval time.Duration // how often batches are sent (default 30s)
+	SpoolPath    string        // where pending events are kept across restarts; empty disables
+}
+
+// Collector runs event sources and ships what they produce. Sources added
+// with a fallback are replaced by it when they fail to start (e.g. the proc
+// connector without CAP_NET_ADMIN).
+type Collector struct {
+	cfg     Config
+	store   *Store
+	send    func(batch []Event, dropped uint64) error
+	sources [][]Source
+	// emitted holds, per source chain, the number the store gave the last
+	// event the chain emitted; a chain's sources may checkpoint once it
+	// is delivered.
+	emitted []*atomic.Uint64
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewCollector(cfg Config, send func(batch []Event, dropped uint64) error) *Collector {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 10000
+	}
+	if cfg.PushInterval <= 0 {
+		cfg.PushInterval = 30 * time.Second
+	}
+	return &Collector{
+		cfg:   cfg,
+		store: NewStore(cfg.BufferSize),
+		send:  send,
+		stop:  make(chan struct{}),
+	}
+}
+
+// AddSource registers a source and the sources to try, in order, when it
+// cannot start.
+func (c *Collector) AddSource(src Source, fallbacks ...Source) {
+	c.sources = append(c.sources, append([]Source{src}, fallbacks...))
+	c.emitted = append(c.emitted, new(atomic.Uint64))
+}
+
+// Enabled reports whether any source was added.
+func (c *Collector) Enabled() bool {
+	return len(c.sources) > 0
+}
+
+func (c *Collector) Start() {
+	if !c.Enabled() {
+		return
+	}
+	if c.cfg.SpoolPath != "" {
+		if err := c.store.Load(c.cfg.SpoolPath); err != nil {
+			log.Printf("Failed to load spooled events: %v", err)
+		}
+	}
+
+	for i, chain := range c.sources {
+		c.wg.Add(1)
+		go func(i int, chain []Source) {
+			defer c.wg.Done()
+			c.run(chain, c.emitted[i])
+		}(i, chain)
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.ship()
+	}()
+}
+
+// Stop stops the sources, makes a last delivery attempt and spools what is
+// left.
+func (c *Collector) Stop() {
+	if !c.Enabled() {
+		return
+	}
+	close(c.stop)
+	c.wg.Wait()
+	if c.cfg.SpoolPath != "" {
+		if err := c.store.Save(c.cfg.SpoolPath); err != nil {
+			log.Printf("Failed to spool events: %v", err)
+			return
+		}
+		// Everything emitted is now either sent or spooled.
+		c.checkpoint(func(*atomic.Uint64) bool { return true })
+	}
+}
+
+// checkpoint lets each source persist its position if delivered confirms
+// that the events its chain emitted are no longer pending.
+func (c *Collector) checkpoint(delivered func(emitted *atomic.Uint64) bool) {
+	for i, chain := range c.sources {
+		emitted := c.emitted[i]
+		for _, src := range chain {
+			if cp, ok := src.(Checkpointer); ok {
+				cp.Checkpoint(func() bool { return delivered(emitted) })
+			}
+		}
+	}
+}
+
+func (c *Collector) run(chain []Source, emitted *atomic.Uint64) {
+	emit := func(e Event) {
+		emitted.Store(c.store.Add(e))
+	}
+	for _, src := range chain {
+		log.Printf("Starting %s event source", src.Name())
+		err := src.Run(c.stop, emit)
+		if err == nil {
+			return
+		}
+		log.Printf("Event source %s unavailable: %v", src.Name(), err)
+	}
+}
+
+func (c *Collector) ship() {
+	ticker := time.NewTicker(c.cfg.PushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.flush()
+		case <-c.stop:
+			c.flush()
+			return
+		}
+	}
+}
+
+// flush sends batches until the store is empty or a send fails, then lets
+// each source checkpoint if none of the events it emitted are pending, so a
+// busy source does not hold back the others.
+func (c *Collector) flush() {
+	for c.store.Len() > 0 {
+		batch, dropped := c.store.Take(maxBatch)
+		if err := c.send(batch, dropped); err != nil {
+			log.Printf("Failed to send %d events: %v", len(batch), err)
+			c.store.Requeue(batch, dropped)
+			break
+		}
+		if dropped > 0 {
+			log.Printf("Dropped %d events while the event buffer was full", dropped)
+		}
+	}
+	c.checkpoint(func(emitted *atomic.Uint64) bool { return c.store.Delivered(emitted.Load()) })
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"snapsec-agent/pkg/api"
+)
+
+// fakeSource emits its events, then runs until stopped. A source with err set
+// fails to start.
+type fakeSource struct {
+	name   string
+	events []Event
+	err    error
+}
+
+func (s *fakeSource) Name() string {
+	return s.name
+}
+
+func (s *fakeSource) Run(stop <-chan struct{}, emit func(Event)) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, e := range s.events {
+		emit(e)
+	}
+	<-stop
+	return nil
+}
+
+// checkpointSource records what delivered() said at each checkpoint.
+type checkpointSource struct {
+	fakeSource
+	mu        sync.Mutex
+	delivered []bool
+}
+
+func (s *checkpointSource) Checkpoint(delivered func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered = append(s.delivered, delivered())
+}
+
+func (s *checkpointSource) lastDelivered() (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.delivered) == 0 {
+		return false, false
+	}
+	return s.delivered[len(s.delivered)-1], true
+}
+
+// eventsBackend stands in for the backend's /events endpoint, like
+// cmd/fakebackend, and records what it receives.
+type eventsBackend struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (b *eventsBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/events" {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var req struct {
+		AgentID string  `json:"agent_id"`
+		Data    []Event `json:"data"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.AgentID == "" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	b.mu.Lock()
+	b.events = append(b.events, req.Data...)
+	b.mu.Unlock()
+	w.Write([]byte(`{}`))
+}
+
+func (b *eventsBackend) received() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Event(nil), b.events...)
+}
+
+func TestCollectorShipsToBackend(t *testing.T) {
+	backend := &eventsBackend{}
+	srv := httptest.NewServer(backend)
+	defer srv.Close()
+	client := api.NewClient(srv.URL, "test-key")
+
+	c := NewCollector(Config{PushInterval: 20 * time.Millisecond}, func(batch []Event, dropped uint64) error {
+		return client.SendEvents("agent-1", batch, dropped)
+	})
+	// The first source fails to start and is replaced by its fallback.
+	c.AddSource(
+		&fakeSource{name: "broken", err: errors.New("unavailable")},
+		&fakeSource{name: "fallback", events: []Event{event(1), event(2)}},
+	)
+	c.AddSource(&fakeSource{name: "other", events: []Event{event(3)}})
+	c.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(backend.received()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+
+	if got := backend.received(); len(got) != 3 {
+		t.Fatalf("backend received %d events, want 3", len(got))
+	}
+	if c.store.Len() != 0 {
+		t.Errorf("%d events left in the store", c.store.Len())
+	}
+}
+
+func TestCollectorSpoolsUndelivered(t *testing.T) {
+	spool := t.TempDir() + "/events.json"
+	c := NewCollector(Config{PushInterval: time.Hour, SpoolPath: spool}, func([]Event, uint64) error {
+		return errors.New("backend unreachable")
+	})
+	c.AddSource(&fakeSource{name: "source", events: []Event{event(1), event(2)}})
+	c.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.store.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+
+	restored := NewStore(10)
+	if err := restored.Load(spool); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 2 {
+		t.Errorf("spooled %d events, want 2", restored.Len())
+	}
+}
+
+func TestCollectorCheckpointsPerSource(t *testing.T) {
+	var mu sync.Mutex
+	fail := true
+	c := NewCollector(Config{PushInterval: 10 * time.Millisecond}, func(batch []Event, dropped uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New("backend unreachable")
+		}
+		return nil
+	})
+	src := &checkpointSource{fakeSource: fakeSource{name: "auth", events: []Event{event(1)}}}
+	c.AddSource(src)
+	c.Start()
+	defer c.Stop()
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if got, ok := src.lastDelivered(); ok && got == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("source never checkpointed with delivered() = %v", want)
+	}
+
+	waitFor(false) // its event is still queued
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	waitFor(true)
+}
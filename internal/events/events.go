@@ -0,0 +1,43 @@
+package events
+
+// Event is one host event. Events are collected continuously, buffered in a
+// Store and shipped in batches to the backend's /events endpoint, between
+// the periodic inventory pushes. Exactly one of the typed payloads is set.
+type Event struct {
+	Type      string        `json:"type"`      // process_exec, process_exit, ssh_login, sudo, su, login, account_change
+	Timestamp string        `json:"timestamp"` // RFC 3339 with nanoseconds
+	Source    string        `json:"source"`    // collector that observed it, e.g. proc_connector
+	Process   *ProcessEvent `json:"process,omitempty"`
+	Auth      *AuthEvent    `json:"auth,omitempty"`
+}
+
+// ProcessEvent describes the process of a process_exec or process_exit
+// event. Exit events carry the details recorded at exec time when the
+// collector saw the exec; ExitCode and Signal are only known to the proc
+// connector.
+type ProcessEvent struct {
+	PID      int32  `json:"pid"`
+	PPID     int32  `json:"ppid,omitempty"`
+	Exe      string `json:"exe,omitempty"`
+	Cmdline  string `json:"cmdline,omitempty"`
+	User     string `json:"user,omitempty"`
+	UID      string `json:"uid,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Signal   int    `json:"signal,omitempty"`
+}
+
+// Source produces events until stop is closed. Run returns an error when the
+// source cannot start at all, so the caller can fall back to another one.
+type Source interface {
+	Name() string
+	Run(stop <-chan struct{}, emit func(Event)) error
+}
+
+// Checkpointer is implemented by sources that resume where they left off
+// after a restart. Checkpoint is called after the events emitted so far may
+// have been delivered; the source persists its position only if delivered()
+// confirms that nothing it emitted is still pending, and must emit and
+// advance its position atomically with respect to Checkpoint.
+type Checkpointer interface {
+	Checkpoint(delivered func() bool)
+}
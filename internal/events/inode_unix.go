@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package events
+
+import (
+	"os"
+	"syscall"
+)
+
+// inode identifies a log file across renames, so that a rotated file is
+// recognised by its new name.
+func inode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"snapsec-agent/internal/modules/processes"
+)
+
+// Process exec and exit events on Linux, from the kernel's proc connector
+// (ProcConnector) or, without the privilege it needs, by diffing /proc
+// (ProcPoller).
+
+const (
+	pollInterval = time.Second
+	maxTracked   = 1 << 16 // processes whose exec details are kept for their exit event
+)
+
+// procTracker reads process details from /proc and remembers them until the
+// process exits, when /proc no longer has them.
+type procTracker struct {
+	redactor *processes.Redactor
+	tracked  map[int32]ProcessEvent
+	users    map[string]string
+}
+
+func newProcTracker(redactor *processes.Redactor) *procTracker {
+	return &procTracker{redactor: redactor, tracked: make(map[int32]ProcessEvent), users: make(map[string]string)}
+}
+
+func (t *procTracker) exec(pid int32, source string) Event {
+	p := t.read(pid)
+	if len(t.tracked) >= maxTracked {
+		t.tracked = make(map[int32]ProcessEvent) // lose exit details rather than grow
+	}
+	t.tracked[pid] = p
+	return newProcessEvent("process_exec", source, p)
+}
+
+func (t *procTracker) exit(pid int32, source string) Event {
+	p, ok := t.tracked[pid]
+	if !ok {
+		p = ProcessEvent{PID: pid}
+	}
+	delete(t.tracked, pid)
+	return newProcessEvent("process_exit", source, p)
+}
+
+func newProcessEvent(typ, source string, p ProcessEvent) Event {
+	return Event{Type: typ, Timestamp: time.Now().UTC().Format(time.RFC3339Nano), Source: source, Process: &p}
+}
+
+// read collects what /proc still has of pid; a short-lived process may
+// already be gone, leaving only the PID.
+func (t *procTracker) read(pid int32) ProcessEvent {
+	p := ProcessEvent{PID: pid}
+	dir := filepath.Join("/proc", strconv.Itoa(int(pid)))
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		p.Exe = strings.TrimSuffix(exe, " (deleted)")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(data) > 0 {
+		args := strings.Split(string(bytes.TrimRight(data, "\x00")), "\x00")
+		p.Cmdline = t.redactor.Cmdline(args)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			k, v, _ := strings.Cut(line, ":")
+			fields := strings.Fields(v)
+			if len(fields) == 0 {
+				continue
+			}
+			switch k {
+			case "PPid":
+				ppid, _ := strconv.Atoi(fields[0])
+				p.PPID = int32(ppid)
+			case "Uid":
+				p.UID = fields[0]
+				p.User = t.username(fields[0])
+			}
+		}
+	}
+	return p
+}
+
+func (t *procTracker) username(uid string) string {
+	if name, ok := t.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	t.users[uid] = name
+	return name
+}
+
+// ProcPoller diffs the /proc process list every second. It misses processes
+// that live shorter than that, re-execs within one PID, and exit codes.
+type ProcPoller struct {
+	Redactor *processes.Redactor
+}
+
+func (p *ProcPoller) Name() string {
+	return "proc_poll"
+}
+
+func (p *ProcPoller) Run(stop <-chan struct{}, emit func(Event)) error {
+	t := newProcTracker(p.Redactor)
+
+	// PIDs are reused, so a process is identified by PID and start time.
+	seen, err := listProcesses()
+	if err != nil {
+		return err
+	}
+	for pid := range seen {
+		t.tracked[pid] = t.read(pid)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := listProcesses()
+		if err != nil {
+			continue
+		}
+		for pid, start := range seen {
+			if s, ok := current[pid]; !ok || s != start {
+				emit(t.exit(pid, p.Name()))
+			}
+		}
+		for pid, start := range current {
+			if s, ok := seen[pid]; !ok || s != start {
+				emit(t.exec(pid, p.Name()))
+			}
+		}
+		seen = current
+	}
+}
+
+// listProcesses maps each PID in /proc to its start time in clock ticks.
+func listProcesses() (map[int32]string, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	procs := make(map[int32]string, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		if start, err := startTime(int32(pid)); err == nil {
+			procs[int32(pid)] = start
+		}
+	}
+	return procs, nil
+}
+
+// startTime returns field 22 of /proc/<pid>/stat. The command name in field
+// 2 may contain spaces and parentheses, so fields are counted from the last
+// ')'.
+func startTime(pid int32) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(int(pid)), "stat"))
+	if err != nil {
+		return "", err
+	}
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return "", fmt.Errorf("malformed stat for %d", pid)
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 20 {
+		return "", fmt.Errorf("malformed stat for %d", pid)
+	}
+	return fields[19], nil
+}
@@ -0,0 +1,206 @@
+//go:build linux
+// +build linux
+
+package events
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"snapsec-agent/internal/modules/processes"
+)
+
+// Proc connector protocol (linux/connector.h, linux/cn_proc.h).
+const (
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+	procEventNone     = 0x00000000 // the reply to PROC_CN_MCAST_LISTEN
+	procEventExec     = 0x00000002
+	procEventExit     = 0x80000000
+
+	cnMsgLen = 20 // struct cn_msg without data
+)
+
+// ackTimeout bounds the wait for the kernel's reply to the subscription.
+const ackTimeout = 5 * time.Second
+
+// ProcConnector receives exec and exit notifications from the kernel as they
+// happen. It needs CAP_NET_ADMIN in the initial network namespace.
+type ProcConnector struct {
+	Redactor *processes.Redactor
+}
+
+func (c *ProcConnector) Name() string {
+	return "proc_connector"
+}
+
+func (c *ProcConnector) Run(stop <-chan struct{}, emit func(Event)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_CONNECTOR)
+	if err != nil {
+		return fmt.Errorf("netlink socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		return fmt.Errorf("bind to proc connector: %w", err)
+	}
+	// A receive timeout lets the loop notice stop.
+	tv := syscall.NsecToTimeval(int64(time.Second))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("set receive timeout: %w", err)
+	}
+	seq := uint32(os.Getpid())
+	if err := syscall.Sendto(fd, listenMessage(seq), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return fmt.Errorf("subscribe to proc events: %w", err)
+	}
+
+	t := newProcTracker(c.Redactor)
+	buf := make([]byte, 64<<10)
+	if err := c.awaitAck(fd, buf, seq, t, emit); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		switch {
+		case errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.ENOBUFS):
+			continue // the kernel dropped events under load; keep going
+		case err != nil:
+			return fmt.Errorf("receive proc events: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if e, ok := c.parse(t, m.Data); ok {
+				emit(e)
+			}
+		}
+	}
+}
+
+// awaitAck waits for the kernel's PROC_EVENT_NONE reply to the subscription,
+// emitting any events that arrive first. The reply carries EPERM without
+// CAP_NET_ADMIN; outside the initial user and pid namespaces the kernel
+// ignores the request and never replies. Either way the source fails, so
+// the collector falls back to polling, unless events show that it works.
+func (c *ProcConnector) awaitAck(fd int, buf []byte, seq uint32, t *procTracker, emit func(Event)) error {
+	heard := false
+	for deadline := time.Now().Add(ackTimeout); time.Now().Before(deadline); {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		switch {
+		case errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.ENOBUFS):
+			continue
+		case err != nil:
+			return fmt.Errorf("receive proc events: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if errno, ok := parseAck(m.Data, seq); ok {
+				if errno != 0 {
+					return fmt.Errorf("subscribe to proc events: %w", syscall.Errno(errno))
+				}
+				return nil
+			}
+			if e, ok := c.parse(t, m.Data); ok {
+				heard = true
+				emit(e)
+			}
+		}
+	}
+	if heard {
+		return nil
+	}
+	return fmt.Errorf("no reply to the proc events subscription within %s", ackTimeout)
+}
+
+// parseAck decodes the PROC_EVENT_NONE reply to our subscription, which
+// echoes its sequence number, and returns its error code.
+func parseAck(data []byte, seq uint32) (uint32, bool) {
+	if len(data) < cnMsgLen+20 {
+		return 0, false
+	}
+	ne := binary.NativeEndian
+	if ne.Uint32(data[8:]) != seq || ne.Uint32(data[cnMsgLen:]) != procEventNone {
+		return 0, false
+	}
+	return ne.Uint32(data[cnMsgLen+16:]), true
+}
+
+// listenMessage is an nlmsghdr carrying a cn_msg with PROC_CN_MCAST_LISTEN.
+func listenMessage(seq uint32) []byte {
+	const size = syscall.NLMSG_HDRLEN + cnMsgLen + 4
+	b := make([]byte, size)
+	ne := binary.NativeEndian
+	ne.PutUint32(b[0:], size)
+	ne.PutUint16(b[4:], syscall.NLMSG_DONE)
+	msg := b[syscall.NLMSG_HDRLEN:]
+	ne.PutUint32(msg[0:], cnIdxProc)
+	ne.PutUint32(msg[4:], cnValProc)
+	ne.PutUint32(msg[8:], seq)
+	ne.PutUint16(msg[16:], 4) // data length
+	ne.PutUint32(msg[cnMsgLen:], procCnMcastListen)
+	return b
+}
+
+// parse decodes a struct proc_event: what, cpu, timestamp_ns, then the
+// event data. Only thread group leaders are reported; thread exits are not
+// process exits.
+func (c *ProcConnector) parse(t *procTracker, data []byte) (Event, bool) {
+	if len(data) < cnMsgLen+16 {
+		return Event{}, false
+	}
+	ev := data[cnMsgLen:]
+	ne := binary.NativeEndian
+	what := ne.Uint32(ev[0:])
+	body := ev[16:]
+
+	switch what {
+	case procEventExec:
+		if len(body) < 8 {
+			return Event{}, false
+		}
+		pid, tgid := int32(ne.Uint32(body[0:])), int32(ne.Uint32(body[4:]))
+		if pid != tgid {
+			return Event{}, false
+		}
+		return t.exec(tgid, c.Name()), true
+
+	case procEventExit:
+		if len(body) < 16 {
+			return Event{}, false
+		}
+		pid, tgid := int32(ne.Uint32(body[0:])), int32(ne.Uint32(body[4:]))
+		if pid != tgid {
+			return Event{}, false
+		}
+		e := t.exit(tgid, c.Name())
+		status := ne.Uint32(body[8:]) // wait status
+		if sig := int(status & 0x7f); sig != 0 {
+			e.Process.Signal = sig
+		} else {
+			code := int(status>>8) & 0xff
+			e.Process.ExitCode = &code
+		}
+		return e, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+// +build !linux
+
+package events
+
+import (
+	"errors"
+
+	"snapsec-agent/internal/modules/processes"
+)
+
+// ProcConnector is Linux-only.
+type ProcConnector struct {
+	Redactor *processes.Redactor
+}
+
+func (c *ProcConnector) Name() string {
+	return "proc_connector"
+}
+
+func (c *ProcConnector) Run(stop <-chan struct{}, emit func(Event)) error {
+	return errors.New("proc connector is only available on Linux")
+}
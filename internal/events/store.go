@@ -0,0 +1,169 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a bounded FIFO of events waiting to be shipped. When it is full
+// the oldest events are dropped and counted, so a backend outage costs the
+// oldest history rather than agent memory. Events are held in a ring of max
+// slots, so adding to a full store is O(1).
+//
+// Each event added is numbered, so a source can tell whether the events it
+// emitted have all left the store (see Delivered). Events loaded from the
+// spool predate this run's numbering and carry 0.
+type Store struct {
+	mu      sync.Mutex
+	ring    []queued
+	head    int // index of the oldest event
+	n       int
+	max     int
+	seq     uint64   // number of the last event added
+	taken   []uint64 // numbers of the last batch taken, restored by Requeue
+	dropped uint64
+}
+
+type queued struct {
+	seq   uint64
+	event Event
+}
+
+func NewStore(max int) *Store {
+	if max <= 0 {
+		max = 1
+	}
+	return &Store{ring: make([]queued, max), max: max}
+}
+
+// Add queues an event and returns its number.
+func (s *Store) Add(e Event) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.n == s.max {
+		s.ring[s.head] = queued{}
+		s.head = (s.head + 1) % s.max
+		s.n--
+		s.dropped++
+	}
+	s.seq++
+	s.ring[(s.head+s.n)%s.max] = queued{seq: s.seq, event: e}
+	s.n++
+	return s.seq
+}
+
+// Delivered reports whether no event numbered up to seq is still queued:
+// each was sent, or dropped when the store was full. Events being sent are
+// not queued, so it is meaningful only between a Take and the Requeue of a
+// failed batch, not during the send.
+func (s *Store) Delivered(seq uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Numbers increase from head to tail; spooled events (0) come first.
+	for i := 0; i < s.n; i++ {
+		if q := s.ring[(s.head+i)%s.max]; q.seq > 0 {
+			return q.seq > seq
+		}
+	}
+	return true
+}
+
+// Take removes and returns up to n of the oldest events, and the number of
+// events dropped since the last Take.
+func (s *Store) Take(n int) ([]Event, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > s.n {
+		n = s.n
+	}
+	batch := make([]Event, n)
+	s.taken = make([]uint64, n)
+	for i := range batch {
+		batch[i], s.taken[i] = s.ring[s.head].event, s.ring[s.head].seq
+		s.ring[s.head] = queued{}
+		s.head = (s.head + 1) % s.max
+	}
+	s.n -= n
+	dropped := s.dropped
+	s.dropped = 0
+	return batch, dropped
+}
+
+// Requeue puts a batch that failed to ship back in front of the newer
+// events. If they no longer all fit, the oldest of the batch are dropped.
+// The batch of the last Take keeps its numbers.
+func (s *Store) Requeue(batch []Event, dropped uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seqs := s.taken
+	if len(seqs) != len(batch) {
+		seqs = make([]uint64, len(batch))
+	}
+	s.taken = nil
+	s.dropped += dropped
+	if over := s.n + len(batch) - s.max; over > 0 {
+		batch, seqs = batch[over:], seqs[over:]
+		s.dropped += uint64(over)
+	}
+	for i := len(batch) - 1; i >= 0; i-- {
+		s.head = (s.head - 1 + s.max) % s.max
+		s.ring[s.head] = queued{seq: seqs[i], event: batch[i]}
+		s.n++
+	}
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+// pending returns the queued events, oldest first. The caller holds mu.
+func (s *Store) pending() []Event {
+	out := make([]Event, s.n)
+	for i := range out {
+		out[i] = s.ring[(s.head+i)%s.max].event
+	}
+	return out
+}
+
+// Save writes the pending events to path, so that a restart does not lose
+// them. An empty store removes the file.
+func (s *Store) Save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.n == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(s.pending())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load queues the events saved by Save ahead of any new ones and removes
+// the file.
+func (s *Store) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []Event
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	s.Requeue(saved, 0)
+	return os.Remove(path)
+}
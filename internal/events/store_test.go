@@ -0,0 +1,107 @@
+package events
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func event(i int) Event {
+	return Event{Type: "process_exec", Timestamp: strconv.Itoa(i)}
+}
+
+func timestamps(events []Event) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = e.Timestamp
+	}
+	return out
+}
+
+func TestStoreDropsOldest(t *testing.T) {
+	s := NewStore(3)
+	for i := 1; i <= 5; i++ {
+		s.Add(event(i))
+	}
+	if s.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", s.Len())
+	}
+	batch, dropped := s.Take(10)
+	if got := timestamps(batch); len(got) != 3 || got[0] != "3" || got[2] != "5" {
+		t.Errorf("batch = %v, want [3 4 5]", got)
+	}
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+	if _, dropped := s.Take(10); dropped != 0 {
+		t.Errorf("dropped count not reset: %d", dropped)
+	}
+}
+
+func TestStoreRequeue(t *testing.T) {
+	s := NewStore(4)
+	for i := 1; i <= 3; i++ {
+		s.Add(event(i))
+	}
+	batch, _ := s.Take(2) // 1 2
+	s.Add(event(4))
+	s.Add(event(5)) // 3 4 5
+	s.Requeue(batch, 1)
+
+	// Only one of the requeued events fits; the oldest is dropped.
+	got, dropped := s.Take(10)
+	want := []string{"2", "3", "4", "5"}
+	for i := range want {
+		if i >= len(got) || got[i].Timestamp != want[i] {
+			t.Fatalf("events = %v, want %v", timestamps(got), want)
+		}
+	}
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.json")
+	s := NewStore(3)
+	for i := 1; i <= 4; i++ {
+		s.Add(event(i))
+	}
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewStore(3)
+	restored.Add(event(5))
+	if err := restored.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := restored.Take(10)
+	if ts := timestamps(got); len(ts) != 3 || ts[0] != "3" || ts[2] != "5" {
+		t.Errorf("events = %v, want [3 4 5] with the spooled events first", ts)
+	}
+}
+
+func TestStoreDelivered(t *testing.T) {
+	s := NewStore(10)
+	first := s.Add(event(1))
+	second := s.Add(event(2))
+	third := s.Add(event(3))
+
+	batch, dropped := s.Take(2)
+	s.Requeue(batch, dropped) // the send failed
+	if s.Delivered(first) {
+		t.Error("requeued event reported delivered")
+	}
+
+	s.Take(2) // sent
+	if !s.Delivered(first) || !s.Delivered(second) {
+		t.Error("sent events not reported delivered")
+	}
+	if s.Delivered(third) {
+		t.Error("queued event reported delivered")
+	}
+	if !s.Delivered(0) {
+		t.Error("a source that emitted nothing has nothing pending")
+	}
+}
@@ -0,0 +1,231 @@
+package containers
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// containerd only offers a gRPC API, so it is queried through its ctr CLI
+// instead, one namespace at a time (Kubernetes uses "k8s.io", nerdctl uses
+// "default", etc.).
+
+// Labels containerd's restart manager and nerdctl record a container's
+// restart policy and published ports under. Kubernetes sets neither; the
+// kubelet owns restarts and services own ports.
+const (
+	restartPolicyLabel = "containerd.io/restart.policy"
+	nerdctlPortsLabel  = "nerdctl/ports"
+)
+
+type ctrContainerInfo struct {
+	ID        string            `json:"ID"`
+	Labels    map[string]string `json:"Labels"`
+	Image     string            `json:"Image"`
+	CreatedAt string            `json:"CreatedAt"`
+	Spec      struct {
+		Process struct {
+			Args []string `json:"args"`
+			User struct {
+				UID int `json:"uid"`
+				GID int `json:"gid"`
+			} `json:"user"`
+			Capabilities struct {
+				Bounding []string `json:"bounding"`
+			} `json:"capabilities"`
+		} `json:"process"`
+		Mounts []struct {
+			Destination string   `json:"destination"`
+			Type        string   `json:"type"`
+			Source      string   `json:"source"`
+			Options     []string `json:"options"`
+		} `json:"mounts"`
+		Linux *struct {
+			Namespaces []struct {
+				Type string `json:"type"`
+				Path string `json:"path"`
+			} `json:"namespaces"`
+			Resources struct {
+				Devices []struct {
+					Allow bool   `json:"allow"`
+					Type  string `json:"type"`
+					Major *int64 `json:"major"`
+					Minor *int64 `json:"minor"`
+				} `json:"devices"`
+			} `json:"resources"`
+		} `json:"linux"`
+	} `json:"Spec"`
+}
+
+type nerdctlPort struct {
+	HostIP        string `json:"HostIP"`
+	HostPort      int    `json:"HostPort"`
+	ContainerPort int    `json:"ContainerPort"`
+	Protocol      string `json:"Protocol"`
+}
+
+func ctr(args ...string) (string, error) {
+	out, err := exec.Command("ctr", args...).Output()
+	return string(out), err
+}
+
+func containerdNamespaces() []string {
+	out, err := ctr("namespaces", "list", "-q")
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(out)
+}
+
+// containerdImages returns the images of every namespace, keyed by reference
+// so containers can be linked to their image digest.
+func containerdImages() ([]ImageInfo, map[string]string) {
+	var images []ImageInfo
+	digests := make(map[string]string)
+
+	for _, ns := range containerdNamespaces() {
+		out, err := ctr("-n", ns, "images", "list")
+		if err != nil {
+			continue
+		}
+		// REF TYPE DIGEST SIZE PLATFORMS LABELS
+		for i, line := range strings.Split(out, "\n") {
+			f := strings.Fields(line)
+			if i == 0 || len(f) < 3 {
+				continue
+			}
+			ref, digest := f[0], f[2]
+			digests[ref] = digest
+
+			info := ImageInfo{
+				Runtime:   "containerd",
+				Namespace: ns,
+				ID:        digest,
+			}
+			if strings.HasPrefix(ref, "sha256:") {
+				// Kubernetes also records images by their config digest.
+				continue
+			}
+			if strings.Contains(ref, "@sha256:") {
+				info.RepoDigests = []string{ref}
+			} else {
+				info.RepoTags = []string{ref}
+			}
+			images = append(images, info)
+		}
+	}
+	return images, digests
+}
+
+func containerdContainers(imageDigests map[string]string) []ContainerInfo {
+	var containers []ContainerInfo
+
+	for _, ns := range containerdNamespaces() {
+		// TASK PID STATUS; containers without a task are stopped.
+		states := make(map[string]string)
+		if out, err := ctr("-n", ns, "tasks", "list"); err == nil {
+			for i, line := range strings.Split(out, "\n") {
+				f := strings.Fields(line)
+				if i == 0 || len(f) < 3 {
+					continue
+				}
+				states[f[0]] = strings.ToLower(f[2])
+			}
+		}
+
+		out, err := ctr("-n", ns, "containers", "list", "-q")
+		if err != nil {
+			continue
+		}
+		for _, id := range strings.Fields(out) {
+			raw, err := ctr("-n", ns, "containers", "info", id)
+			if err != nil {
+				continue
+			}
+			info, err := parseCtrContainer(ns, raw)
+			if err != nil {
+				continue
+			}
+			info.ImageDigest = imageDigests[info.Image]
+			info.State = states[id]
+			if info.State == "" {
+				info.State = "created"
+			}
+			containers = append(containers, info)
+		}
+	}
+	return containers
+}
+
+// parseCtrContainer reads the output of "ctr containers info". containerd has
+// no privileged flag, restart policy or port list of its own: they are derived
+// from the OCI spec and the labels its clients set, and left unset when
+// neither says.
+func parseCtrContainer(ns, raw string) (ContainerInfo, error) {
+	var ci ctrContainerInfo
+	if err := json.Unmarshal([]byte(raw), &ci); err != nil {
+		return ContainerInfo{}, err
+	}
+
+	info := ContainerInfo{
+		Runtime:       "containerd",
+		Namespace:     ns,
+		ID:            ci.ID,
+		Name:          ci.ID,
+		Image:         ci.Image,
+		Command:       strings.Join(ci.Spec.Process.Args, " "),
+		CreatedAt:     ci.CreatedAt,
+		Capabilities:  ci.Spec.Process.Capabilities.Bounding,
+		RestartPolicy: ci.Labels[restartPolicyLabel],
+	}
+	info.User = strconv.Itoa(ci.Spec.Process.User.UID)
+	for _, m := range ci.Spec.Mounts {
+		info.Mounts = append(info.Mounts, MountInfo{
+			Type:        m.Type,
+			Source:      m.Source,
+			Destination: m.Destination,
+			ReadOnly:    contains(m.Options, "ro"),
+		})
+	}
+
+	var ports []nerdctlPort
+	if label := ci.Labels[nerdctlPortsLabel]; label != "" && json.Unmarshal([]byte(label), &ports) == nil {
+		for _, p := range ports {
+			info.Ports = append(info.Ports, PortInfo{
+				HostIP:        p.HostIP,
+				HostPort:      p.HostPort,
+				ContainerPort: p.ContainerPort,
+				Protocol:      p.Protocol,
+			})
+		}
+	}
+
+	if linux := ci.Spec.Linux; linux != nil {
+		// A namespace missing from the spec, or joined by path to one of
+		// pid 1's, is shared with the host.
+		own := make(map[string]bool)
+		for _, n := range linux.Namespaces {
+			if n.Path == "" || !strings.HasPrefix(n.Path, "/proc/1/") {
+				own[n.Type] = true
+			}
+		}
+		if !own["network"] {
+			info.NetworkMode = "host"
+		}
+		if !own["pid"] {
+			info.PidMode = "host"
+		}
+
+		// Privileged containers (nerdctl --privileged, Kubernetes
+		// privileged: true) get CAP_SYS_ADMIN and access to every device.
+		allDevices := false
+		for _, d := range linux.Resources.Devices {
+			if d.Allow && (d.Type == "" || d.Type == "a") && d.Major == nil && d.Minor == nil {
+				allDevices = true
+			}
+		}
+		info.Privileged = allDevices && contains(info.Capabilities, "CAP_SYS_ADMIN")
+	}
+	return info, nil
+}
@@ -0,0 +1,234 @@
+package containers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ContainersModule inventories containers and local images of the Docker,
+// Podman and containerd runtimes on the host. Endpoints overrides socket
+// discovery (e.g. to point at a fake API server); leave it empty in normal use.
+type ContainersModule struct {
+	Endpoints []Endpoint
+}
+
+// Endpoint is a Docker-compatible API socket and the runtime behind it.
+type Endpoint struct {
+	Runtime string `json:"runtime"` // docker or podman
+	Socket  string `json:"socket"`
+}
+
+type PortInfo struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      int    `json:"host_port,omitempty"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+}
+
+type MountInfo struct {
+	Type        string `json:"type"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"read_only"`
+}
+
+type ContainerInfo struct {
+	Runtime       string      `json:"runtime"`
+	Namespace     string      `json:"namespace,omitempty"` // containerd only
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Image         string      `json:"image"`
+	ImageID       string      `json:"image_id,omitempty"`
+	ImageDigest   string      `json:"image_digest,omitempty"`
+	Command       string      `json:"command"`
+	State         string      `json:"state"`
+	Status        string      `json:"status,omitempty"`
+	CreatedAt     string      `json:"created_at,omitempty"`
+	User          string      `json:"user,omitempty"`
+	Ports         []PortInfo  `json:"ports,omitempty"`
+	Mounts        []MountInfo `json:"mounts,omitempty"`
+	Privileged    bool        `json:"privileged"`
+	CapAdd        []string    `json:"cap_add,omitempty"`
+	CapDrop       []string    `json:"cap_drop,omitempty"`
+	Capabilities  []string    `json:"capabilities,omitempty"` // full bounding set, when the runtime exposes it
+	NetworkMode   string      `json:"network_mode,omitempty"`
+	PidMode       string      `json:"pid_mode,omitempty"`
+	RestartPolicy string      `json:"restart_policy,omitempty"`
+}
+
+type ImageInfo struct {
+	Runtime     string   `json:"runtime"`
+	Namespace   string   `json:"namespace,omitempty"` // containerd only
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repo_tags,omitempty"`
+	RepoDigests []string `json:"repo_digests,omitempty"`
+	SizeBytes   int64    `json:"size_bytes,omitempty"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+}
+
+type RuntimeInfo struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Version  string `json:"version,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ContainersData struct {
+	Runtimes   []RuntimeInfo   `json:"runtimes"`
+	Containers []ContainerInfo `json:"containers"`
+	Images     []ImageInfo     `json:"images"`
+}
+
+func (m *ContainersModule) Name() string {
+	return "containers"
+}
+
+func (m *ContainersModule) Gather() (interface{}, error) {
+	var data ContainersData
+
+	if runtime.GOOS == "windows" {
+		return data, nil
+	}
+
+	endpoints := m.Endpoints
+	if len(endpoints) == 0 {
+		endpoints = discoverEndpoints()
+	}
+
+	// A runtime that cannot be reached is reported with its error rather
+	// than failing the module, so the other runtimes are still inventoried.
+	for _, ep := range endpoints {
+		client := NewDockerClient(ep.Socket)
+		rt := RuntimeInfo{Name: ep.Runtime, Endpoint: ep.Socket}
+
+		version, err := client.Version()
+		if err != nil {
+			rt.Error = err.Error()
+			data.Runtimes = append(data.Runtimes, rt)
+			continue
+		}
+		rt.Version = version
+
+		images, err := client.Images(ep.Runtime)
+		if err != nil {
+			rt.Error = err.Error()
+		}
+		containers, err := client.Containers(ep.Runtime)
+		if err != nil {
+			rt.Error = err.Error()
+		}
+
+		linkImageDigests(containers, images)
+		data.Images = append(data.Images, images...)
+		data.Containers = append(data.Containers, containers...)
+		data.Runtimes = append(data.Runtimes, rt)
+	}
+
+	if len(m.Endpoints) == 0 && hasContainerd() {
+		rt := RuntimeInfo{Name: "containerd", Endpoint: containerdSocket}
+		if out, err := ctr("version"); err == nil {
+			rt.Version = ctrServerVersion(out)
+		}
+		images, digests := containerdImages()
+		data.Images = append(data.Images, images...)
+		data.Containers = append(data.Containers, containerdContainers(digests)...)
+		data.Runtimes = append(data.Runtimes, rt)
+	}
+
+	return data, nil
+}
+
+const containerdSocket = "/run/containerd/containerd.sock"
+
+// discoverEndpoints finds Docker and Podman API sockets: DOCKER_HOST when it
+// names a unix socket, the standard system sockets, and rootless Podman
+// sockets under each user's runtime dir.
+func discoverEndpoints() []Endpoint {
+	var endpoints []Endpoint
+	seen := make(map[string]bool)
+
+	add := func(rt, socket string) {
+		if seen[socket] {
+			return
+		}
+		if fi, err := os.Stat(socket); err != nil || fi.Mode()&os.ModeSocket == 0 {
+			return
+		}
+		// /var/run/docker.sock is sometimes podman-docker's compatibility link.
+		if target, err := filepath.EvalSymlinks(socket); err == nil && strings.Contains(target, "podman") {
+			rt = "podman"
+			if seen[target] {
+				return
+			}
+			seen[target] = true
+		}
+		seen[socket] = true
+		endpoints = append(endpoints, Endpoint{Runtime: rt, Socket: socket})
+	}
+
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		add("docker", strings.TrimPrefix(host, "unix://"))
+	}
+	add("docker", "/var/run/docker.sock")
+	add("podman", "/run/podman/podman.sock")
+
+	userSockets, _ := filepath.Glob("/run/user/*/podman/podman.sock")
+	for _, s := range userSockets {
+		add("podman", s)
+	}
+
+	return endpoints
+}
+
+func hasContainerd() bool {
+	if _, err := os.Stat(containerdSocket); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("ctr")
+	return err == nil
+}
+
+// ctrServerVersion extracts the daemon version from `ctr version`, which
+// prints a Client: and a Server: section each with a Version: line.
+func ctrServerVersion(out string) string {
+	inServer := false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "Server:" {
+			inServer = true
+			continue
+		}
+		if inServer && strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return ""
+}
+
+// linkImageDigests fills each container's image digest from the image list,
+// since the container list only carries the local image ID.
+func linkImageDigests(containers []ContainerInfo, images []ImageInfo) {
+	digests := make(map[string]string)
+	for _, img := range images {
+		if len(img.RepoDigests) > 0 {
+			if i := strings.Index(img.RepoDigests[0], "@"); i >= 0 {
+				digests[img.ID] = img.RepoDigests[0][i+1:]
+			}
+		}
+	}
+	for i := range containers {
+		containers[i].ImageDigest = digests[containers[i].ImageID]
+	}
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package containers
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeEngine serves a canned Docker Engine API on a unix socket.
+func fakeEngine(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", reply(`{"Version":"27.1.1","ApiVersion":"1.46"}`))
+	mux.HandleFunc("/containers/json", reply(`[{
+		"Id":"c1","Names":["/web"],"Image":"nginx:1.27","ImageID":"sha256:img1",
+		"Command":"nginx -g 'daemon off;'","Created":1700000000,"State":"running","Status":"Up 2 hours",
+		"Ports":[{"IP":"0.0.0.0","PrivatePort":80,"PublicPort":8080,"Type":"tcp"}],
+		"Mounts":[{"Type":"bind","Source":"/srv/www","Destination":"/usr/share/nginx/html","RW":false}]
+	}]`))
+	mux.HandleFunc("/containers/c1/json", reply(`{
+		"Config":{"User":"101"},
+		"HostConfig":{"Privileged":true,"CapAdd":["NET_ADMIN"],"NetworkMode":"bridge","PidMode":"host",
+			"RestartPolicy":{"Name":"unless-stopped"}}
+	}`))
+	mux.HandleFunc("/images/json", reply(`[{
+		"Id":"sha256:img1","RepoTags":["nginx:1.27","<none>:<none>"],
+		"RepoDigests":["nginx@sha256:abc"],"Size":1024,"Created":1700000000
+	}]`))
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return socket
+}
+
+func TestGatherFakeSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the module does not run on windows")
+	}
+	socket := fakeEngine(t)
+	m := &ContainersModule{Endpoints: []Endpoint{{Runtime: "docker", Socket: socket}}}
+
+	raw, err := m.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := raw.(ContainersData)
+
+	if len(data.Runtimes) != 1 || data.Runtimes[0].Version != "27.1.1" || data.Runtimes[0].Error != "" {
+		t.Fatalf("runtimes = %+v", data.Runtimes)
+	}
+	if len(data.Containers) != 1 {
+		t.Fatalf("got %d containers, want 1", len(data.Containers))
+	}
+	c := data.Containers[0]
+	if c.Name != "web" || c.State != "running" || c.User != "101" || c.ImageDigest != "sha256:abc" {
+		t.Errorf("container = %+v", c)
+	}
+	if !c.Privileged || c.RestartPolicy != "unless-stopped" || c.PidMode != "host" {
+		t.Errorf("host config not mapped: %+v", c)
+	}
+	wantPorts := []PortInfo{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}
+	if !reflect.DeepEqual(c.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", c.Ports, wantPorts)
+	}
+	if len(c.Mounts) != 1 || !c.Mounts[0].ReadOnly {
+		t.Errorf("mounts = %+v", c.Mounts)
+	}
+
+	if len(data.Images) != 1 || !reflect.DeepEqual(data.Images[0].RepoTags, []string{"nginx:1.27"}) {
+		t.Errorf("images = %+v", data.Images)
+	}
+}
+
+func TestGatherUnreachableSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the module does not run on windows")
+	}
+	m := &ContainersModule{Endpoints: []Endpoint{{Runtime: "podman", Socket: filepath.Join(t.TempDir(), "missing.sock")}}}
+
+	raw, err := m.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := raw.(ContainersData)
+	if len(data.Runtimes) != 1 || data.Runtimes[0].Error == "" {
+		t.Errorf("runtimes = %+v, want the podman runtime with an error", data.Runtimes)
+	}
+}
+
+func TestParseCtrContainer(t *testing.T) {
+	// Trimmed "ctr containers info" output of a nerdctl --privileged
+	// --net host --restart always -p 8080:80 container.
+	raw := `{
+		"ID":"abc","Image":"docker.io/library/nginx:latest","CreatedAt":"2024-05-01T10:00:00Z",
+		"Labels":{"containerd.io/restart.policy":"always",
+			"nerdctl/ports":"[{\"HostPort\":8080,\"ContainerPort\":80,\"Protocol\":\"tcp\",\"HostIP\":\"0.0.0.0\"}]"},
+		"Spec":{
+			"process":{"args":["nginx"],"user":{"uid":0,"gid":0},
+				"capabilities":{"bounding":["CAP_CHOWN","CAP_SYS_ADMIN"]}},
+			"mounts":[{"destination":"/proc","type":"proc","source":"proc","options":["nosuid"]}],
+			"linux":{
+				"namespaces":[{"type":"pid"},{"type":"ipc"},{"type":"mount"}],
+				"resources":{"devices":[{"allow":true,"access":"rwm"}]}
+			}
+		}
+	}`
+	info, err := parseCtrContainer("default", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Privileged {
+		t.Error("privileged container not detected")
+	}
+	if info.RestartPolicy != "always" {
+		t.Errorf("restart policy = %q", info.RestartPolicy)
+	}
+	if info.NetworkMode != "host" || info.PidMode != "" {
+		t.Errorf("network mode = %q, pid mode = %q", info.NetworkMode, info.PidMode)
+	}
+	wantPorts := []PortInfo{{HostIP: "0.0.0.0", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}
+	if !reflect.DeepEqual(info.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", info.Ports, wantPorts)
+	}
+
+	// A default container: device cgroup denies all, no labels.
+	raw = `{"ID":"def","Spec":{"process":{"capabilities":{"bounding":["CAP_CHOWN"]}},
+		"linux":{"namespaces":[{"type":"pid"},{"type":"network"}],
+			"resources":{"devices":[{"allow":false,"access":"rwm"}]}}}}`
+	info, err = parseCtrContainer("k8s.io", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Privileged || info.RestartPolicy != "" || info.Ports != nil || info.NetworkMode != "" || info.PidMode != "" {
+		t.Errorf("unexpected host configuration: %+v", info)
+	}
+}
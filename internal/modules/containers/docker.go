@@ -0,0 +1,207 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DockerClient speaks the Docker Engine API over a unix socket. Podman's
+// service exposes the same API, so it is used for both runtimes.
+type DockerClient struct {
+	Socket string
+	http   *http.Client
+}
+
+func NewDockerClient(socket string) *DockerClient {
+	return &DockerClient{
+		Socket: socket,
+		http: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+type dockerVersion struct {
+	Version    string `json:"Version"`
+	APIVersion string `json:"ApiVersion"`
+}
+
+type dockerContainer struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Image   string   `json:"Image"`
+	ImageID string   `json:"ImageID"`
+	Command string   `json:"Command"`
+	Created int64    `json:"Created"`
+	State   string   `json:"State"`
+	Status  string   `json:"Status"`
+	Ports   []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+	Mounts []struct {
+		Type        string `json:"Type"`
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+}
+
+type dockerContainerDetail struct {
+	Config struct {
+		User string `json:"User"`
+	} `json:"Config"`
+	HostConfig struct {
+		Privileged    bool     `json:"Privileged"`
+		CapAdd        []string `json:"CapAdd"`
+		CapDrop       []string `json:"CapDrop"`
+		NetworkMode   string   `json:"NetworkMode"`
+		PidMode       string   `json:"PidMode"`
+		RestartPolicy struct {
+			Name              string `json:"Name"`
+			MaximumRetryCount int    `json:"MaximumRetryCount"`
+		} `json:"RestartPolicy"`
+	} `json:"HostConfig"`
+}
+
+type dockerImage struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Size        int64    `json:"Size"`
+	Created     int64    `json:"Created"`
+}
+
+// Version returns the engine version, which also confirms the socket is live.
+func (c *DockerClient) Version() (string, error) {
+	var v dockerVersion
+	if err := c.get("/version", &v); err != nil {
+		return "", err
+	}
+	return v.Version, nil
+}
+
+// Containers lists running and stopped containers with the security-relevant
+// host configuration from each container's inspect output.
+func (c *DockerClient) Containers(runtimeName string) ([]ContainerInfo, error) {
+	var list []dockerContainer
+	if err := c.get("/containers/json?all=1", &list); err != nil {
+		return nil, err
+	}
+
+	var out []ContainerInfo
+	for _, dc := range list {
+		info := ContainerInfo{
+			Runtime: runtimeName,
+			ID:      dc.ID,
+			Image:   dc.Image,
+			ImageID: dc.ImageID,
+			Command: dc.Command,
+			State:   dc.State,
+			Status:  dc.Status,
+		}
+		if len(dc.Names) > 0 {
+			info.Name = strings.TrimPrefix(dc.Names[0], "/")
+		}
+		if dc.Created > 0 {
+			info.CreatedAt = time.Unix(dc.Created, 0).Format(time.RFC3339)
+		}
+		for _, p := range dc.Ports {
+			info.Ports = append(info.Ports, PortInfo{
+				HostIP:        p.IP,
+				HostPort:      p.PublicPort,
+				ContainerPort: p.PrivatePort,
+				Protocol:      p.Type,
+			})
+		}
+		for _, m := range dc.Mounts {
+			info.Mounts = append(info.Mounts, MountInfo{
+				Type:        m.Type,
+				Source:      m.Source,
+				Destination: m.Destination,
+				ReadOnly:    !m.RW,
+			})
+		}
+
+		// Inspect is best-effort: the container may be gone by now.
+		var detail dockerContainerDetail
+		if err := c.get("/containers/"+url.PathEscape(dc.ID)+"/json", &detail); err == nil {
+			info.User = detail.Config.User
+			info.Privileged = detail.HostConfig.Privileged
+			info.CapAdd = detail.HostConfig.CapAdd
+			info.CapDrop = detail.HostConfig.CapDrop
+			info.NetworkMode = detail.HostConfig.NetworkMode
+			info.PidMode = detail.HostConfig.PidMode
+			info.RestartPolicy = detail.HostConfig.RestartPolicy.Name
+		}
+
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// Images lists local images.
+func (c *DockerClient) Images(runtimeName string) ([]ImageInfo, error) {
+	var list []dockerImage
+	if err := c.get("/images/json", &list); err != nil {
+		return nil, err
+	}
+
+	var out []ImageInfo
+	for _, di := range list {
+		info := ImageInfo{
+			Runtime:     runtimeName,
+			ID:          di.ID,
+			RepoTags:    withoutNone(di.RepoTags),
+			RepoDigests: withoutNone(di.RepoDigests),
+			SizeBytes:   di.Size,
+		}
+		if di.Created > 0 {
+			info.CreatedAt = time.Unix(di.Created, 0).Format(time.RFC3339)
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+func (c *DockerClient) get(path string, v interface{}) error {
+	// The host part is ignored by the unix dialer.
+	resp, err := c.http.Get("http://localhost" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s returned status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// withoutNone drops the "<none>:<none>" placeholders docker uses for
+// untagged images.
+func withoutNone(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if !strings.HasPrefix(v, "<none>") {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package eol
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"snapsec-agent/internal/atomicfile"
+)
+
+// The dataset lists the release cycles of each product with their dates,
+// in the spirit of endoflife.date. A bundled copy ships with the agent; the
+// backend can push a newer one, whose products replace the bundled ones.
+
+// DatasetFile is the name of the backend's dataset in the agent's cache
+// directory.
+const DatasetFile = "eol.json"
+
+//go:embed eol.json
+var bundledDataset []byte
+
+type dataset struct {
+	Version  string             `json:"version"`
+	Aliases  map[string]string  `json:"aliases"` // host platform -> product
+	Products map[string]product `json:"products"`
+}
+
+type product struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Link     string  `json:"link,omitempty"`
+	Cycles   []cycle `json:"cycles"`
+}
+
+type cycle struct {
+	Cycle    string `json:"cycle"`
+	Release  string `json:"release,omitempty"`
+	Support  string `json:"support,omitempty"` // end of active support
+	EOL      string `json:"eol,omitempty"`     // end of security support
+	Extended string `json:"extended,omitempty"`
+}
+
+// loadDataset returns the bundled dataset updated from the one at path: its
+// products and aliases replace those of the same name. A missing or invalid
+// file leaves the bundled dataset.
+func loadDataset(path string) *dataset {
+	d := &dataset{
+		Aliases:  make(map[string]string),
+		Products: make(map[string]product),
+	}
+	if err := json.Unmarshal(bundledDataset, d); err != nil {
+		log.Printf("Invalid bundled EOL dataset: %v", err)
+	}
+	if path == "" {
+		return d
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d
+	}
+	var update dataset
+	if err := json.Unmarshal(data, &update); err != nil {
+		return d
+	}
+	if update.Version != "" {
+		d.Version = update.Version
+	}
+	for k, v := range update.Aliases {
+		d.Aliases[k] = v
+	}
+	for k, v := range update.Products {
+		d.Products[k] = v
+	}
+	return d
+}
+
+// SaveDataset checks that data is a dataset and stores it at path, where the
+// eol module and scanner plugin read it.
+func SaveDataset(path string, data []byte) error {
+	var d dataset
+	if err := json.Unmarshal(data, &d); err != nil {
+		return fmt.Errorf("invalid EOL dataset: %w", err)
+	}
+	return atomicfile.WriteIfChanged(path, data)
+}
@@ -0,0 +1,158 @@
+package eol
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Software versions are read by asking each executable, found on PATH or at
+// the fixed locations packages install versioned servers to.
+
+// detector finds the installations of one dataset product.
+type detector struct {
+	product string
+	command []string // executable names tried on PATH; each found one is asked
+	args    []string
+	globs   []string       // further executables, e.g. one per installed major version
+	pattern *regexp.Regexp // its first group is the version
+	exclude string         // output that belongs to another product
+}
+
+var detectors = []detector{
+	{product: "python", command: []string{"python3", "python"}, args: []string{"--version"},
+		pattern: regexp.MustCompile(`Python (\d+\.\d+(?:\.\d+)?)`)},
+	{product: "nodejs", command: []string{"node", "nodejs"}, args: []string{"--version"},
+		pattern: regexp.MustCompile(`^v(\d+\.\d+\.\d+)`)},
+	{product: "php", command: []string{"php"}, args: []string{"-v"},
+		pattern: regexp.MustCompile(`PHP (\d+\.\d+\.\d+)`)},
+	{product: "ruby", command: []string{"ruby"}, args: []string{"-v"},
+		pattern: regexp.MustCompile(`ruby (\d+\.\d+\.\d+)`)},
+	{product: "go", command: []string{"go"}, args: []string{"version"}, globs: []string{"/usr/local/go/bin/go"},
+		pattern: regexp.MustCompile(`go(\d+\.\d+(?:\.\d+)?)`)},
+	// Java 8 and older report 1.8.0_392; the dataset knows them as 8.
+	{product: "java", command: []string{"java"}, args: []string{"-version"},
+		pattern: regexp.MustCompile(`version "(?:1\.)?(\d+(?:\.\d+)*)`)},
+	{product: "nginx", command: []string{"nginx"}, args: []string{"-v"},
+		pattern: regexp.MustCompile(`nginx/(\d+\.\d+\.\d+)`)},
+	{product: "apache-http-server", command: []string{"apache2", "httpd"}, args: []string{"-v"},
+		globs:   []string{"/usr/sbin/apache2", "/usr/sbin/httpd"},
+		pattern: regexp.MustCompile(`Apache/(\d+\.\d+\.\d+)`)},
+	{product: "mysql", command: []string{"mysqld"}, args: []string{"--version"}, globs: []string{"/usr/sbin/mysqld"},
+		pattern: regexp.MustCompile(`Ver (\d+\.\d+\.\d+)`), exclude: "MariaDB"},
+	{product: "mariadb", command: []string{"mariadbd", "mysqld"}, args: []string{"--version"},
+		globs:   []string{"/usr/sbin/mariadbd", "/usr/sbin/mysqld"},
+		pattern: regexp.MustCompile(`Ver (\d+\.\d+\.\d+)-MariaDB`)},
+	{product: "postgresql", command: []string{"postgres"}, args: []string{"--version"},
+		globs:   []string{"/usr/lib/postgresql/*/bin/postgres", "/usr/pgsql-*/bin/postgres"},
+		pattern: regexp.MustCompile(`\(PostgreSQL\) (\d+(?:\.\d+)?)`)},
+	{product: "mongodb", command: []string{"mongod"}, args: []string{"--version"}, globs: []string{"/usr/bin/mongod"},
+		pattern: regexp.MustCompile(`db version v(\d+\.\d+\.\d+)`)},
+}
+
+const versionTimeout = 10 * time.Second
+
+// versionCache remembers what each executable reported, so that inventory
+// pushes and scans do not start every server binary again. An entry is
+// reused while the file at the path is the same one (inode), unmodified.
+var versionCache = struct {
+	sync.Mutex
+	entries map[string]cachedVersion // product + "\x00" + path
+}{entries: make(map[string]cachedVersion)}
+
+type cachedVersion struct {
+	info    os.FileInfo
+	version string
+}
+
+type found struct {
+	product, version, path string
+}
+
+// detectSoftware runs the detectors. An executable reached by several names
+// or symlinks, and further installations of a version already found (e.g.
+// pyenv shims), are reported once.
+func detectSoftware() []found {
+	var out []found
+	for _, d := range detectors {
+		paths := make(map[string]bool)
+		versions := make(map[string]bool)
+		for _, path := range d.candidates() {
+			if resolved, err := filepath.EvalSymlinks(path); err == nil {
+				path = resolved
+			}
+			if paths[path] {
+				continue
+			}
+			paths[path] = true
+			if version := d.cachedVersion(path); version != "" && !versions[version] {
+				versions[version] = true
+				out = append(out, found{product: d.product, version: version, path: path})
+			}
+		}
+	}
+	return out
+}
+
+func (d detector) candidates() []string {
+	var paths []string
+	for _, name := range d.command {
+		if path, err := exec.LookPath(name); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	for _, g := range d.globs {
+		matches, _ := filepath.Glob(g)
+		paths = append(paths, matches...)
+	}
+	return paths
+}
+
+// cachedVersion returns the version path reported when it was last run, or
+// runs it if it has since been replaced or modified.
+func (d detector) cachedVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	key := d.product + "\x00" + path
+
+	versionCache.Lock()
+	c, ok := versionCache.entries[key]
+	versionCache.Unlock()
+	if ok && os.SameFile(c.info, info) && c.info.ModTime().Equal(info.ModTime()) && c.info.Size() == info.Size() {
+		return c.version
+	}
+
+	// Nothing found may be a timeout or a transient failure; try again on
+	// the next check.
+	version := d.version(path)
+	if version == "" {
+		return ""
+	}
+	versionCache.Lock()
+	versionCache.entries[key] = cachedVersion{info: info, version: version}
+	versionCache.Unlock()
+	return version
+}
+
+// version runs the executable; some print their version on stderr.
+func (d detector) version(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, path, d.args...).CombinedOutput()
+	text := string(out)
+	if d.exclude != "" && strings.Contains(text, d.exclude) {
+		return ""
+	}
+	m := d.pattern.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
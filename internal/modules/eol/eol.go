@@ -0,0 +1,196 @@
+package eol
+
+import (
+	"strings"
+	"time"
+
+	"snapsec-agent/internal/modules/host"
+)
+
+// EOLModule reports whether the operating system and the major software on
+// the host (kernel, language runtimes, databases, web servers) are still
+// supported, per an offline end-of-life dataset. DatasetPath is where
+// SaveDataset keeps the backend's updates to the bundled dataset. The eol
+// scanner plugin turns the same report into findings.
+type EOLModule struct {
+	DatasetPath string
+}
+
+// Status of a release.
+const (
+	StatusSupported       = "supported"
+	StatusApproaching     = "approaching_eol"  // end of life within approachingWindow
+	StatusExtendedSupport = "extended_support" // past end of life, in extended support (LTS, ESM, ELS)
+	StatusEOL             = "eol"
+	StatusUnknown         = "unknown" // the release is not in the dataset
+)
+
+const approachingWindow = 180 * 24 * time.Hour
+
+type EOLData struct {
+	Dataset  string     `json:"dataset"` // version of the dataset used
+	Software []Software `json:"software"`
+}
+
+// Software is one product found on the host and the lifecycle of its
+// release. Dates are YYYY-MM-DD; an empty EOLDate with a known Cycle means
+// no end of life has been announced.
+type Software struct {
+	Product  string `json:"product"` // dataset id: ubuntu, linux, python, postgresql
+	Name     string `json:"name"`
+	Category string `json:"category"` // os, kernel, runtime, database, web_server
+	Version  string `json:"version"`
+	Path     string `json:"path,omitempty"` // executable the version was read from
+
+	Cycle       string `json:"cycle,omitempty"` // release line, e.g. 22.04, 3.11
+	ReleaseDate string `json:"release_date,omitempty"`
+	SupportEnd  string `json:"support_end,omitempty"` // end of active support, where it precedes EOLDate
+	EOLDate     string `json:"eol_date,omitempty"`
+	ExtendedEnd string `json:"extended_support_end,omitempty"`
+	Status      string `json:"status"`
+	Link        string `json:"link,omitempty"`
+
+	// SupportedBy names the OS product whose lifecycle applies instead, for
+	// a distribution kernel (one with a release suffix, 5.15.0-91-generic):
+	// the distribution patches it for as long as it supports the release.
+	SupportedBy string `json:"supported_by,omitempty"`
+}
+
+func (m *EOLModule) Name() string {
+	return "eol"
+}
+
+func (m *EOLModule) Gather() (interface{}, error) {
+	return Check(m.DatasetPath, time.Now()), nil
+}
+
+// Check detects the OS and major software and evaluates them against the
+// bundled dataset as updated from datasetPath.
+func Check(datasetPath string, now time.Time) EOLData {
+	d := loadDataset(datasetPath)
+	data := EOLData{Dataset: d.Version, Software: []Software{}}
+
+	osInfo, err := host.DetectOS()
+	if err == nil {
+		osProduct := d.osProduct(osInfo)
+		system := d.evaluate(osProduct, osInfo.Version, now)
+		if system.Name == "" {
+			system.Name = osInfo.Distribution
+		}
+		system.Category = "os"
+		data.Software = append(data.Software, system)
+
+		if osInfo.Name == "linux" && osInfo.Kernel != "" {
+			kernel := d.evaluate("linux", osInfo.Kernel, now)
+			kernel.Category = "kernel"
+			if strings.Contains(osInfo.Kernel, "-") && system.Cycle != "" {
+				kernel.Cycle, kernel.ReleaseDate, kernel.SupportEnd = system.Cycle, "", system.SupportEnd
+				kernel.EOLDate, kernel.ExtendedEnd = system.EOLDate, system.ExtendedEnd
+				kernel.Status, kernel.Link = system.Status, system.Link
+				kernel.SupportedBy = system.Product
+			}
+			data.Software = append(data.Software, kernel)
+		}
+	}
+
+	for _, f := range detectSoftware() {
+		s := d.evaluate(f.product, f.version, now)
+		s.Path = f.path
+		data.Software = append(data.Software, s)
+	}
+	return data
+}
+
+// osProduct maps host.OSData to a dataset product: gopsutil's platform
+// names go through the dataset's aliases (redhat -> rhel).
+func (d *dataset) osProduct(o host.OSData) string {
+	switch o.Name {
+	case "windows":
+		return windowsProduct(o.Distribution)
+	case "darwin":
+		return "macos"
+	}
+	id := strings.ToLower(o.Distribution)
+	if alias, ok := d.Aliases[id]; ok {
+		return alias
+	}
+	return id
+}
+
+// windowsProduct picks the lifecycle of a Windows edition from its product
+// name ("Microsoft Windows 10 Pro", "Microsoft Windows Server 2019
+// Datacenter"). Editions share build numbers but not end-of-life dates, so
+// each has its own dataset product. IoT editions have lifecycles of their own
+// that the dataset does not carry; they are reported as unknown.
+func windowsProduct(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "server"):
+		return "windows-server"
+	case strings.Contains(name, "iot"):
+		return "windows-iot"
+	case strings.Contains(name, "ltsc") || strings.Contains(name, "ltsb"):
+		return "windows-ltsc"
+	case strings.Contains(name, "enterprise") || strings.Contains(name, "education"):
+		return "windows-enterprise"
+	}
+	return "windows"
+}
+
+// evaluate finds the release cycle of version and its status at now.
+func (d *dataset) evaluate(id, version string, now time.Time) Software {
+	s := Software{Product: id, Version: version, Status: StatusUnknown}
+	p, ok := d.Products[id]
+	if !ok {
+		return s
+	}
+	s.Name, s.Category, s.Link = p.Name, p.Category, p.Link
+
+	c, ok := p.match(version)
+	if !ok {
+		return s
+	}
+	s.Cycle, s.ReleaseDate, s.SupportEnd, s.EOLDate, s.ExtendedEnd = c.Cycle, c.Release, c.Support, c.EOL, c.Extended
+	s.Status = c.status(now)
+	return s
+}
+
+// match returns the longest cycle that version belongs to: "3.11" for
+// 3.11.4, "12" for Debian 12.5, "10.0.22631" for "10.0.22631 Build 22631".
+func (p product) match(version string) (cycle, bool) {
+	var best cycle
+	found := false
+	for _, c := range p.Cycles {
+		if !strings.HasPrefix(version, c.Cycle) {
+			continue
+		}
+		if rest := version[len(c.Cycle):]; rest != "" && !strings.ContainsRune(".-_+ ", rune(rest[0])) {
+			continue // 3.1 is not a prefix of 3.11
+		}
+		if !found || len(c.Cycle) > len(best.Cycle) {
+			best, found = c, true
+		}
+	}
+	return best, found
+}
+
+func (c cycle) status(now time.Time) string {
+	eol, ok := parseDate(c.EOL)
+	switch {
+	case !ok:
+		return StatusSupported
+	case now.Before(eol.Add(-approachingWindow)):
+		return StatusSupported
+	case now.Before(eol):
+		return StatusApproaching
+	}
+	if extended, ok := parseDate(c.Extended); ok && now.Before(extended) {
+		return StatusExtendedSupport
+	}
+	return StatusEOL
+}
+
+func parseDate(s string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", s)
+	return t, err == nil
+}
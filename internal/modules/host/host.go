@@ -50,16 +50,27 @@ func (m *HostModule) Gather() (interface{}, error) {
 		UptimeSeconds: info.Uptime,
 	}
 
-	osData := OSData{
+	return map[string]interface{}{
+		"host": hostData,
+		"os":   osData(info),
+	}, nil
+}
+
+// DetectOS returns the operating system as the host_os module reports it.
+func DetectOS() (OSData, error) {
+	info, err := host.Info()
+	if err != nil {
+		return OSData{}, err
+	}
+	return osData(info), nil
+}
+
+func osData(info *host.InfoStat) OSData {
+	return OSData{
 		Name:         runtime.GOOS,
 		Distribution: info.Platform,
 		Version:      info.PlatformVersion,
 		Kernel:       info.KernelVersion,
 		Architecture: runtime.GOARCH,
 	}
-
-	return map[string]interface{}{
-		"host": hostData,
-		"os":   osData,
-	}, nil
 }
@@ -0,0 +1,305 @@
+package logins
+
+import (
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+
+	"snapsec-agent/internal/utmp"
+)
+
+// LoginsModule reports who is logged in, who logged in recently, failed
+// login attempts and boot/shutdown history, from the utmp, wtmp and btmp
+// files that who, last and lastb read. host.HostData has the current
+// uptime; this is the history behind it.
+type LoginsModule struct{}
+
+const (
+	utmpPath = "/var/run/utmp"
+	wtmpPath = "/var/log/wtmp"
+	btmpPath = "/var/log/btmp" // readable by root only
+
+	maxHistory = 200
+	maxBoots   = 50
+	maxFailed  = 50   // of each of the recent, per-user and per-source lists
+	maxCounted = 1000 // names counted per list while reading btmp
+)
+
+type LoginsData struct {
+	Sessions []Session    `json:"sessions"`
+	History  []Login      `json:"history"` // most recent first
+	Boots    []Boot       `json:"boots"`   // most recent first
+	Failed   FailedLogins `json:"failed"`
+}
+
+// Session is a current login from utmp.
+type Session struct {
+	User      string    `json:"user"`
+	TTY       string    `json:"tty"`
+	Host      string    `json:"host,omitempty"` // remote host, or the X display
+	Address   string    `json:"address,omitempty"`
+	PID       int32     `json:"pid"`
+	LoginTime time.Time `json:"login_time"`
+}
+
+// Login is a wtmp login paired with its logout, as last shows it.
+type Login struct {
+	User            string     `json:"user"`
+	TTY             string     `json:"tty"`
+	Host            string     `json:"host,omitempty"`
+	Address         string     `json:"address,omitempty"`
+	LoginTime       time.Time  `json:"login_time"`
+	LogoutTime      *time.Time `json:"logout_time,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds,omitempty"`
+	Status          string     `json:"status"` // logged_in, logged_out, down (ended by a shutdown), crash (ended by a boot without shutdown)
+}
+
+// Boot is a wtmp boot record and the shutdown that ended it.
+type Boot struct {
+	Time         time.Time  `json:"time"`
+	Kernel       string     `json:"kernel,omitempty"`
+	ShutdownTime *time.Time `json:"shutdown_time,omitempty"`
+	Crashed      bool       `json:"crashed"` // followed by another boot without a shutdown
+}
+
+// FailedLogins summarises btmp. Since is the oldest attempt still in the
+// file, which logrotate usually empties monthly.
+type FailedLogins struct {
+	Total    int           `json:"total"`
+	Last24h  int           `json:"last_24h"`
+	Since    *time.Time    `json:"since,omitempty"`
+	ByUser   []FailedCount `json:"by_user,omitempty"`   // most attempts first
+	BySource []FailedCount `json:"by_source,omitempty"` // most attempts first
+	Recent   []FailedLogin `json:"recent,omitempty"`    // most recent first
+}
+
+type FailedCount struct {
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+type FailedLogin struct {
+	User string    `json:"user"`
+	TTY  string    `json:"tty,omitempty"` // ssh:notty for SSH
+	Host string    `json:"host,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+func (m *LoginsModule) Name() string {
+	return "logins"
+}
+
+func (m *LoginsModule) Gather() (interface{}, error) {
+	data := LoginsData{Sessions: []Session{}, History: []Login{}, Boots: []Boot{}}
+
+	if runtime.GOOS != "linux" {
+		return data, nil
+	}
+
+	// wtmp and btmp are streamed through the aggregators, which keep only
+	// what is reported. A missing or unreadable file gives no records.
+	utmp.Read(utmpPath, func(r utmp.Record) {
+		if s, ok := session(r); ok {
+			data.Sessions = append(data.Sessions, s)
+		}
+	})
+
+	h := newHistory()
+	utmp.Read(wtmpPath, h.add)
+	data.History, data.Boots = h.result()
+
+	f := newFailedCounter(time.Now())
+	utmp.Read(btmpPath, f.add)
+	data.Failed = f.result()
+	return data, nil
+}
+
+func session(r utmp.Record) (Session, bool) {
+	if r.Type != utmp.UserProcess || r.User == "" {
+		return Session{}, false
+	}
+	return Session{User: r.User, TTY: r.Line, Host: r.Host, Address: r.Addr, PID: r.PID, LoginTime: r.Time}, true
+}
+
+// history replays wtmp the way last does: a dead process record closes the
+// login on its line, a shutdown closes every open login, and a boot closes
+// whatever a missing shutdown left open. Only the latest logins and boots
+// are kept; a login still open when it falls out is simply not reported.
+type history struct {
+	logins []*Login
+	boots  []*Boot
+	open   map[string]*Login // line -> login
+}
+
+func newHistory() *history {
+	return &history{open: make(map[string]*Login)}
+}
+
+func (h *history) closeAll(t time.Time, status string) {
+	for line, l := range h.open {
+		l.end(t, status)
+		delete(h.open, line)
+	}
+}
+
+func (h *history) add(r utmp.Record) {
+	switch {
+	case r.Type == utmp.UserProcess && r.User != "":
+		if l, ok := h.open[r.Line]; ok {
+			l.end(r.Time, "logged_out")
+		}
+		l := &Login{User: r.User, TTY: r.Line, Host: r.Host, Address: r.Addr, LoginTime: r.Time, Status: "logged_in"}
+		h.open[r.Line] = l
+		h.logins = keepLast(append(h.logins, l), maxHistory)
+
+	case r.Type == utmp.DeadProcess:
+		if l, ok := h.open[r.Line]; ok {
+			l.end(r.Time, "logged_out")
+			delete(h.open, r.Line)
+		}
+
+	case r.Type == utmp.BootTime:
+		if n := len(h.boots); n > 0 && h.boots[n-1].ShutdownTime == nil {
+			h.boots[n-1].Crashed = true
+		}
+		h.closeAll(r.Time, "crash")
+		h.boots = keepLast(append(h.boots, &Boot{Time: r.Time, Kernel: r.Host}), maxBoots)
+
+	case r.Type == utmp.RunLevel && r.User == "shutdown":
+		h.closeAll(r.Time, "down")
+		if n := len(h.boots); n > 0 && h.boots[n-1].ShutdownTime == nil {
+			t := r.Time
+			h.boots[n-1].ShutdownTime = &t
+		}
+	}
+}
+
+// result returns the kept logins and boots, most recent first.
+func (h *history) result() ([]Login, []Boot) {
+	logins := make([]Login, 0, maxHistory)
+	for i := len(h.logins) - 1; i >= 0 && len(logins) < maxHistory; i-- {
+		logins = append(logins, *h.logins[i])
+	}
+	boots := make([]Boot, 0, maxBoots)
+	for i := len(h.boots) - 1; i >= 0 && len(boots) < maxBoots; i-- {
+		boots = append(boots, *h.boots[i])
+	}
+	return logins, boots
+}
+
+// keepLast drops all but the last n elements once s holds twice as many,
+// so that appending stays amortised O(1).
+func keepLast[T any](s []T, n int) []T {
+	if len(s) < 2*n {
+		return s
+	}
+	return append([]T(nil), s[len(s)-n:]...)
+}
+
+func (l *Login) end(t time.Time, status string) {
+	l.LogoutTime = &t
+	l.DurationSeconds = int64(t.Sub(l.LoginTime).Seconds())
+	l.Status = status
+}
+
+// failedCounter summarises btmp record by record.
+type failedCounter struct {
+	now      time.Time
+	f        FailedLogins
+	byUser   map[string]*FailedCount
+	bySource map[string]*FailedCount
+	recent   []FailedLogin
+}
+
+func newFailedCounter(now time.Time) *failedCounter {
+	return &failedCounter{
+		now:      now,
+		byUser:   make(map[string]*FailedCount),
+		bySource: make(map[string]*FailedCount),
+	}
+}
+
+// count adds an attempt by name. A dictionary attack brings a new user name
+// or source with almost every record, so once m holds twice maxCounted names
+// it is cut back to the maxCounted with the most attempts. A name dropped and
+// seen again counts from zero, which only affects names far too rare to be
+// reported.
+func count(m map[string]*FailedCount, name string, t time.Time) {
+	c, ok := m[name]
+	if !ok {
+		if len(m) >= 2*maxCounted {
+			for _, dropped := range rankCounts(m)[maxCounted:] {
+				delete(m, dropped.Name)
+			}
+		}
+		c = &FailedCount{Name: name}
+		m[name] = c
+	}
+	c.Count++
+	if t.After(c.Last) {
+		c.Last = t
+	}
+}
+
+func (fc *failedCounter) add(r utmp.Record) {
+	if r.Type != utmp.LoginProcess && r.Type != utmp.UserProcess {
+		return
+	}
+	f := &fc.f
+	f.Total++
+	if fc.now.Sub(r.Time) <= 24*time.Hour {
+		f.Last24h++
+	}
+	if f.Since == nil || r.Time.Before(*f.Since) {
+		t := r.Time
+		f.Since = &t
+	}
+	source := r.Host
+	if source == "" {
+		source = r.Addr
+	}
+	count(fc.byUser, r.User, r.Time)
+	if source != "" {
+		count(fc.bySource, source, r.Time)
+	}
+	fc.recent = keepLast(append(fc.recent, FailedLogin{User: r.User, TTY: r.Line, Host: source, Time: r.Time}), maxFailed)
+}
+
+func (fc *failedCounter) result() FailedLogins {
+	f := fc.f
+	f.ByUser = topCounts(fc.byUser)
+	f.BySource = topCounts(fc.bySource)
+	recent := fc.recent
+	if len(recent) > maxFailed {
+		recent = recent[len(recent)-maxFailed:]
+	}
+	recent = slices.Clone(recent)
+	slices.Reverse(recent)
+	f.Recent = recent
+	return f
+}
+
+func topCounts(m map[string]*FailedCount) []FailedCount {
+	out := rankCounts(m)
+	if len(out) > maxFailed {
+		out = out[:maxFailed]
+	}
+	return out
+}
+
+// rankCounts sorts the counts by attempts, most first, then by name.
+func rankCounts(m map[string]*FailedCount) []FailedCount {
+	out := make([]FailedCount, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
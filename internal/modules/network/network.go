@@ -1,8 +1,8 @@
 package network
 
 import (
-	"bufio"
-	"os"
+	stdnet "net"
+	"runtime"
 	"strings"
 
 	"github.com/shirou/gopsutil/v3/net"
@@ -11,24 +11,50 @@ import (
 type NetworkModule struct{}
 
 type InterfaceData struct {
-	Name string   `json:"name"`
-	MAC  string   `json:"mac"`
-	IPv4 []string `json:"ipv4"`
-	IPv6 []string `json:"ipv6"`
-	MTU  int      `json:"mtu"`
-	State string  `json:"state"`
+	Name      string        `json:"name"`
+	MAC       string        `json:"mac"`
+	IPv4      []string      `json:"ipv4"`
+	IPv6      []string      `json:"ipv6"`
+	Addresses []AddressData `json:"addresses,omitempty"`
+	MTU       int           `json:"mtu"`
+	State     string        `json:"state"`
+}
+
+// AddressData is one interface address. Origin is dhcp, autoconf, static,
+// link-local or loopback where the OS exposes it, and unknown otherwise.
+type AddressData struct {
+	Address      string `json:"address"`
+	PrefixLength int    `json:"prefix_length"`
+	Family       string `json:"family"`
+	Scope        string `json:"scope,omitempty"`
+	Origin       string `json:"origin"`
 }
 
 type RouteData struct {
+	Family      string `json:"family"`
 	Destination string `json:"destination"`
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
+	Metric      int    `json:"metric"`
+	Default     bool   `json:"default"`
+}
+
+type NeighborData struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+	Family    string `json:"family"`
+	State     string `json:"state"`
 }
 
 type NetworkData struct {
-	Interfaces []InterfaceData `json:"interfaces"`
-	Routes     []RouteData     `json:"routes"`
-	DNS        []string        `json:"dns"`
+	Interfaces       []InterfaceData `json:"interfaces"`
+	Routes           []RouteData     `json:"routes"`
+	DefaultGateway   string          `json:"default_gateway,omitempty"`
+	DefaultGatewayV6 string          `json:"default_gateway_v6,omitempty"`
+	Neighbors        []NeighborData  `json:"neighbors"`
+	DNS              []string        `json:"dns"`
+	Resolver         ResolverData    `json:"resolver"`
 }
 
 func (m *NetworkModule) Name() string {
@@ -41,6 +67,11 @@ func (m *NetworkModule) Gather() (interface{}, error) {
 		return nil, err
 	}
 
+	var addrsByIface map[string][]AddressData
+	if runtime.GOOS == "linux" {
+		addrsByIface = linuxAddresses()
+	}
+
 	var intfData []InterfaceData
 	for _, i := range interfaces {
 		var ipv4, ipv6 []string
@@ -60,40 +91,70 @@ func (m *NetworkModule) Gather() (interface{}, error) {
 			}
 		}
 
+		addresses, ok := addrsByIface[i.Name]
+		if !ok {
+			addresses = addressesFromCIDRs(i.Addrs)
+		}
+
 		intfData = append(intfData, InterfaceData{
-			Name:  i.Name,
-			MAC:   i.HardwareAddr,
-			IPv4:  ipv4,
-			IPv6:  ipv6,
-			MTU:   i.MTU,
-			State: state,
+			Name:      i.Name,
+			MAC:       i.HardwareAddr,
+			IPv4:      ipv4,
+			IPv6:      ipv6,
+			Addresses: addresses,
+			MTU:       i.MTU,
+			State:     state,
 		})
 	}
 
-	// DNS (Linux specific)
-	var dns []string
-	if f, err := os.Open("/etc/resolv.conf"); err == nil {
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "nameserver") {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					dns = append(dns, parts[1])
-				}
-			}
-		}
-		f.Close()
-	}
+	resolver := gatherResolver()
 
-	// Routes (Simplified)
+	// Routes and neighbors are read from the kernel on Linux only.
 	var routes []RouteData
-	// In a real scenario, we'd use netlink on Linux or parse 'route print' on Windows.
-	// For this agent, we'll leave it as a placeholder or implement basic parsing.
+	var neighbors []NeighborData
+	if runtime.GOOS == "linux" {
+		routes = linuxRoutes()
+		neighbors = linuxNeighbors()
+	}
+	gw4, gw6 := defaultGateways(routes)
 
 	return NetworkData{
-		Interfaces: intfData,
-		Routes:     routes,
-		DNS:        dns,
+		Interfaces:       intfData,
+		Routes:           routes,
+		DefaultGateway:   gw4,
+		DefaultGatewayV6: gw6,
+		Neighbors:        neighbors,
+		DNS:              resolver.Nameservers,
+		Resolver:         resolver,
 	}, nil
 }
+
+// addressesFromCIDRs converts gopsutil's "ip/prefix" strings when the OS does
+// not report address origins.
+func addressesFromCIDRs(addrs net.InterfaceAddrList) []AddressData {
+	var out []AddressData
+	for _, a := range addrs {
+		ip, ipnet, err := stdnet.ParseCIDR(a.Addr)
+		if err != nil {
+			continue
+		}
+		prefix, _ := ipnet.Mask.Size()
+		family := "ipv4"
+		if ip.To4() == nil {
+			family = "ipv6"
+		}
+		origin := "unknown"
+		if ip.IsLinkLocalUnicast() {
+			origin = "link-local"
+		} else if ip.IsLoopback() {
+			origin = "loopback"
+		}
+		out = append(out, AddressData{
+			Address:      ip.String(),
+			PrefixLength: prefix,
+			Family:       family,
+			Origin:       origin,
+		})
+	}
+	return out
+}
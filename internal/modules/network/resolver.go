@@ -0,0 +1,70 @@
+package network
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// systemdStub is the local listener systemd-resolved writes into
+// /etc/resolv.conf; the real upstream servers live in its own resolv.conf.
+const systemdStub = "127.0.0.53"
+
+type ResolverData struct {
+	Nameservers     []string `json:"nameservers"`
+	SearchDomains   []string `json:"search_domains,omitempty"`
+	Options         []string `json:"options,omitempty"`
+	SystemdResolved bool     `json:"systemd_resolved"`
+	// UpstreamServers are the servers systemd-resolved forwards to when
+	// Nameservers only lists its local stub.
+	UpstreamServers []string `json:"upstream_servers,omitempty"`
+}
+
+func gatherResolver() ResolverData {
+	data := parseResolvConf("/etc/resolv.conf")
+
+	for _, ns := range data.Nameservers {
+		if ns == systemdStub {
+			data.SystemdResolved = true
+			break
+		}
+	}
+	if data.SystemdResolved {
+		upstream := parseResolvConf("/run/systemd/resolve/resolv.conf")
+		data.UpstreamServers = upstream.Nameservers
+		if len(data.SearchDomains) == 0 {
+			data.SearchDomains = upstream.SearchDomains
+		}
+	}
+
+	return data
+}
+
+// parseResolvConf reads nameserver, search/domain and options lines. A
+// "domain" line is the single-entry form of "search".
+func parseResolvConf(path string) ResolverData {
+	var data ResolverData
+
+	f, err := os.Open(path)
+	if err != nil {
+		return data
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 2 || strings.HasPrefix(parts[0], "#") || strings.HasPrefix(parts[0], ";") {
+			continue
+		}
+		switch parts[0] {
+		case "nameserver":
+			data.Nameservers = append(data.Nameservers, parts[1])
+		case "search", "domain":
+			data.SearchDomains = parts[1:]
+		case "options":
+			data.Options = append(data.Options, parts[1:]...)
+		}
+	}
+	return data
+}
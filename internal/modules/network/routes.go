@@ -0,0 +1,292 @@
+package network
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// --- Linux: routing table ---
+
+// linuxRoutes reads IPv4 routes from /proc/net/route and IPv6 routes from
+// /proc/net/ipv6_route.
+func linuxRoutes() []RouteData {
+	routes := ipv4Routes("/proc/net/route")
+	return append(routes, ipv6Routes("/proc/net/ipv6_route")...)
+}
+
+// ipv4Routes parses /proc/net/route:
+//
+//	Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+//
+// Addresses are little-endian hex.
+func ipv4Routes(path string) []RouteData {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const rtfUp = 0x1
+
+	var routes []RouteData
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		if flags&rtfUp == 0 {
+			continue
+		}
+		dest := leHexIPv4(fields[1])
+		gateway := leHexIPv4(fields[2])
+		mask := leHexIPv4(fields[7])
+		if dest == nil || gateway == nil || mask == nil {
+			continue
+		}
+		prefix, _ := net.IPMask(mask).Size()
+		metric, _ := strconv.Atoi(fields[6])
+
+		r := RouteData{
+			Family:      "ipv4",
+			Destination: (&net.IPNet{IP: dest, Mask: net.IPMask(mask)}).String(),
+			Interface:   fields[0],
+			Metric:      metric,
+			Default:     prefix == 0,
+		}
+		if !gateway.IsUnspecified() {
+			r.Gateway = gateway.String()
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// ipv6Routes parses /proc/net/ipv6_route:
+//
+//	dest dest_prefixlen src src_prefixlen next_hop metric refcnt use flags iface
+//
+// Addresses are plain big-endian hex; prefix length and metric are hex.
+func ipv6Routes(path string) []RouteData {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const (
+		rtfUp    = 0x1
+		rtfLocal = 0x80000000
+	)
+
+	var routes []RouteData
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(fields[8], 16, 32)
+		iface := fields[9]
+		// Skip the loopback and the kernel's local address and multicast routes.
+		if flags&rtfUp == 0 || flags&rtfLocal != 0 || iface == "lo" {
+			continue
+		}
+		dest, err := hex.DecodeString(fields[0])
+		if err != nil || len(dest) != 16 || dest[0] == 0xff {
+			continue
+		}
+		nextHop, err := hex.DecodeString(fields[4])
+		if err != nil || len(nextHop) != 16 {
+			continue
+		}
+		prefix, _ := strconv.ParseUint(fields[1], 16, 8)
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+
+		r := RouteData{
+			Family:      "ipv6",
+			Destination: (&net.IPNet{IP: net.IP(dest), Mask: net.CIDRMask(int(prefix), 128)}).String(),
+			Interface:   iface,
+			Metric:      int(metric),
+			Default:     prefix == 0,
+		}
+		if gw := net.IP(nextHop); !gw.IsUnspecified() {
+			r.Gateway = gw.String()
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// leHexIPv4 decodes a little-endian hex IPv4 address such as "010200C0".
+func leHexIPv4(s string) net.IP {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return nil
+	}
+	return net.IPv4(b[3], b[2], b[1], b[0]).To4()
+}
+
+// defaultGateways returns the gateways of the default routes, lowest metric
+// first per family.
+func defaultGateways(routes []RouteData) (v4, v6 string) {
+	best := map[string]int{}
+	for _, r := range routes {
+		if !r.Default || r.Gateway == "" {
+			continue
+		}
+		if m, ok := best[r.Family]; ok && m <= r.Metric {
+			continue
+		}
+		best[r.Family] = r.Metric
+		if r.Family == "ipv4" {
+			v4 = r.Gateway
+		} else {
+			v6 = r.Gateway
+		}
+	}
+	return v4, v6
+}
+
+// --- Linux: neighbor (ARP/NDP) table ---
+
+// linuxNeighbors uses `ip -j neigh` for both families when iproute2 is
+// installed and falls back to the IPv4-only /proc/net/arp.
+func linuxNeighbors() []NeighborData {
+	if _, err := exec.LookPath("ip"); err == nil {
+		if out, err := exec.Command("ip", "-j", "neigh", "show").Output(); err == nil {
+			var entries []struct {
+				Dst    string   `json:"dst"`
+				Dev    string   `json:"dev"`
+				Lladdr string   `json:"lladdr"`
+				State  []string `json:"state"`
+			}
+			if json.Unmarshal(out, &entries) == nil {
+				var neighbors []NeighborData
+				for _, e := range entries {
+					if e.Lladdr == "" {
+						continue // incomplete or failed resolution
+					}
+					family := "ipv4"
+					if strings.Contains(e.Dst, ":") {
+						family = "ipv6"
+					}
+					neighbors = append(neighbors, NeighborData{
+						IP:        e.Dst,
+						MAC:       e.Lladdr,
+						Interface: e.Dev,
+						Family:    family,
+						State:     strings.ToLower(strings.Join(e.State, ",")),
+					})
+				}
+				return neighbors
+			}
+		}
+	}
+	return procARP("/proc/net/arp")
+}
+
+// procARP parses /proc/net/arp:
+//
+//	IP address HW type Flags HW address Mask Device
+func procARP(path string) []NeighborData {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const atfCom = 0x2 // entry is complete
+	const atfPerm = 0x4
+
+	var neighbors []NeighborData
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if flags&atfCom == 0 {
+			continue
+		}
+		state := "reachable"
+		if flags&atfPerm != 0 {
+			state = "permanent"
+		}
+		neighbors = append(neighbors, NeighborData{
+			IP:        fields[0],
+			MAC:       fields[3],
+			Interface: fields[5],
+			Family:    "ipv4",
+			State:     state,
+		})
+	}
+	return neighbors
+}
+
+// --- Linux: per-interface addressing ---
+
+// linuxAddresses returns each interface's addresses with prefix length and
+// origin from `ip -j addr`. The kernel marks addresses obtained from DHCP or
+// SLAAC as dynamic (finite lifetime); configured ones never expire.
+func linuxAddresses() map[string][]AddressData {
+	if _, err := exec.LookPath("ip"); err != nil {
+		return nil
+	}
+	out, err := exec.Command("ip", "-j", "addr", "show").Output()
+	if err != nil {
+		return nil
+	}
+	var links []struct {
+		Ifname   string `json:"ifname"`
+		AddrInfo []struct {
+			Family    string `json:"family"`
+			Local     string `json:"local"`
+			Prefixlen int    `json:"prefixlen"`
+			Scope     string `json:"scope"`
+			Dynamic   bool   `json:"dynamic"`
+		} `json:"addr_info"`
+	}
+	if err := json.Unmarshal(out, &links); err != nil {
+		return nil
+	}
+
+	addrs := make(map[string][]AddressData)
+	for _, l := range links {
+		for _, a := range l.AddrInfo {
+			family := "ipv4"
+			if a.Family == "inet6" {
+				family = "ipv6"
+			}
+			origin := "static"
+			switch {
+			case a.Scope == "link":
+				origin = "link-local"
+			case a.Scope == "host":
+				origin = "loopback"
+			case a.Dynamic && family == "ipv4":
+				origin = "dhcp"
+			case a.Dynamic:
+				origin = "autoconf" // SLAAC or DHCPv6
+			}
+			addrs[l.Ifname] = append(addrs[l.Ifname], AddressData{
+				Address:      a.Local,
+				PrefixLength: a.Prefixlen,
+				Family:       family,
+				Scope:        a.Scope,
+				Origin:       origin,
+			})
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,62 @@
+package packages
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+)
+
+// --- Linux: apk (Alpine) ---
+// The installed database is a series of blank-line separated records of
+// "X:value" lines; see apk-tools' "Package index format".
+
+const apkInstalledPath = "/lib/apk/db/installed"
+
+func gatherApk(path string) []PackageInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var pkgs []PackageInfo
+	var p PackageInfo
+	flush := func() {
+		if p.Name != "" {
+			pkgs = append(pkgs, p)
+		}
+		p = PackageInfo{}
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		v := line[2:]
+		switch line[0] {
+		case 'P':
+			p.Name = v
+		case 'V':
+			p.Version = v
+		case 'A':
+			p.Arch = v
+		case 'U':
+			p.Homepage = v
+		case 'm':
+			p.Maintainer = v
+		case 'o':
+			p.SourcePackage = v
+		case 'I':
+			p.InstalledSize, _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+	flush()
+	return pkgs
+}
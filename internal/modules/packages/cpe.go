@@ -0,0 +1,354 @@
+package packages
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"unicode"
+
+	"snapsec-agent/internal/atomicfile"
+)
+
+// CPE 2.3 names (cpe:2.3:part:vendor:product:version:...) are what NVD
+// indexes vulnerabilities by. Package names do not say who the vendor is, so
+// a mapping table settles it for well-known products and the rest is
+// derived from package metadata, with a confidence that says how.
+
+// Confidence of a generated CPE, from a table hit on the package's own name
+// down to a guess that the vendor is the product.
+const (
+	cpeConfidenceName    = 0.9 // the package name is in the table
+	cpeConfidenceSource  = 0.8 // its source package is
+	cpeConfidenceVendor  = 0.6 // vendor from a bundle id, publisher or homepage domain in the table
+	cpeConfidenceDerived = 0.4 // vendor from a namespace, code host owner or homepage domain
+	cpeConfidenceGuess   = 0.2 // vendor taken to be the product
+)
+
+//go:embed cpe_mappings.json
+var bundledCPEMappings []byte
+
+// cpeMappings is the mapping table. Products is keyed by "<scope>/<name>":
+// the scope is "os" for distribution and Homebrew packages, "app" for
+// desktop applications, or the ecosystem of a language package. Names are
+// lowercase; pypi names are normalised and apps have versions and
+// parentheses removed. Domains map a homepage host (or a parent domain) to
+// a vendor; Publishers map a lowercase publisher to one.
+type cpeMappings struct {
+	Version    string                `json:"version"`
+	Products   map[string]cpeProduct `json:"products"`
+	Domains    map[string]string     `json:"domains"`
+	Publishers map[string]string     `json:"publishers"`
+}
+
+type cpeProduct struct {
+	Part     string `json:"part,omitempty"` // a (default) or o
+	Vendor   string `json:"vendor"`
+	Product  string `json:"product"`
+	TargetSW string `json:"target_sw,omitempty"`
+}
+
+// loadCPEMappings returns the bundled table, with each product, domain and
+// publisher the backend's table at path defines replacing the bundled entry.
+// A missing or invalid file leaves the bundled table.
+func loadCPEMappings(path string) *cpeMappings {
+	m := &cpeMappings{
+		Products:   make(map[string]cpeProduct),
+		Domains:    make(map[string]string),
+		Publishers: make(map[string]string),
+	}
+	if err := json.Unmarshal(bundledCPEMappings, m); err != nil {
+		log.Printf("Invalid bundled CPE mappings: %v", err)
+	}
+	if path == "" {
+		return m
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m
+	}
+	var update cpeMappings
+	if err := json.Unmarshal(data, &update); err != nil {
+		return m
+	}
+	if update.Version != "" {
+		m.Version = update.Version
+	}
+	for k, v := range update.Products {
+		m.Products[k] = v
+	}
+	for k, v := range update.Domains {
+		m.Domains[k] = v
+	}
+	for k, v := range update.Publishers {
+		m.Publishers[k] = v
+	}
+	return m
+}
+
+// SaveCPEMappings checks that data is a mapping table and stores it at path
+// for the next inventory.
+func SaveCPEMappings(path string, data []byte) error {
+	var m cpeMappings
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("invalid CPE mappings: %w", err)
+	}
+	return atomicfile.WriteIfChanged(path, data)
+}
+
+// apply sets the CPE and its confidence on p. Packages without a version
+// are left alone, as a CPE without one matches every release.
+func (m *cpeMappings) apply(p *PackageInfo) {
+	version := cpeVersion(p)
+	if version == "" {
+		return
+	}
+	prod, confidence := m.resolve(p)
+	if prod.Vendor == "" || prod.Product == "" {
+		return
+	}
+	part := prod.Part
+	if part == "" {
+		part = "a"
+	}
+	targetSW := "*"
+	if prod.TargetSW != "" {
+		targetSW = cpeEscape(prod.TargetSW)
+	}
+	p.CPE = "cpe:2.3:" + part + ":" + cpeEscape(prod.Vendor) + ":" + cpeEscape(prod.Product) + ":" +
+		cpeEscape(version) + ":*:*:*:*:" + targetSW + ":*:*"
+	p.CPEConfidence = confidence
+}
+
+// resolve finds the vendor and product of p: in the table by name, then by
+// source package, then from metadata.
+func (m *cpeMappings) resolve(p *PackageInfo) (cpeProduct, float64) {
+	scope, name := cpeScope(p)
+	if prod, ok := m.Products[scope+"/"+name]; ok {
+		return prod, cpeConfidenceName
+	}
+	if src := strings.ToLower(p.SourcePackage); src != "" && src != name {
+		if prod, ok := m.Products[scope+"/"+src]; ok {
+			return prod, cpeConfidenceSource
+		}
+	}
+
+	product := cpeProductName(scope, name)
+	if p.Ecosystem != "" {
+		if vendor := namespaceVendor(p.Ecosystem, name); vendor != "" {
+			return cpeProduct{Vendor: vendor, Product: product}, cpeConfidenceDerived
+		}
+	}
+	if scope == "app" {
+		if vendor := m.Publishers[strings.ToLower(p.Publisher)]; vendor != "" {
+			return cpeProduct{Vendor: vendor, Product: strings.TrimPrefix(product, vendor+"_")}, cpeConfidenceVendor
+		}
+		if p.Vendor != "" { // from the bundle id
+			return cpeProduct{Vendor: p.Vendor, Product: strings.TrimPrefix(product, p.Vendor+"_")}, cpeConfidenceVendor
+		}
+	}
+	if vendor, confidence := m.homepageVendor(p.Homepage); vendor != "" {
+		return cpeProduct{Vendor: vendor, Product: product}, confidence
+	}
+	if scope == "app" && p.Publisher != "" {
+		if vendor := cpeToken(strings.Fields(p.Publisher)[0]); vendor != "" {
+			return cpeProduct{Vendor: vendor, Product: strings.TrimPrefix(product, vendor+"_")}, cpeConfidenceDerived
+		}
+	}
+	return cpeProduct{Vendor: product, Product: product}, cpeConfidenceGuess
+}
+
+// cpeScope returns the table scope of p and its name as the table keys it.
+func cpeScope(p *PackageInfo) (string, string) {
+	name := strings.ToLower(p.Name)
+	switch {
+	case p.Ecosystem == "pypi":
+		return p.Ecosystem, pypiNameSeparators.ReplaceAllString(name, "-")
+	case p.Ecosystem != "":
+		return p.Ecosystem, name
+	}
+	switch p.Source {
+	case "apt", "rpm", "apk", "pacman":
+		return "os", name
+	case "brew":
+		name, _, _ = strings.Cut(name, "@") // openssl@3
+		return "os", name
+	}
+	if p.Product != "" {
+		name = strings.ToLower(p.Product)
+	}
+	return "app", appName(name)
+}
+
+// appName drops what desktop application names add to the product:
+// "Mozilla Firefox (x64 en-US)" and "7-Zip 23.01 (x64)" become
+// "mozilla firefox" and "7-zip".
+func appName(name string) string {
+	name = strings.NewReplacer("(r)", "", "(tm)", "", "®", "", "™", "").Replace(name)
+	if i := strings.Index(name, " ("); i > 0 {
+		name = name[:i]
+	}
+	fields := strings.Fields(name)
+	for len(fields) > 1 {
+		last := fields[len(fields)-1]
+		if !strings.ContainsAny(last, "0123456789") || strings.Trim(last, "0123456789.-v") != "" {
+			break
+		}
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+// cpeProductName turns a name into a product token: the artifact of a maven
+// coordinate, the last element of a module path, spaces as underscores.
+func cpeProductName(scope, name string) string {
+	switch scope {
+	case "maven", "golang", "composer", "npm":
+		if i := strings.LastIndexAny(name, ":/"); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// namespaceVendor derives a vendor from a language package's namespace:
+// org.apache.commons:commons-text -> apache, @angular/core -> angular,
+// github.com/owner/repo -> owner, vendor/package -> vendor.
+func namespaceVendor(ecosystem, name string) string {
+	switch ecosystem {
+	case "maven":
+		group, _, ok := strings.Cut(name, ":")
+		if !ok {
+			return ""
+		}
+		labels := strings.Split(group, ".")
+		if len(labels) >= 2 {
+			switch labels[0] {
+			case "org", "com", "io", "net", "dev", "co":
+				return cpeToken(labels[1])
+			}
+		}
+		return cpeToken(labels[0])
+	case "npm":
+		if scope, _, ok := strings.Cut(name, "/"); ok {
+			return cpeToken(strings.TrimPrefix(scope, "@"))
+		}
+	case "golang":
+		parts := strings.Split(name, "/")
+		if len(parts) >= 3 && codeHosts[parts[0]] {
+			return cpeToken(parts[1])
+		}
+	case "composer":
+		if vendor, _, ok := strings.Cut(name, "/"); ok {
+			return cpeToken(vendor)
+		}
+	}
+	return ""
+}
+
+// codeHosts are hosts whose first path element is the owner of a project.
+var codeHosts = map[string]bool{"github.com": true, "gitlab.com": true, "bitbucket.org": true, "codeberg.org": true}
+
+// homepageVendor derives a vendor from a homepage: the table's vendor for
+// the host or a parent domain, the owner on a code host, or the label left
+// of the public suffix.
+func (m *cpeMappings) homepageVendor(homepage string) (string, float64) {
+	u, err := url.Parse(homepage)
+	if err != nil || u.Hostname() == "" {
+		return "", 0
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for d := host; d != ""; {
+		if vendor := m.Domains[d]; vendor != "" {
+			return vendor, cpeConfidenceVendor
+		}
+		_, rest, ok := strings.Cut(d, ".")
+		if !ok {
+			break
+		}
+		d = rest
+	}
+	if codeHosts[host] {
+		if owner, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); owner != "" {
+			return cpeToken(owner), cpeConfidenceDerived
+		}
+		return "", 0
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return "", 0
+	}
+	label := labels[len(labels)-2]
+	// example.co.uk, example.com.au
+	if len(labels) >= 3 && (label == "co" || label == "com" || label == "org" || label == "ac") && len(labels[len(labels)-1]) == 2 {
+		label = labels[len(labels)-3]
+	}
+	return cpeToken(label), cpeConfidenceDerived
+}
+
+// cpeVersion is the upstream version of p, as NVD records versions: without
+// the Debian epoch and revision, the apk -rN or the pacman pkgrel.
+func cpeVersion(p *PackageInfo) string {
+	v := p.Version
+	switch p.Source {
+	case "apt", "pacman":
+		if _, rest, ok := strings.Cut(v, ":"); ok {
+			v = rest
+		}
+		if i := strings.LastIndex(v, "-"); i > 0 {
+			v = v[:i]
+		}
+		for _, marker := range []string{"+dfsg", ".dfsg", "+ds", "~"} {
+			if i := strings.Index(v, marker); i > 0 {
+				v = v[:i]
+			}
+		}
+	case "apk":
+		if i := strings.LastIndex(v, "-r"); i > 0 {
+			v = v[:i]
+		}
+	}
+	if p.Ecosystem == "golang" {
+		v = strings.TrimPrefix(v, "v")
+	}
+	return v
+}
+
+// cpeToken lowercases s and keeps the characters a vendor token has.
+func cpeToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r > unicode.MaxASCII:
+			return -1
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case r == '-' || r == '_':
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// cpeEscape quotes a CPE 2.3 formatted string component: lowercase,
+// spaces as underscores, and a backslash before any character other than
+// letters, digits, '_', '-' and '.'.
+func cpeEscape(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r > unicode.MaxASCII || !unicode.IsPrint(r):
+			// not representable
+		case r == ' ':
+			b.WriteByte('_')
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,246 @@
+package packages
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Linux: dpkg (Debian/Ubuntu) ---
+// Read from dpkg's status database, with install times from dpkg.log and
+// the origin repository from apt's package lists.
+
+const (
+	dpkgStatusPath = "/var/lib/dpkg/status"
+	dpkgInfoDir    = "/var/lib/dpkg/info"
+	dpkgLogGlob    = "/var/log/dpkg.log*"
+	aptListsGlob   = "/var/lib/apt/lists/*_Packages*"
+)
+
+func gatherDpkg() []PackageInfo {
+	var pkgs []PackageInfo
+	readStanzas(dpkgStatusPath, func(f map[string]string) {
+		// "install ok installed"; removed packages linger as "config-files".
+		status := strings.Fields(f["Status"])
+		if len(status) != 3 || status[2] == "not-installed" || status[2] == "config-files" {
+			return
+		}
+		p := PackageInfo{
+			Name:       f["Package"],
+			Version:    f["Version"],
+			Arch:       f["Architecture"],
+			Maintainer: f["Maintainer"],
+			Homepage:   f["Homepage"],
+		}
+		// "Source: glibc (2.36-9)"; without the field the source package
+		// has the binary's name.
+		p.SourcePackage = p.Name
+		if src := strings.Fields(f["Source"]); len(src) > 0 {
+			p.SourcePackage = src[0]
+		}
+		if kib, err := strconv.ParseInt(f["Installed-Size"], 10, 64); err == nil {
+			p.InstalledSize = kib * 1024
+		}
+		if p.Name != "" {
+			pkgs = append(pkgs, p)
+		}
+	})
+
+	times := dpkgInstallTimes(dpkgLogGlob)
+	origins := cachedAptOrigins(aptListsGlob, pkgs)
+	for i := range pkgs {
+		p := &pkgs[i]
+		t, ok := times[p.Name+":"+p.Arch]
+		if !ok {
+			t = dpkgListTime(p.Name, p.Arch)
+		}
+		if !t.IsZero() {
+			p.InstalledAt = t.UTC().Format(time.RFC3339)
+		}
+		p.Repository = origins[aptKey(p.Name, p.Version, p.Arch)]
+	}
+	return pkgs
+}
+
+// readStanzas calls fn with the fields of each blank-line separated stanza
+// of a deb822 file. Continuation lines are dropped, which loses only
+// multi-line fields such as Description and Conffiles.
+func readStanzas(path string, fn func(map[string]string)) {
+	r, err := openText(path)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(fields) > 0 {
+				fn(fields)
+				fields = make(map[string]string)
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[k] = strings.TrimSpace(v)
+		}
+	}
+	if len(fields) > 0 {
+		fn(fields)
+	}
+}
+
+// dpkgInstallTimes returns when each package ("name:arch") last reached
+// the installed state, from dpkg.log and its rotations:
+//
+//	2024-05-01 10:00:00 status installed libc6:amd64 2.36-9
+func dpkgInstallTimes(pattern string) map[string]time.Time {
+	times := make(map[string]time.Time)
+	logs, _ := filepath.Glob(pattern)
+	for _, path := range logs {
+		r, err := openText(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 6 || fields[2] != "status" || fields[3] != "installed" {
+				continue
+			}
+			t, err := time.ParseInLocation("2006-01-02 15:04:05", fields[0]+" "+fields[1], time.Local)
+			if err != nil {
+				continue
+			}
+			if t.After(times[fields[4]]) {
+				times[fields[4]] = t
+			}
+		}
+		r.Close()
+	}
+	return times
+}
+
+// openText opens a plain or gzip-compressed file. Other compressions (apt
+// can be configured to keep its lists as .lz4 or .xz) are not supported.
+func openText(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{gz, f}, nil
+	case ".lz4", ".xz", ".zst", ".bz2":
+		f.Close()
+		return nil, fmt.Errorf("unsupported compression: %s", path)
+	}
+	return f, nil
+}
+
+// dpkgListTime falls back to the modification time of the package's file
+// list, which dpkg rewrites on every install or upgrade. Multi-arch
+// packages name it <name>:<arch>.list.
+func dpkgListTime(name, arch string) time.Time {
+	for _, file := range []string{name + ":" + arch + ".list", name + ".list"} {
+		if fi, err := os.Stat(filepath.Join(dpkgInfoDir, file)); err == nil {
+			return fi.ModTime()
+		}
+	}
+	return time.Time{}
+}
+
+func aptKey(name, version, arch string) string {
+	return name + " " + version + " " + arch
+}
+
+// aptOriginsCache keeps the last aptOrigins result. The lists can exceed
+// 100MB, so they are parsed again only when a list or the dpkg status file
+// (and with it the installed set) changed.
+var aptOriginsCache = struct {
+	sync.Mutex
+	stamp   string
+	origins map[string]string
+}{}
+
+func cachedAptOrigins(pattern string, installed []PackageInfo) map[string]string {
+	lists, _ := filepath.Glob(pattern)
+	sort.Strings(lists)
+	var stamp strings.Builder
+	for _, path := range append([]string{dpkgStatusPath}, lists...) {
+		if fi, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&stamp, "%s %d %d\n", path, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+
+	aptOriginsCache.Lock()
+	defer aptOriginsCache.Unlock()
+	if aptOriginsCache.origins == nil || aptOriginsCache.stamp != stamp.String() {
+		aptOriginsCache.origins = aptOrigins(pattern, installed)
+		aptOriginsCache.stamp = stamp.String()
+	}
+	return aptOriginsCache.origins
+}
+
+// aptOrigins maps the installed packages to the first apt list, in name
+// order, offering their exact version. Packages installed from a .deb file
+// or from a repository since removed have none.
+func aptOrigins(pattern string, installed []PackageInfo) map[string]string {
+	wanted := make(map[string]bool, len(installed))
+	for _, p := range installed {
+		wanted[aptKey(p.Name, p.Version, p.Arch)] = true
+	}
+	origins := make(map[string]string)
+	lists, _ := filepath.Glob(pattern)
+	sort.Strings(lists)
+	for _, path := range lists {
+		repo := aptListRepository(filepath.Base(path))
+		readStanzas(path, func(f map[string]string) {
+			key := aptKey(f["Package"], f["Version"], f["Architecture"])
+			if wanted[key] {
+				if _, ok := origins[key]; !ok {
+					origins[key] = repo
+				}
+			}
+		})
+	}
+	return origins
+}
+
+// aptListRepository names a repository after its list file:
+// deb.debian.org_debian_dists_bookworm-security_main_binary-amd64_Packages
+// becomes "deb.debian.org/debian bookworm-security/main".
+func aptListRepository(file string) string {
+	file = strings.TrimSuffix(strings.TrimSuffix(file, ".gz"), "_Packages")
+	base, dist, ok := strings.Cut(file, "_dists_")
+	if !ok {
+		return strings.ReplaceAll(file, "_", "/") // flat repository
+	}
+	parts := strings.Split(dist, "_")
+	if len(parts) > 1 && strings.HasPrefix(parts[len(parts)-1], "binary-") {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.ReplaceAll(base, "_", "/") + " " + strings.Join(parts, "/")
+}
@@ -0,0 +1,102 @@
+package packages
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Linux: flatpak ---
+// Installations keep each ref as <kind>/<id>/<arch>/<branch>/active, a link
+// to the deployed commit. The version is the newest release in the
+// AppStream metainfo the app ships; runtimes and apps without one report
+// their branch.
+
+// flatpakInstallations are the system installation and per-user ones.
+var flatpakInstallations = []string{"/var/lib/flatpak", "/root/.local/share/flatpak", "/home/*/.local/share/flatpak"}
+
+type appStream struct {
+	Names      []localized `xml:"name"`
+	Developers []localized `xml:"developer_name"`
+	URLs       []struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"url"`
+	Releases []struct {
+		Version string `xml:"version,attr"`
+	} `xml:"releases>release"`
+}
+
+// localized is an element repeated once per translation; the untranslated
+// one has no xml:lang.
+type localized struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+func untranslated(values []localized) string {
+	for _, v := range values {
+		if v.Lang == "" {
+			return strings.TrimSpace(v.Value)
+		}
+	}
+	return ""
+}
+
+func gatherFlatpaks(installations []string) []PackageInfo {
+	var pkgs []PackageInfo
+	for _, pattern := range installations {
+		dirs, _ := filepath.Glob(pattern)
+		for _, dir := range dirs {
+			for _, kind := range []string{"app", "runtime"} {
+				refs, _ := filepath.Glob(filepath.Join(dir, kind, "*", "*", "*", "active"))
+				for _, active := range refs {
+					pkgs = append(pkgs, flatpakRef(active))
+				}
+			}
+		}
+	}
+	return pkgs
+}
+
+// flatpakRef describes the ref deployed at .../<id>/<arch>/<branch>/active.
+func flatpakRef(active string) PackageInfo {
+	branchDir := filepath.Dir(active)
+	archDir := filepath.Dir(branchDir)
+	id := filepath.Base(filepath.Dir(archDir))
+	p := PackageInfo{
+		Name:    id,
+		Version: filepath.Base(branchDir),
+		Arch:    filepath.Base(archDir),
+		Vendor:  vendorFromBundleID(id),
+		Path:    active,
+	}
+
+	for _, dir := range []string{"metainfo", "appdata"} {
+		matches, _ := filepath.Glob(filepath.Join(active, "files", "share", dir, id+".*.xml"))
+		if len(matches) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(matches[0])
+		if err != nil {
+			continue
+		}
+		var meta appStream
+		if xml.Unmarshal(data, &meta) != nil {
+			continue
+		}
+		if len(meta.Releases) > 0 && meta.Releases[0].Version != "" {
+			p.Version = meta.Releases[0].Version // newest first, per the spec
+		}
+		p.Product = untranslated(meta.Names)
+		p.Publisher = untranslated(meta.Developers)
+		for _, u := range meta.URLs {
+			if u.Type == "homepage" {
+				p.Homepage = strings.TrimSpace(u.Value)
+			}
+		}
+		break
+	}
+	return p
+}
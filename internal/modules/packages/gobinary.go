@@ -0,0 +1,212 @@
+package packages
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Go executables embed their build info: the toolchain version, the main
+// module and every dependency module with its version and go.sum hash. That
+// is what govulncheck matches in binary mode, so it is reported as is.
+
+// DefaultBinaryPaths are searched for Go executables when the config sets no
+// binary_paths. Executables of running processes are always inspected.
+var DefaultBinaryPaths = map[string][]string{
+	"linux":   {"/usr/local/bin", "/usr/local/sbin", "/usr/bin", "/usr/sbin", "/opt", "/root/go/bin", "/home/*/go/bin"},
+	"darwin":  {"/usr/local/bin", "/opt/homebrew/bin", "/opt", "/Users/*/go/bin"},
+	"windows": {`C:\Program Files`, `C:\ProgramData`, `C:\Users\*\go\bin`},
+}
+
+// maxBinaryWalkEntries bounds the walk of each binary path.
+const maxBinaryWalkEntries = 200000
+
+// GoBinary is a Go executable and the modules it was built from.
+type GoBinary struct {
+	Path      string     `json:"path"`
+	GoVersion string     `json:"go_version"`
+	Module    GoModule   `json:"module"`
+	Deps      []GoModule `json:"deps,omitempty"`
+	// Settings holds the build settings worth inventorying: GOOS, GOARCH,
+	// CGO_ENABLED, -trimpath and the VCS revision.
+	Settings map[string]string `json:"settings,omitempty"`
+	PIDs     []int32           `json:"pids,omitempty"` // running processes of this executable
+}
+
+// GoModule is one module of a Go build. Replace is set when a replace
+// directive substituted another module or a local directory.
+type GoModule struct {
+	Path    string    `json:"path"`
+	Version string    `json:"version,omitempty"`
+	Sum     string    `json:"sum,omitempty"`
+	PURL    string    `json:"purl,omitempty"`
+	Replace *GoModule `json:"replace,omitempty"`
+}
+
+var goBuildSettings = map[string]bool{
+	"GOOS": true, "GOARCH": true, "CGO_ENABLED": true, "-trimpath": true,
+	"vcs": true, "vcs.revision": true, "vcs.time": true, "vcs.modified": true,
+}
+
+// goBinaryCache remembers the build info read from each executable, or that
+// it had none, so an unchanged file is not parsed again on every push. The
+// entries of files no longer found are dropped after each gather.
+var goBinaryCache = struct {
+	sync.Mutex
+	entries map[string]goBinaryEntry
+}{entries: make(map[string]goBinaryEntry)}
+
+type goBinaryEntry struct {
+	info os.FileInfo
+	bin  GoBinary
+	ok   bool
+}
+
+// gatherGoBinaries reads the build info of executables below paths and of
+// running processes. Each executable is reported once, by its resolved path.
+func gatherGoBinaries(paths []string) []GoBinary {
+	if len(paths) == 0 {
+		paths = DefaultBinaryPaths[runtime.GOOS]
+	}
+
+	goBinaryCache.Lock()
+	defer goBinaryCache.Unlock()
+	prev := goBinaryCache.entries
+	next := make(map[string]goBinaryEntry)
+	defer func() { goBinaryCache.entries = next }()
+
+	byPath := make(map[string]*GoBinary)
+	checked := make(map[string]bool)
+	inspect := func(path string) *GoBinary {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			resolved = path
+		}
+		if checked[resolved] {
+			return byPath[resolved]
+		}
+		checked[resolved] = true
+		if b, ok := cachedGoBinary(resolved, prev, next); ok {
+			byPath[resolved] = &b
+		}
+		return byPath[resolved]
+	}
+
+	if procs, err := process.Processes(); err == nil {
+		for _, p := range procs {
+			exe, err := p.Exe()
+			if err != nil || exe == "" {
+				continue
+			}
+			path := exe
+			if runtime.GOOS == "linux" && strings.HasSuffix(exe, " (deleted)") {
+				// Replaced on disk (e.g. by an upgrade); the running
+				// image is still readable through procfs.
+				path = fmt.Sprintf("/proc/%d/exe", p.Pid)
+			}
+			if b := inspect(path); b != nil {
+				if path != exe {
+					b.Path = exe
+				}
+				b.PIDs = append(b.PIDs, p.Pid)
+			}
+		}
+	}
+
+	for _, dir := range globAll(paths) {
+		walkRoot(dir, maxBinaryWalkEntries, nil, func(path string, d fs.DirEntry) error {
+			if d.Type().IsRegular() && isExecutableFile(d) {
+				inspect(path)
+			}
+			return nil
+		})
+	}
+
+	bins := make([]GoBinary, 0, len(byPath))
+	for _, b := range byPath {
+		bins = append(bins, *b)
+	}
+	sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+	return bins
+}
+
+// isExecutableFile filters the walk before files are opened: on Unix by the
+// execute bits, on Windows by extension.
+func isExecutableFile(d fs.DirEntry) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(d.Name()), ".exe")
+	}
+	info, err := d.Info()
+	return err == nil && info.Mode().Perm()&0111 != 0 && info.Size() > 0
+}
+
+// cachedGoBinary returns the build info of path from prev while the file is
+// the same one, unmodified, and reads it otherwise. The entry is kept in next.
+func cachedGoBinary(path string, prev, next map[string]goBinaryEntry) (GoBinary, bool) {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return GoBinary{}, false
+	}
+	if e, ok := prev[path]; ok && os.SameFile(e.info, fi) && e.info.ModTime().Equal(fi.ModTime()) && e.info.Size() == fi.Size() {
+		next[path] = e
+		return e.bin, e.ok
+	}
+	b, ok := readGoBinary(path)
+	next[path] = goBinaryEntry{info: fi, bin: b, ok: ok}
+	return b, ok
+}
+
+func readGoBinary(path string) (GoBinary, bool) {
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		return GoBinary{}, false
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return GoBinary{}, false
+	}
+
+	b := GoBinary{
+		Path:      path,
+		GoVersion: info.GoVersion,
+		Module:    GoModule{Path: info.Path},
+	}
+	if info.Main.Path != "" {
+		b.Module = goModule(&info.Main)
+	}
+	for _, dep := range info.Deps {
+		b.Deps = append(b.Deps, goModule(dep))
+	}
+	for _, s := range info.Settings {
+		if goBuildSettings[s.Key] {
+			if b.Settings == nil {
+				b.Settings = make(map[string]string)
+			}
+			b.Settings[s.Key] = s.Value
+		}
+	}
+	return b, true
+}
+
+func goModule(m *debug.Module) GoModule {
+	mod := GoModule{Path: m.Path, Version: m.Version, Sum: m.Sum}
+	if mod.Version == "(devel)" {
+		mod.Version = "" // built from a checkout, not a tagged module
+	}
+	if !strings.HasPrefix(mod.Path, ".") && !filepath.IsAbs(mod.Path) {
+		mod.PURL = golangPURL(mod.Path, mod.Version) // not a local replacement directory
+	}
+	if m.Replace != nil {
+		r := goModule(m.Replace)
+		mod.Replace = &r
+	}
+	return mod
+}
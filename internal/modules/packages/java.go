@@ -0,0 +1,277 @@
+package packages
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Java libraries are identified inside .jar/.war/.ear archives, including
+// archives nested in other archives (WEB-INF/lib, BOOT-INF/lib, lib/ in an
+// EAR). Each archive is described by its META-INF/maven/**/pom.properties;
+// without one, by META-INF/MANIFEST.MF or its file name. Shaded archives
+// that dropped their metadata are still caught by marker classes.
+
+const (
+	maxArchiveDepth  = 4        // nesting levels opened below the file on disk
+	maxNestedArchive = 64 << 20 // nested archives are read into memory
+)
+
+// JavaLibrary is one library found in a Java archive.
+type JavaLibrary struct {
+	GroupID    string `json:"group_id,omitempty"`
+	ArtifactID string `json:"artifact_id"`
+	Version    string `json:"version,omitempty"`
+	Path       string `json:"path"`   // outer.war!/WEB-INF/lib/inner.jar
+	Source     string `json:"source"` // pom.properties, manifest, filename or class
+	// Classes lists the marker classes (see javaMarkers) present alongside
+	// the library, e.g. log4j-core's JndiLookup.
+	Classes []string `json:"classes,omitempty"`
+}
+
+func (l JavaLibrary) packageInfo() PackageInfo {
+	name := l.ArtifactID
+	if l.GroupID != "" {
+		name = l.GroupID + ":" + l.ArtifactID
+	}
+	return PackageInfo{
+		Name:      name,
+		Version:   l.Version,
+		Ecosystem: "maven",
+		Path:      l.Path,
+		PURL:      l.PURL(),
+	}
+}
+
+// PURL returns the library's Maven package URL.
+func (l JavaLibrary) PURL() string {
+	return purl("maven", l.GroupID, l.ArtifactID, l.Version)
+}
+
+// javaMarker ties a class whose presence matters for known vulnerabilities to
+// the artifact that ships it.
+type javaMarker struct {
+	group, artifact string
+}
+
+// bundleArtifacts maps the OSGi Bundle-SymbolicName of libraries with known
+// vulnerabilities to their Maven coordinates, for archives whose manifest is
+// all that identifies them.
+var bundleArtifacts = map[string]javaMarker{
+	"org.apache.logging.log4j.core":   {"org.apache.logging.log4j", "log4j-core"},
+	"org.apache.logging.log4j.api":    {"org.apache.logging.log4j", "log4j-api"},
+	"log4j":                           {"log4j", "log4j"},
+	"org.apache.commons.commons-text": {"org.apache.commons", "commons-text"},
+	"org.springframework.beans":       {"org.springframework", "spring-beans"},
+}
+
+var javaMarkers = map[string]javaMarker{
+	"org/apache/logging/log4j/core/lookup/JndiLookup.class":   {"org.apache.logging.log4j", "log4j-core"},
+	"org/apache/log4j/net/JMSAppender.class":                  {"log4j", "log4j"},
+	"org/apache/log4j/net/SocketServer.class":                 {"log4j", "log4j"},
+	"org/apache/log4j/jdbc/JDBCAppender.class":                {"log4j", "log4j"},
+	"org/apache/commons/text/lookup/ScriptStringLookup.class": {"org.apache.commons", "commons-text"},
+}
+
+// isJavaArchive reports whether name has a Java archive extension.
+func isJavaArchive(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jar", ".war", ".ear":
+		return true
+	}
+	return false
+}
+
+// FindJavaLibraries inspects every Java archive below roots. Directories
+// listed in excludes (or below them) are skipped.
+func FindJavaLibraries(ctx context.Context, roots, excludes []string) []JavaLibrary {
+	skip := func(dir string) bool {
+		for _, ex := range excludes {
+			if ex != "" && (dir == ex || strings.HasPrefix(dir, strings.TrimSuffix(ex, "/")+"/")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var libs []JavaLibrary
+	for _, root := range roots {
+		walkRoot(root, 0, skip, func(p string, d fs.DirEntry) error {
+			if ctx.Err() != nil {
+				return fs.SkipAll
+			}
+			if !d.IsDir() && d.Type().IsRegular() && isJavaArchive(d.Name()) {
+				libs = append(libs, InspectJavaArchive(p)...)
+			}
+			return nil
+		})
+	}
+	return libs
+}
+
+// InspectJavaArchive returns the libraries in the archive at path and in the
+// archives nested inside it.
+func InspectJavaArchive(file string) []JavaLibrary {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	return inspectZip(&r.Reader, file, 0)
+}
+
+func inspectZip(r *zip.Reader, archivePath string, depth int) []JavaLibrary {
+	var libs, nested []JavaLibrary
+	var manifest *zip.File
+	var markers []string
+
+	for _, f := range r.File {
+		switch {
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && strings.HasSuffix(f.Name, "/pom.properties"):
+			if lib, ok := readPomProperties(f); ok {
+				lib.Path, lib.Source = archivePath, "pom.properties"
+				libs = append(libs, lib)
+			}
+		case f.Name == "META-INF/MANIFEST.MF":
+			manifest = f
+		case isJavaArchive(f.Name) && depth < maxArchiveDepth:
+			nested = append(nested, inspectNested(f, archivePath+"!/"+f.Name, depth+1)...)
+		default:
+			if _, ok := javaMarkers[f.Name]; ok {
+				markers = append(markers, f.Name)
+			}
+		}
+	}
+
+	// The archive's own identity, when it carries no Maven metadata.
+	if len(libs) == 0 {
+		lib, ok := readJavaManifest(manifest)
+		if !ok {
+			lib, ok = javaFilename(path.Base(archivePath))
+		}
+		if ok {
+			lib.Path = archivePath
+			libs = append(libs, lib)
+		}
+	}
+
+	for _, class := range markers {
+		m := javaMarkers[class]
+		i := findArtifact(libs, m)
+		if i < 0 {
+			libs = append(libs, JavaLibrary{GroupID: m.group, ArtifactID: m.artifact, Path: archivePath, Source: "class"})
+			i = len(libs) - 1
+		}
+		libs[i].Classes = append(libs[i].Classes, class)
+	}
+	return append(libs, nested...)
+}
+
+// findArtifact matches on artifact ID, and on group ID when both are known.
+func findArtifact(libs []JavaLibrary, m javaMarker) int {
+	for i, l := range libs {
+		if l.ArtifactID == m.artifact && (l.GroupID == "" || l.GroupID == m.group) {
+			return i
+		}
+	}
+	return -1
+}
+
+func inspectNested(f *zip.File, archivePath string, depth int) []JavaLibrary {
+	if f.UncompressedSize64 > maxNestedArchive {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxNestedArchive))
+	rc.Close()
+	if err != nil {
+		return nil
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil
+	}
+	return inspectZip(r, archivePath, depth)
+}
+
+// readPomProperties reads groupId, artifactId and version.
+func readPomProperties(f *zip.File) (JavaLibrary, bool) {
+	props := readZipProperties(f, "=")
+	lib := JavaLibrary{GroupID: props["groupId"], ArtifactID: props["artifactId"], Version: props["version"]}
+	return lib, lib.ArtifactID != ""
+}
+
+// readJavaManifest derives a library from the main section of MANIFEST.MF.
+// Implementation-Title is used when it looks like an artifact ID rather than
+// a display name; otherwise the OSGi Bundle-SymbolicName, which has no
+// reliable mapping to Maven coordinates, is kept whole as the artifact
+// unless bundleArtifacts knows it.
+func readJavaManifest(f *zip.File) (JavaLibrary, bool) {
+	if f == nil {
+		return JavaLibrary{}, false
+	}
+	attrs := readZipProperties(f, ":")
+	lib := JavaLibrary{Source: "manifest"}
+	if title := attrs["Implementation-Title"]; title != "" && !strings.Contains(title, " ") {
+		lib.GroupID = attrs["Implementation-Vendor-Id"]
+		lib.ArtifactID = title
+		lib.Version = attrs["Implementation-Version"]
+	} else {
+		lib.ArtifactID = strings.TrimSpace(strings.Split(attrs["Bundle-SymbolicName"], ";")[0])
+		lib.Version = attrs["Bundle-Version"]
+		if m, ok := bundleArtifacts[lib.ArtifactID]; ok {
+			lib.GroupID, lib.ArtifactID = m.group, m.artifact
+		}
+	}
+	return lib, lib.ArtifactID != "" && lib.Version != ""
+}
+
+// readZipProperties reads "key<sep>value" lines up to the first blank line,
+// joining manifest continuation lines (which start with a space).
+func readZipProperties(f *zip.File, sep string) map[string]string {
+	props := make(map[string]string)
+	rc, err := f.Open()
+	if err != nil {
+		return props
+	}
+	defer rc.Close()
+
+	var last string
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "" && sep == ":":
+			return props // end of the manifest main section
+		case strings.HasPrefix(line, " ") && last != "":
+			props[last] += line[1:]
+		case strings.HasPrefix(line, "#"):
+		default:
+			if k, v, ok := strings.Cut(line, sep); ok {
+				last = strings.TrimSpace(k)
+				props[last] = strings.TrimSpace(v)
+			}
+		}
+	}
+	return props
+}
+
+var javaFilenameVersion = regexp.MustCompile(`^(.+?)-(\d+(?:\.\d+)*(?:[.-][A-Za-z0-9]+)*)\.(?:jar|war|ear)$`)
+
+// javaFilename splits "name-1.2.3.jar" into artifact and version.
+func javaFilename(name string) (JavaLibrary, bool) {
+	m := javaFilenameVersion.FindStringSubmatch(name)
+	if m == nil {
+		return JavaLibrary{}, false
+	}
+	return JavaLibrary{ArtifactID: m[1], Version: m[2], Source: "filename"}, true
+}
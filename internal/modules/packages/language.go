@@ -0,0 +1,504 @@
+package packages
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Application dependencies are found in the system interpreter locations
+// and by walking the configured roots for installed packages and lock files.
+
+// DefaultPackageRoots are walked when the config sets no package_roots.
+var DefaultPackageRoots = []string{"/opt", "/srv", "/var/www", "/home", "/root", "/app", "/usr/local/src"}
+
+// System-wide install locations, scanned regardless of the roots.
+var (
+	pythonSitePatterns = []string{
+		"/usr/lib/python3/dist-packages",
+		"/usr/lib/python3*/site-packages",
+		"/usr/lib64/python3*/site-packages",
+		"/usr/local/lib/python3*/site-packages",
+		"/usr/local/lib/python3*/dist-packages",
+	}
+	nodeGlobalPatterns = []string{"/usr/lib/node_modules", "/usr/local/lib/node_modules"}
+	javaAppPatterns    = []string{"/var/lib/tomcat*/webapps", "/var/lib/jetty*/webapps", "/usr/share/tomcat*/lib"}
+	gemSpecPatterns    = []string{
+		"/var/lib/gems/*/specifications",
+		"/usr/share/gems/specifications",
+		"/usr/lib/ruby/gems/*/specifications",
+		"/usr/lib64/ruby/gems/*/specifications",
+		"/usr/local/lib/ruby/gems/*/specifications",
+		"/usr/local/share/gems/specifications",
+	}
+)
+
+const (
+	maxWalkDepth   = 12
+	maxWalkEntries = 500000
+)
+
+// languageRescanInterval is how often the roots are walked again. A walk can
+// visit hundreds of thousands of entries and open every Java archive, while
+// installed applications change far less often than inventory is pushed.
+const languageRescanInterval = 6 * time.Hour
+
+// languageCache holds the last walk's result for the roots it walked.
+var languageCache = struct {
+	sync.Mutex
+	roots string
+	at    time.Time
+	pkgs  []PackageInfo
+}{}
+
+// skipDirs are never descended into while walking roots, nor are the
+// pseudo-filesystems in skipPaths. A proc or dev directory elsewhere is an
+// ordinary directory.
+var (
+	skipDirs  = map[string]bool{".git": true, ".hg": true, ".svn": true, ".cache": true}
+	skipPaths = map[string]bool{"/proc": true, "/sys": true, "/dev": true}
+)
+
+type languageCollector struct {
+	pkgs []PackageInfo
+	seen map[string]bool
+}
+
+func (c *languageCollector) add(p PackageInfo) {
+	if p.Name == "" {
+		return
+	}
+	key := p.Ecosystem + "\x00" + p.Name + "\x00" + p.Version + "\x00" + p.Path
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.pkgs = append(c.pkgs, p)
+}
+
+// gatherLanguagePackages returns pip, npm, gem, Composer, Cargo, Go module and
+// Maven (Java archive) dependencies, from the last walk if it is more recent
+// than languageRescanInterval.
+func gatherLanguagePackages(roots []string) []PackageInfo {
+	languageCache.Lock()
+	defer languageCache.Unlock()
+
+	key := strings.Join(roots, "\x00")
+	if languageCache.at.IsZero() || languageCache.roots != key || time.Since(languageCache.at) >= languageRescanInterval {
+		languageCache.pkgs = walkLanguagePackages(roots)
+		languageCache.roots = key
+		languageCache.at = time.Now()
+	}
+	// Callers fill in CPEs, so they get their own copy.
+	return append([]PackageInfo(nil), languageCache.pkgs...)
+}
+
+func walkLanguagePackages(roots []string) []PackageInfo {
+	c := &languageCollector{seen: make(map[string]bool)}
+
+	for _, dir := range globAll(pythonSitePatterns) {
+		c.sitePackages(dir)
+	}
+	for _, dir := range globAll(nodeGlobalPatterns) {
+		c.nodeModules(dir, 0)
+	}
+	for _, dir := range globAll(gemSpecPatterns) {
+		c.gemSpecs(dir)
+	}
+	for _, dir := range globAll(javaAppPatterns) {
+		c.walk(dir)
+	}
+
+	if len(roots) == 0 {
+		roots = DefaultPackageRoots
+	}
+	for _, root := range roots {
+		c.walk(root)
+	}
+	return c.pkgs
+}
+
+func globAll(patterns []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		matches, _ := filepath.Glob(p)
+		for _, m := range matches {
+			if resolved, err := filepath.EvalSymlinks(m); err == nil && !seen[resolved] {
+				seen[resolved] = true
+				out = append(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// walk looks for installed package trees, lock files and Java archives
+// below root.
+func (c *languageCollector) walk(root string) {
+	walkRoot(root, maxWalkEntries, nil, func(path string, d fs.DirEntry) error {
+		name := d.Name()
+		if d.IsDir() {
+			switch {
+			case name == "site-packages" || name == "dist-packages":
+				c.sitePackages(path)
+				return fs.SkipDir
+			case name == "node_modules":
+				c.nodeModules(path, 0)
+				return fs.SkipDir
+			case name == "specifications" && strings.Contains(path, "gems"):
+				c.gemSpecs(path)
+				return fs.SkipDir
+			case name == "mod" && strings.HasSuffix(path, string(os.PathSeparator)+filepath.Join("go", "pkg", "mod")):
+				// The Go module cache holds every module version ever
+				// downloaded, not what is installed.
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		switch name {
+		case "Gemfile.lock":
+			c.gemfileLock(path)
+		case "composer.lock", "installed.json":
+			if name == "composer.lock" || filepath.Base(filepath.Dir(path)) == "composer" {
+				c.composer(path)
+			}
+		case "Cargo.lock":
+			c.cargoLock(path)
+		case "go.mod":
+			c.goMod(path)
+		default:
+			if isJavaArchive(name) && d.Type().IsRegular() {
+				for _, lib := range InspectJavaArchive(path) {
+					c.add(lib.packageInfo())
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// walkRoot calls visit for every entry below root, honouring skipDirs, the
+// depth limit and up to limit entries (0 for no limit). Directories for which
+// skip returns true are not descended into. Symlinks are not followed.
+func walkRoot(root string, limit int, skip func(path string) bool, visit func(path string, d fs.DirEntry) error) {
+	root = filepath.Clean(root)
+	baseDepth := strings.Count(root, string(os.PathSeparator))
+	entries := 0
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entries++; limit > 0 && entries > limit {
+			return fs.SkipAll
+		}
+		if d.IsDir() && path != root {
+			if skipDirs[d.Name()] || skipPaths[path] || strings.Count(path, string(os.PathSeparator))-baseDepth > maxWalkDepth {
+				return fs.SkipDir
+			}
+			if skip != nil && skip(path) {
+				return fs.SkipDir
+			}
+		}
+		return visit(path, d)
+	})
+}
+
+// --- Python ---
+
+// sitePackages reads *.dist-info/METADATA and *.egg-info (a PKG-INFO file or
+// a directory holding one).
+func (c *languageCollector) sitePackages(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		var meta string
+		switch {
+		case strings.HasSuffix(e.Name(), ".dist-info"):
+			meta = filepath.Join(path, "METADATA")
+		case strings.HasSuffix(e.Name(), ".egg-info") && e.IsDir():
+			meta = filepath.Join(path, "PKG-INFO")
+		case strings.HasSuffix(e.Name(), ".egg-info"):
+			meta = path
+		default:
+			continue
+		}
+		name, version := pythonMetadata(meta)
+		c.add(PackageInfo{
+			Name:      name,
+			Version:   version,
+			Ecosystem: "pypi",
+			Path:      path,
+			PURL:      pypiPURL(name, version),
+		})
+	}
+}
+
+// pythonMetadata reads Name and Version from the RFC 822 style header.
+func pythonMetadata(path string) (name, version string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break // end of headers
+		}
+		switch {
+		case strings.HasPrefix(line, "Name:"):
+			name = strings.TrimSpace(line[5:])
+		case strings.HasPrefix(line, "Version:"):
+			version = strings.TrimSpace(line[8:])
+		}
+	}
+	return name, version
+}
+
+// --- npm ---
+
+// nodeModules reads <dir>/<pkg>/package.json and <dir>/@scope/<pkg>/package.json,
+// recursing into nested node_modules.
+func (c *languageCollector) nodeModules(dir string, depth int) {
+	if depth > 8 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if strings.HasPrefix(e.Name(), "@") {
+			scoped, _ := os.ReadDir(path)
+			for _, s := range scoped {
+				if s.IsDir() {
+					c.nodePackage(filepath.Join(path, s.Name()), depth)
+				}
+			}
+			continue
+		}
+		c.nodePackage(path, depth)
+	}
+}
+
+func (c *languageCollector) nodePackage(dir string, depth int) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return
+	}
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(data, &pkg) == nil && pkg.Name != "" {
+		c.add(PackageInfo{
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Ecosystem: "npm",
+			Path:      dir,
+			PURL:      npmPURL(pkg.Name, pkg.Version),
+		})
+	}
+	c.nodeModules(filepath.Join(dir, "node_modules"), depth+1)
+}
+
+// --- Ruby ---
+
+var (
+	gemspecName    = regexp.MustCompile(`\.name\s*=\s*["']([^"']+)["']`)
+	gemspecVersion = regexp.MustCompile(`\.version\s*=\s*["']([^"']+)["']`)
+	gemfileSpec    = regexp.MustCompile(`^    ([^\s(]+) \(([^)]+)\)$`)
+)
+
+// gemSpecs reads the installed gem specifications.
+func (c *languageCollector) gemSpecs(dir string) {
+	specs, _ := filepath.Glob(filepath.Join(dir, "*.gemspec"))
+	for _, path := range specs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		name, version := firstMatch(gemspecName, data), firstMatch(gemspecVersion, data)
+		c.add(PackageInfo{
+			Name:      name,
+			Version:   version,
+			Ecosystem: "gem",
+			Path:      path,
+			PURL:      purl("gem", "", name, version),
+		})
+	}
+}
+
+// gemfileLock reads the "    name (version)" lines under GEM specs:.
+func (c *languageCollector) gemfileLock(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	inGem := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && !strings.HasPrefix(line, " ") {
+			inGem = line == "GEM"
+			continue
+		}
+		if !inGem {
+			continue
+		}
+		if m := gemfileSpec.FindStringSubmatch(line); m != nil {
+			version := strings.SplitN(m[2], "-", 2)[0] // drop platform suffix
+			c.add(PackageInfo{
+				Name:      m[1],
+				Version:   version,
+				Ecosystem: "gem",
+				Path:      path,
+				PURL:      purl("gem", "", m[1], version),
+			})
+		}
+	}
+}
+
+func firstMatch(re *regexp.Regexp, data []byte) string {
+	if m := re.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// --- Composer ---
+
+// composer reads composer.lock or vendor/composer/installed.json, whose
+// package list is either top-level (Composer 1) or under "packages".
+func (c *languageCollector) composer(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	type composerPkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var doc struct {
+		Packages    []composerPkg `json:"packages"`
+		PackagesDev []composerPkg `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		var list []composerPkg
+		if json.Unmarshal(data, &list) != nil {
+			return
+		}
+		doc.Packages = list
+	}
+	for _, p := range append(doc.Packages, doc.PackagesDev...) {
+		version := strings.TrimPrefix(p.Version, "v")
+		c.add(PackageInfo{
+			Name:      p.Name,
+			Version:   version,
+			Ecosystem: "composer",
+			Path:      path,
+			PURL:      composerPURL(p.Name, version),
+		})
+	}
+}
+
+// --- Cargo ---
+
+// cargoLock reads the name and version of each [[package]] table.
+func (c *languageCollector) cargoLock(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var name, version string
+	flush := func() {
+		c.add(PackageInfo{
+			Name:      name,
+			Version:   version,
+			Ecosystem: "cargo",
+			Path:      path,
+			PURL:      purl("cargo", "", name, version),
+		})
+		name, version = "", ""
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "["):
+			flush()
+		case strings.HasPrefix(line, "name = "):
+			name = strings.Trim(strings.TrimPrefix(line, "name = "), `"`)
+		case strings.HasPrefix(line, "version = "):
+			version = strings.Trim(strings.TrimPrefix(line, "version = "), `"`)
+		}
+	}
+	flush()
+}
+
+// --- Go ---
+
+// goMod reads the require directives of a go.mod, which since Go 1.17 list
+// every module in the build.
+func (c *languageCollector) goMod(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	inRequire := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inRequire:
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		c.add(PackageInfo{
+			Name:      fields[0],
+			Version:   fields[1],
+			Ecosystem: "golang",
+			Path:      path,
+			PURL:      golangPURL(fields[0], fields[1]),
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package packages
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// FileOwners maps each given file path to the name of the installed package
+// that ships it. Paths no package owns are left out. dpkg and apk databases
+// are read directly; rpm is queried once for the whole batch.
+func FileOwners(paths []string) map[string]string {
+	owners := make(map[string]string)
+	if len(paths) == 0 {
+		return owners
+	}
+
+	// Packages record the path they installed, which on merged-/usr systems
+	// may be /bin/x while the service or process names /usr/bin/x.
+	want := make(map[string][]string)
+	for _, p := range paths {
+		for _, alias := range pathAliases(p) {
+			want[alias] = append(want[alias], p)
+		}
+	}
+	found := func(path, pkg string) {
+		for _, p := range want[path] {
+			if _, ok := owners[p]; !ok {
+				owners[p] = pkg
+			}
+		}
+	}
+
+	switch {
+	case dirExists("/var/lib/dpkg/info"):
+		dpkgOwners(found)
+	case fileExists("/lib/apk/db/installed"):
+		apkOwners(found)
+	default:
+		if _, err := exec.LookPath("rpm"); err == nil {
+			rpmOwners(paths, owners)
+		}
+	}
+	return owners
+}
+
+func pathAliases(p string) []string {
+	aliases := []string{p}
+	if resolved, err := filepath.EvalSymlinks(p); err == nil && resolved != p {
+		aliases = append(aliases, resolved)
+	}
+	if strings.HasPrefix(p, "/usr/") {
+		aliases = append(aliases, strings.TrimPrefix(p, "/usr"))
+	} else if strings.HasPrefix(p, "/bin/") || strings.HasPrefix(p, "/sbin/") || strings.HasPrefix(p, "/lib") {
+		aliases = append(aliases, "/usr"+p)
+	}
+	return aliases
+}
+
+// dpkgOwners reads /var/lib/dpkg/info/<pkg>[:arch].list, one path per line.
+func dpkgOwners(found func(path, pkg string)) {
+	lists, _ := filepath.Glob("/var/lib/dpkg/info/*.list")
+	for _, list := range lists {
+		pkg := strings.TrimSuffix(filepath.Base(list), ".list")
+		if i := strings.Index(pkg, ":"); i >= 0 {
+			pkg = pkg[:i]
+		}
+		f, err := os.Open(list)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			found(scanner.Text(), pkg)
+		}
+		f.Close()
+	}
+}
+
+// apkOwners reads the apk database: P: starts a package, F: sets the current
+// directory and R: names a file in it.
+func apkOwners(found func(path, pkg string)) {
+	f, err := os.Open("/lib/apk/db/installed")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var pkg, dir string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "P:"):
+			pkg, dir = line[2:], ""
+		case strings.HasPrefix(line, "F:"):
+			dir = line[2:]
+		case strings.HasPrefix(line, "R:"):
+			found("/"+filepath.Join(dir, line[2:]), pkg)
+		}
+	}
+}
+
+// rpmOwners asks rpm about every path at once. rpm prints one line per
+// argument, "file ... is not owned by any package" for unowned ones.
+func rpmOwners(paths []string, owners map[string]string) {
+	out, _ := exec.Command("rpm", append([]string{"-qf", "--queryformat", "%{NAME}\n"}, paths...)...).Output()
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) != len(paths) {
+		return
+	}
+	for i, line := range lines {
+		if line != "" && !strings.Contains(line, " ") {
+			owners[paths[i]] = line
+		}
+	}
+}
+
+func dirExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
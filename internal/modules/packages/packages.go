@@ -8,28 +8,68 @@ import (
 	"strings"
 )
 
-type PackagesModule struct{}
+// PackagesModule inventories OS packages and application dependencies.
+// Roots are the directories walked for language packages; empty means
+// DefaultPackageRoots. BinaryPaths are searched for Go executables; empty
+// means DefaultBinaryPaths. CPEMappingsPath is where SaveCPEMappings keeps
+// the backend's additions to the bundled CPE mapping table.
+type PackagesModule struct {
+	Roots           []string
+	BinaryPaths     []string
+	CPEMappingsPath string
+}
 
-// PackageInfo carries the metadata AIM's CPE resolver uses. Beyond name/version,
-// we collect authoritative vendor metadata per platform (dpkg Maintainer/Homepage,
-// rpm Vendor/URL, Windows registry Publisher, macOS bundle identifier) so the
-// backend can derive an accurate CPE vendor instead of guessing from the name.
+// PackageInfo identifies a package by PURL and, best effort, by CPE, with
+// the confidence of the CPE's vendor and product (see cpe.go). The vendor
+// metadata collected per platform (dpkg Maintainer/Homepage, rpm Vendor/URL,
+// Windows registry Publisher, macOS bundle identifier) is kept so a consumer
+// can derive its own.
 type PackageInfo struct {
 	Name        string `json:"name"`
 	Version     string `json:"version"`
+	Release     string `json:"release,omitempty"` // rpm; Version carries the Debian revision and the apk -rN suffix
+	Epoch       string `json:"epoch,omitempty"`   // rpm
 	Arch        string `json:"arch,omitempty"`
 	Vendor      string `json:"vendor,omitempty"`
 	Publisher   string `json:"publisher,omitempty"`
 	Maintainer  string `json:"maintainer,omitempty"`
 	Homepage    string `json:"homepage,omitempty"`
 	Product     string `json:"product,omitempty"`
-	InstalledAt string `json:"installed_at,omitempty"`
+	InstalledAt string `json:"installed_at,omitempty"` // RFC 3339
+
+	// OS packages read from the package database.
+	SourcePackage string `json:"source_package,omitempty"`
+	InstalledSize int64  `json:"installed_size,omitempty"` // bytes
+	Repository    string `json:"repository,omitempty"`     // apt list or dnf/yum repo id
+
+	Ecosystem string `json:"ecosystem,omitempty"` // pypi, npm, gem, composer, cargo, golang, maven
+	Path      string `json:"path,omitempty"`      // install location, lock file or archive (outer.war!/inner.jar)
+	PURL      string `json:"purl,omitempty"`
+	Source    string `json:"source,omitempty"` // OS packages: apt, rpm, apk, pacman, snap, flatpak, macos, brew, windows
+
+	CPE           string  `json:"cpe,omitempty"`            // CPE 2.3 formatted string
+	CPEConfidence float64 `json:"cpe_confidence,omitempty"` // 0.2 (guessed) to 0.9 (mapping table)
+}
+
+// PackageSource is one package manager found on the host and how many of
+// the List entries it contributed.
+type PackageSource struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
 }
 
+// PackagesData lists the OS packages of every source on the host, each
+// tagged with its source. Type is the first source, kept for backends that
+// predate Sources.
 type PackagesData struct {
-	Type  string        `json:"type"`
-	Count int           `json:"count"`
-	List  []PackageInfo `json:"list"`
+	Type       string          `json:"type"`
+	Sources    []PackageSource `json:"sources"`
+	Count      int             `json:"count"`
+	List       []PackageInfo   `json:"list"`
+	Language   []PackageInfo   `json:"language,omitempty"` // application dependencies
+	GoBinaries []GoBinary      `json:"go_binaries,omitempty"`
+	Updates    []PackageUpdate `json:"updates,omitempty"` // Linux
+	Reboot     *RebootStatus   `json:"reboot,omitempty"`  // Linux
 }
 
 func (m *PackagesModule) Name() string {
@@ -37,31 +77,73 @@ func (m *PackagesModule) Name() string {
 }
 
 func (m *PackagesModule) Gather() (interface{}, error) {
-	var pkgType string
 	var list []PackageInfo
+	var sources []PackageSource
+	var updates []PackageUpdate
+	var reboot *RebootStatus
+
+	add := func(source string, pkgs []PackageInfo) {
+		if len(pkgs) == 0 {
+			return
+		}
+		for i := range pkgs {
+			pkgs[i].Source = source
+		}
+		list = append(list, pkgs...)
+		sources = append(sources, PackageSource{Type: source, Count: len(pkgs)})
+	}
 
 	switch runtime.GOOS {
 	case "linux":
-		if _, err := exec.LookPath("dpkg-query"); err == nil {
-			pkgType = "apt"
-			list = gatherDpkg()
-		} else if _, err := exec.LookPath("rpm"); err == nil {
-			pkgType = "rpm"
-			list = gatherRpm()
-		}
+		add("apt", gatherDpkg())
+		add("rpm", gatherRpm())
+		add("apk", gatherApk(apkInstalledPath))
+		add("pacman", gatherPacman(pacmanLocalPath))
+		add("snap", gatherSnaps(snapStatePath, snapMountDir))
+		add("flatpak", gatherFlatpaks(flatpakInstallations))
+		updates = gatherUpdates(list)
+		reboot = gatherRebootStatus()
 	case "darwin":
-		pkgType = "macos"
-		list = gatherMacApps()
-		list = append(list, gatherBrew()...)
+		add("macos", gatherMacApps())
+		add("brew", gatherBrew())
 	case "windows":
-		pkgType = "windows"
-		list = gatherWindows()
+		add("windows", gatherWindows())
+	}
+
+	pkgType := ""
+	if len(sources) > 0 {
+		pkgType = sources[0].Type
+	} else {
+		sources = []PackageSource{}
+	}
+
+	var language []PackageInfo
+	if runtime.GOOS != "windows" {
+		language = gatherLanguagePackages(m.Roots)
+	}
+
+	var distro osRelease
+	if runtime.GOOS == "linux" {
+		distro = readOSRelease(osReleasePaths)
+	}
+	cpes := loadCPEMappings(m.CPEMappingsPath)
+	for i := range list {
+		list[i].PURL = osPURL(list[i], distro)
+		cpes.apply(&list[i])
+	}
+	for i := range language {
+		cpes.apply(&language[i])
 	}
 
 	return PackagesData{
-		Type:  pkgType,
-		Count: len(list),
-		List:  list,
+		Type:       pkgType,
+		Sources:    sources,
+		Count:      len(list),
+		List:       list,
+		Language:   language,
+		GoBinaries: gatherGoBinaries(m.BinaryPaths),
+		Updates:    updates,
+		Reboot:     reboot,
 	}, nil
 }
 
@@ -74,51 +156,6 @@ func clean(s string) string {
 	return s
 }
 
-// --- Linux: dpkg (Debian/Ubuntu) ---
-// Tab-delimited so Maintainer/Homepage (which contain spaces) parse cleanly.
-func gatherDpkg() []PackageInfo {
-	out, _ := exec.Command("dpkg-query", "-W",
-		"-f=${Package}\t${Version}\t${Architecture}\t${Maintainer}\t${Homepage}\n").Output()
-
-	var pkgs []PackageInfo
-	for _, line := range strings.Split(string(out), "\n") {
-		line = strings.TrimRight(line, "\r")
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		f := strings.Split(line, "\t")
-		p := PackageInfo{Name: clean(field(f, 0)), Version: clean(field(f, 1)), Arch: clean(field(f, 2))}
-		p.Maintainer = clean(field(f, 3))
-		p.Homepage = clean(field(f, 4))
-		if p.Name != "" {
-			pkgs = append(pkgs, p)
-		}
-	}
-	return pkgs
-}
-
-// --- Linux: rpm (RHEL/Fedora/SUSE) ---
-func gatherRpm() []PackageInfo {
-	out, _ := exec.Command("rpm", "-qa", "--queryformat",
-		"%{NAME}\t%{VERSION}\t%{ARCH}\t%{VENDOR}\t%{URL}\n").Output()
-
-	var pkgs []PackageInfo
-	for _, line := range strings.Split(string(out), "\n") {
-		line = strings.TrimRight(line, "\r")
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		f := strings.Split(line, "\t")
-		p := PackageInfo{Name: clean(field(f, 0)), Version: clean(field(f, 1)), Arch: clean(field(f, 2))}
-		p.Vendor = clean(field(f, 3))
-		p.Homepage = clean(field(f, 4))
-		if p.Name != "" {
-			pkgs = append(pkgs, p)
-		}
-	}
-	return pkgs
-}
-
 // --- macOS: installed applications (Info.plist) ---
 // The bundle identifier (e.g. com.google.Chrome) yields an authoritative vendor.
 func gatherMacApps() []PackageInfo {
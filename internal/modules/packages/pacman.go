@@ -0,0 +1,70 @@
+package packages
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Linux: pacman (Arch) ---
+// Each installed package has a directory in the local database whose desc
+// file holds "%FIELD%" headers each followed by value lines and a blank
+// line.
+
+const pacmanLocalPath = "/var/lib/pacman/local"
+
+func gatherPacman(dir string) []PackageInfo {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var pkgs []PackageInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		desc := readPacmanDesc(filepath.Join(dir, e.Name(), "desc"))
+		p := PackageInfo{
+			Name:       desc["NAME"],
+			Version:    desc["VERSION"],
+			Arch:       desc["ARCH"],
+			Homepage:   desc["URL"],
+			Maintainer: desc["PACKAGER"],
+		}
+		p.SourcePackage = desc["BASE"]
+		p.InstalledSize, _ = strconv.ParseInt(desc["SIZE"], 10, 64)
+		if sec, err := strconv.ParseInt(desc["INSTALLDATE"], 10, 64); err == nil {
+			p.InstalledAt = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+		}
+		if p.Name != "" {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
+
+// readPacmanDesc returns the first value line of each field.
+func readPacmanDesc(path string) map[string]string {
+	fields := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fields
+	}
+	var key string
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%") && len(line) > 2:
+			key = line[1 : len(line)-1]
+		case line == "":
+			key = ""
+		case key != "":
+			if _, ok := fields[key]; !ok {
+				fields[key] = line
+			}
+		}
+	}
+	return fields
+}
@@ -0,0 +1,190 @@
+package packages
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Package URLs (https://github.com/package-url/purl-spec) identify a package
+// across ecosystems: pkg:<type>/<namespace>/<name>@<version>.
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// purl builds a package URL. namespace may be empty.
+func purl(purlType, namespace, name, version string) string {
+	var b strings.Builder
+	b.WriteString("pkg:")
+	b.WriteString(purlType)
+	b.WriteByte('/')
+	if namespace != "" {
+		for _, seg := range strings.Split(namespace, "/") {
+			b.WriteString(purlEscape(seg))
+			b.WriteByte('/')
+		}
+	}
+	b.WriteString(purlEscape(name))
+	if version != "" {
+		b.WriteByte('@')
+		b.WriteString(purlEscape(version))
+	}
+	return b.String()
+}
+
+func purlEscape(s string) string {
+	return strings.NewReplacer("+", "%2B", "@", "%40").Replace(url.PathEscape(s))
+}
+
+// withQualifiers appends key=value qualifiers, given as pairs, in key order
+// as the spec requires. Empty values are left out.
+func withQualifiers(p string, pairs ...string) string {
+	var q []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			q = append(q, pairs[i]+"="+strings.ReplaceAll(purlEscape(pairs[i+1]), "&", "%26"))
+		}
+	}
+	if len(q) == 0 {
+		return p
+	}
+	sort.Strings(q)
+	return p + "?" + strings.Join(q, "&")
+}
+
+// pypiPURL applies PEP 503 name normalisation, as the purl spec requires.
+func pypiPURL(name, version string) string {
+	return purl("pypi", "", pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-"), version)
+}
+
+// npmPURL splits a scoped name (@scope/name) into namespace and name.
+func npmPURL(name, version string) string {
+	if strings.HasPrefix(name, "@") {
+		if scope, pkg, ok := strings.Cut(name, "/"); ok {
+			return purl("npm", scope, pkg, version)
+		}
+	}
+	return purl("npm", "", name, version)
+}
+
+// composerPURL splits vendor/package.
+func composerPURL(name, version string) string {
+	if vendor, pkg, ok := strings.Cut(name, "/"); ok {
+		return purl("composer", vendor, pkg, version)
+	}
+	return purl("composer", "", name, version)
+}
+
+// golangPURL uses the module path minus its last element as namespace.
+func golangPURL(module, version string) string {
+	if i := strings.LastIndex(module, "/"); i >= 0 {
+		return purl("golang", module[:i], module[i+1:], version)
+	}
+	return purl("golang", "", module, version)
+}
+
+// --- OS packages ---
+
+// osRelease is the distribution as /etc/os-release describes it.
+type osRelease struct {
+	ID        string // debian, ubuntu, rhel, fedora, alpine, arch, ...
+	VersionID string // 12, 22.04, 9.4, 3.19.1; empty on rolling releases
+	Codename  string
+}
+
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+func readOSRelease(paths []string) osRelease {
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		var r osRelease
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			k, v, ok := strings.Cut(scanner.Text(), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(strings.TrimSpace(v), `"'`)
+			switch k {
+			case "ID":
+				r.ID = strings.ToLower(v)
+			case "VERSION_ID":
+				r.VersionID = v
+			case "VERSION_CODENAME":
+				r.Codename = v
+			}
+		}
+		f.Close()
+		return r
+	}
+	return osRelease{}
+}
+
+// distro is the distro qualifier: debian-12, ubuntu-22.04, alpine-3.19.1.
+func (r osRelease) distro() string {
+	switch {
+	case r.ID == "":
+		return ""
+	case r.VersionID != "":
+		return r.ID + "-" + r.VersionID
+	case r.Codename != "":
+		return r.ID + "-" + r.Codename
+	}
+	return r.ID
+}
+
+// rpmNamespaces maps os-release IDs to the vendor namespaces rpm purls use
+// elsewhere (e.g. by vulnerability databases).
+var rpmNamespaces = map[string]string{
+	"rhel":      "redhat",
+	"ol":        "oracle",
+	"amzn":      "amazon",
+	"sles":      "suse",
+	"sled":      "suse",
+	"sle-micro": "suse",
+}
+
+// osPURL builds the purl of an OS package from the package database its
+// Source names. Sources without a purl type (snap, flatpak, macOS apps,
+// Homebrew, Windows) get none.
+func osPURL(p PackageInfo, r osRelease) string {
+	ns := r.ID
+	switch p.Source {
+	case "apt":
+		if ns == "" {
+			ns = "debian"
+		}
+		return withQualifiers(purl("deb", ns, p.Name, p.Version), "arch", p.Arch, "distro", r.distro())
+	case "rpm":
+		if v, ok := rpmNamespaces[ns]; ok {
+			ns = v
+		} else if strings.HasPrefix(ns, "opensuse") {
+			ns = "opensuse"
+		}
+		version := p.Version
+		if p.Release != "" {
+			version += "-" + p.Release
+		}
+		epoch := p.Epoch
+		if epoch == "0" {
+			epoch = ""
+		}
+		return withQualifiers(purl("rpm", ns, p.Name, version), "arch", p.Arch, "epoch", epoch, "distro", r.distro())
+	case "apk":
+		if ns == "" {
+			ns = "alpine"
+		}
+		return withQualifiers(purl("apk", ns, p.Name, p.Version), "arch", p.Arch, "distro", r.distro())
+	case "pacman":
+		if ns == "" {
+			ns = "arch"
+		}
+		return withQualifiers(purl("alpm", ns, p.Name, p.Version), "arch", p.Arch, "distro", r.distro())
+	}
+	return ""
+}
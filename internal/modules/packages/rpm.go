@@ -0,0 +1,194 @@
+package packages
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Linux: rpm (RHEL/Fedora/SUSE) ---
+// Read from the database directly; rpm -qa is only the fallback for a
+// container format the readers do not know.
+
+// dnfHistoryPaths are dnf 4's transaction history, which records the
+// repository each package was installed from.
+var dnfHistoryPaths = []string{"/var/lib/dnf/history.sqlite"}
+
+// yumDBDir is yum's per-package metadata (RHEL 7), including from_repo.
+const yumDBDir = "/var/lib/yum/yumdb"
+
+func gatherRpm() []PackageInfo {
+	blobs, err := readRpmDB(rpmDBDirs)
+	if err != nil {
+		if _, lookErr := exec.LookPath("rpm"); lookErr == nil {
+			log.Printf("Reading the rpm database failed, falling back to rpm -qa: %v", err)
+			return queryRpm()
+		}
+		return nil
+	}
+
+	repos := rpmRepositories()
+	var pkgs []PackageInfo
+	for _, blob := range blobs {
+		h, err := parseRpmHeader(blob)
+		if err != nil {
+			continue
+		}
+		p := PackageInfo{
+			Name:       h.strings[rpmTagName],
+			Version:    h.strings[rpmTagVersion],
+			Release:    h.strings[rpmTagRelease],
+			Arch:       h.strings[rpmTagArch],
+			Vendor:     clean(h.strings[rpmTagVendor]),
+			Maintainer: clean(h.strings[rpmTagPackager]),
+			Homepage:   clean(h.strings[rpmTagURL]),
+		}
+		if p.Name == "" {
+			continue
+		}
+		if epoch, ok := h.ints[rpmTagEpoch]; ok {
+			p.Epoch = strconv.FormatInt(epoch, 10)
+		}
+		if t := h.ints[rpmTagInstallTime]; t > 0 {
+			p.InstalledAt = time.Unix(t, 0).UTC().Format(time.RFC3339)
+		}
+		p.InstalledSize = h.ints[rpmTagLongSize]
+		if p.InstalledSize == 0 {
+			p.InstalledSize = h.ints[rpmTagSize]
+		}
+		p.SourcePackage = sourceRPMName(h.strings[rpmTagSourceRPM])
+		p.Repository = repos[rpmKey(p.Name, p.Version, p.Release, p.Arch)]
+		pkgs = append(pkgs, p)
+	}
+	return pkgs
+}
+
+// sourceRPMName strips "-version-release.src.rpm" from a SOURCERPM tag.
+func sourceRPMName(srpm string) string {
+	srpm = strings.TrimSuffix(strings.TrimSuffix(srpm, ".rpm"), ".src")
+	srpm = strings.TrimSuffix(srpm, ".nosrc")
+	if srpm == "" {
+		return ""
+	}
+	return rpmName(srpm)
+}
+
+func rpmKey(name, version, release, arch string) string {
+	return name + "-" + version + "-" + release + "-" + arch
+}
+
+// rpmRepositories maps rpmKey to the repository the package came from, per
+// dnf's history or, on yum systems, the yumdb.
+func rpmRepositories() map[string]string {
+	repos := make(map[string]string)
+	for _, path := range dnfHistoryPaths {
+		if fileExists(path) {
+			dnfHistoryRepos(path, repos)
+		}
+	}
+	// <yumdb>/<letter>/<pkgid>-<name>-<version>-<release>-<arch>/from_repo
+	matches, _ := filepath.Glob(filepath.Join(yumDBDir, "*", "*", "from_repo"))
+	for _, m := range matches {
+		dir := filepath.Base(filepath.Dir(m))
+		_, key, ok := strings.Cut(dir, "-")
+		if !ok {
+			continue
+		}
+		if data, err := os.ReadFile(m); err == nil {
+			repos[key] = strings.TrimSpace(string(data))
+		}
+	}
+	return repos
+}
+
+// dnf transaction item actions that leave the item installed.
+var dnfInstallActions = map[int64]bool{1: true, 2: true, 6: true, 9: true} // install, downgrade, upgrade, reinstall
+
+// dnfHistoryRepos reads libdnf's history schema:
+//
+//	repo       (id INTEGER PRIMARY KEY, repoid TEXT)
+//	rpm        (item_id INTEGER, name, epoch, version, release, arch)
+//	trans_item (id INTEGER PRIMARY KEY, trans_id, item_id, repo_id, action, reason, state)
+//
+// The newest completed install-like transaction item of a package names
+// its repository.
+func dnfHistoryRepos(path string, repos map[string]string) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	repoIDs := make(map[int64]string)
+	db.scanTable("repo", func(rowid int64, v []interface{}) {
+		if len(v) >= 2 {
+			if s, ok := v[1].(string); ok {
+				repoIDs[rowid] = s
+			}
+		}
+	})
+
+	items := make(map[int64]string)
+	db.scanTable("rpm", func(_ int64, v []interface{}) {
+		if len(v) < 6 {
+			return
+		}
+		id, _ := v[0].(int64)
+		name, _ := v[1].(string)
+		version, _ := v[3].(string)
+		release, _ := v[4].(string)
+		arch, _ := v[5].(string)
+		items[id] = rpmKey(name, version, release, arch)
+	})
+
+	const stateDone = 1
+	db.scanTable("trans_item", func(_ int64, v []interface{}) {
+		if len(v) < 7 {
+			return
+		}
+		item, _ := v[2].(int64)
+		repo, _ := v[3].(int64)
+		action, _ := v[4].(int64)
+		state, _ := v[6].(int64)
+		key, ok := items[item]
+		if !ok || !dnfInstallActions[action] || state != stateDone {
+			return
+		}
+		if name := repoIDs[repo]; name != "" {
+			repos[key] = name // rows are in id order, so the newest wins
+		}
+	})
+}
+
+// queryRpm asks rpm itself.
+func queryRpm() []PackageInfo {
+	out, _ := exec.Command("rpm", "-qa", "--queryformat",
+		"%{NAME}\t%{VERSION}\t%{ARCH}\t%{VENDOR}\t%{URL}\t%{RELEASE}\t%{EPOCH}\t%{INSTALLTIME}\t%{SIZE}\t%{SOURCERPM}\n").Output()
+
+	var pkgs []PackageInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		p := PackageInfo{Name: clean(field(f, 0)), Version: clean(field(f, 1)), Arch: clean(field(f, 2))}
+		p.Vendor = clean(field(f, 3))
+		p.Homepage = clean(field(f, 4))
+		p.Release = clean(field(f, 5))
+		p.Epoch = clean(field(f, 6))
+		if t, err := strconv.ParseInt(clean(field(f, 7)), 10, 64); err == nil {
+			p.InstalledAt = time.Unix(t, 0).UTC().Format(time.RFC3339)
+		}
+		p.InstalledSize, _ = strconv.ParseInt(clean(field(f, 8)), 10, 64)
+		p.SourcePackage = sourceRPMName(clean(field(f, 9)))
+		if p.Name != "" {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,272 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The rpm database holds one header blob per installed package, in one of
+// three containers: SQLite (rpm 4.16+, Fedora 33+, RHEL 9), the ndb format
+// (SUSE 15.3+) or a Berkeley DB hash file (RHEL 7/8, older SUSE). rpm is not
+// needed to read any of them.
+
+// rpmDBDirs are where distributions keep the database; Fedora 36+ moved it
+// to /usr/lib/sysimage/rpm and left a symlink.
+var rpmDBDirs = []string{"/var/lib/rpm", "/usr/lib/sysimage/rpm"}
+
+// readRpmDB returns the header blobs of the first database found.
+func readRpmDB(dirs []string) ([][]byte, error) {
+	for _, dir := range dirs {
+		if path := filepath.Join(dir, "rpmdb.sqlite"); fileExists(path) {
+			return readRpmSQLite(path)
+		}
+		if path := filepath.Join(dir, "Packages.db"); fileExists(path) {
+			return readRpmNDB(path)
+		}
+		if path := filepath.Join(dir, "Packages"); fileExists(path) {
+			return readRpmBDB(path)
+		}
+	}
+	return nil, errors.New("no rpm database")
+}
+
+func readRpmSQLite(path string) ([][]byte, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	// CREATE TABLE Packages (hnum INTEGER PRIMARY KEY AUTOINCREMENT, blob BLOB NOT NULL)
+	var blobs [][]byte
+	err = db.scanTable("Packages", func(_ int64, v []interface{}) {
+		if len(v) >= 2 {
+			if b, ok := v[1].([]byte); ok {
+				blobs = append(blobs, b)
+			}
+		}
+	})
+	return blobs, err
+}
+
+// --- ndb ---
+
+const (
+	ndbHeaderMagic = 0x506d7052 // "RpmP"
+	ndbSlotMagic   = 0x746f6c53 // "Slot"
+	ndbBlobMagic   = 0x53626c42 // "BlbS"
+	ndbSlotSize    = 16
+	ndbBlockSize   = 16
+	ndbPageSize    = 4096
+)
+
+// readRpmNDB reads Packages.db: a 32-byte header, pages of 16-byte slots
+// pointing at blobs, and the blobs, each with a 16-byte header. Everything
+// is little-endian.
+func readRpmNDB(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	if len(data) < 32 || le.Uint32(data) != ndbHeaderMagic {
+		return nil, errors.New("not an rpm ndb database")
+	}
+	slotPages := int(le.Uint32(data[12:]))
+	slotsEnd := slotPages * ndbPageSize
+	if slotsEnd > len(data) {
+		return nil, errors.New("truncated rpm ndb database")
+	}
+
+	var blobs [][]byte
+	for at := 32; at+ndbSlotSize <= slotsEnd; at += ndbSlotSize {
+		if le.Uint32(data[at:]) != ndbSlotMagic || le.Uint32(data[at+4:]) == 0 {
+			continue
+		}
+		off := int(le.Uint32(data[at+8:])) * ndbBlockSize
+		if off+16 > len(data) || le.Uint32(data[off:]) != ndbBlobMagic {
+			continue
+		}
+		size := int(le.Uint32(data[off+12:]))
+		if off+16+size > len(data) {
+			continue
+		}
+		blobs = append(blobs, data[off+16:off+16+size])
+	}
+	return blobs, nil
+}
+
+// --- Berkeley DB hash ---
+
+const (
+	bdbHashMagic    = 0x061561
+	bdbPageHash     = 13 // P_HASH
+	bdbPageHashOld  = 2  // P_HASH_UNSORTED
+	bdbPageOverflow = 7
+	bdbKeyData      = 1 // H_KEYDATA
+	bdbOffPage      = 3 // H_OFFPAGE
+	bdbPageHeader   = 26
+)
+
+// readRpmBDB reads the values of a Berkeley DB hash database by visiting
+// every hash page rather than following the buckets. rpm stores headers
+// off-page, in chains of overflow pages.
+func readRpmBDB(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 512 {
+		return nil, errors.New("not a Berkeley DB database")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(data[12:]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(data[12:]) != bdbHashMagic {
+			return nil, errors.New("not a Berkeley DB hash database")
+		}
+	}
+	pageSize := int(order.Uint32(data[20:]))
+	if pageSize < 512 || pageSize > 65536 {
+		return nil, fmt.Errorf("bad Berkeley DB page size %d", pageSize)
+	}
+	pages := len(data) / pageSize
+	page := func(n int) []byte {
+		if n <= 0 || n >= pages {
+			return nil
+		}
+		return data[n*pageSize : (n+1)*pageSize]
+	}
+
+	var blobs [][]byte
+	for n := 1; n < pages; n++ {
+		p := page(n)
+		if p[25] != bdbPageHash && p[25] != bdbPageHashOld {
+			continue
+		}
+		entries := int(order.Uint16(p[20:]))
+		if bdbPageHeader+2*entries > pageSize {
+			continue
+		}
+		// Entries alternate key, value; items are laid out from the end
+		// of the page down, so each ends where the previous one starts.
+		for i := 1; i < entries; i += 2 {
+			start := int(order.Uint16(p[bdbPageHeader+2*i:]))
+			end := int(order.Uint16(p[bdbPageHeader+2*(i-1):]))
+			if start >= end || end > pageSize {
+				continue
+			}
+			item := p[start:end]
+			switch item[0] {
+			case bdbKeyData:
+				blobs = append(blobs, item[1:])
+			case bdbOffPage:
+				if len(item) < 12 {
+					continue
+				}
+				if b := bdbOverflow(page, order, int(order.Uint32(item[4:])), int(order.Uint32(item[8:]))); b != nil {
+					blobs = append(blobs, b)
+				}
+			}
+		}
+	}
+	return blobs, nil
+}
+
+// bdbOverflow follows a chain of overflow pages, whose header's hf_offset
+// field holds the number of bytes used on the page.
+func bdbOverflow(page func(int) []byte, order binary.ByteOrder, n, size int) []byte {
+	out := make([]byte, 0, size)
+	for seen := 0; n != 0 && len(out) < size; seen++ {
+		p := page(n)
+		if p == nil || p[25] != bdbPageOverflow || seen > 1<<16 {
+			return nil
+		}
+		used := int(order.Uint16(p[22:]))
+		if bdbPageHeader+used > len(p) {
+			return nil
+		}
+		out = append(out, p[bdbPageHeader:bdbPageHeader+used]...)
+		n = int(order.Uint32(p[16:]))
+	}
+	if len(out) != size {
+		return nil
+	}
+	return out
+}
+
+// --- header blobs ---
+
+const (
+	rpmTagName        = 1000
+	rpmTagVersion     = 1001
+	rpmTagRelease     = 1002
+	rpmTagEpoch       = 1003
+	rpmTagInstallTime = 1008
+	rpmTagSize        = 1009
+	rpmTagVendor      = 1011
+	rpmTagPackager    = 1015
+	rpmTagURL         = 1020
+	rpmTagArch        = 1022
+	rpmTagSourceRPM   = 1044
+	rpmTagLongSize    = 5009
+
+	rpmTypeInt32      = 4
+	rpmTypeInt64      = 5
+	rpmTypeString     = 6
+	rpmTypeI18NString = 9
+)
+
+// rpmHeader holds the tags of a header blob that the inventory uses:
+// strings, and the first value of integer tags.
+type rpmHeader struct {
+	strings map[int]string
+	ints    map[int]int64
+}
+
+// parseRpmHeader parses a header blob as stored in the database: entry
+// and data-store lengths, index entries of tag, type, offset and count, and
+// the data store, all big-endian.
+func parseRpmHeader(blob []byte) (*rpmHeader, error) {
+	if len(blob) < 8 {
+		return nil, errors.New("short rpm header")
+	}
+	il := int(binary.BigEndian.Uint32(blob))
+	dl := int(binary.BigEndian.Uint32(blob[4:]))
+	if il <= 0 || il > 1<<16 || 8+16*il+dl > len(blob) {
+		return nil, errors.New("malformed rpm header")
+	}
+	store := blob[8+16*il : 8+16*il+dl]
+
+	h := &rpmHeader{strings: make(map[int]string), ints: make(map[int]int64)}
+	for i := 0; i < il; i++ {
+		e := blob[8+16*i:]
+		tag := int(int32(binary.BigEndian.Uint32(e)))
+		typ := binary.BigEndian.Uint32(e[4:])
+		off := int(int32(binary.BigEndian.Uint32(e[8:])))
+		if off < 0 || off >= len(store) {
+			continue
+		}
+		switch typ {
+		case rpmTypeString, rpmTypeI18NString:
+			s := store[off:]
+			if end := bytes.IndexByte(s, 0); end >= 0 {
+				s = s[:end]
+			}
+			h.strings[tag] = string(s)
+		case rpmTypeInt32:
+			if off+4 <= len(store) {
+				h.ints[tag] = int64(binary.BigEndian.Uint32(store[off:]))
+			}
+		case rpmTypeInt64:
+			if off+8 <= len(store) {
+				h.ints[tag] = int64(binary.BigEndian.Uint64(store[off:]))
+			}
+		}
+	}
+	return h, nil
+}
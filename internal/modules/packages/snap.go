@@ -0,0 +1,76 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// --- Linux: snap ---
+// snapd's state lists the installed snaps with the revision in use; the
+// version is only in the snap's own meta/snap.yaml, read from its mount
+// under /snap.
+
+const (
+	snapStatePath = "/var/lib/snapd/state.json"
+	snapMountDir  = "/snap"
+)
+
+type snapState struct {
+	Data struct {
+		Snaps map[string]struct {
+			Current  interface{}    `json:"current"` // revision, a string or, in old states, a number
+			Sequence []snapSideInfo `json:"sequence"`
+		} `json:"snaps"`
+	} `json:"data"`
+}
+
+type snapSideInfo struct {
+	Revision interface{} `json:"revision"`
+	Title    string      `json:"title"`
+}
+
+type snapYAML struct {
+	Name          string   `yaml:"name"`
+	Version       string   `yaml:"version"`
+	Architectures []string `yaml:"architectures"`
+}
+
+func gatherSnaps(statePath, mountDir string) []PackageInfo {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil
+	}
+	var state snapState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+
+	var pkgs []PackageInfo
+	for name, s := range state.Data.Snaps {
+		revision := fmt.Sprint(s.Current)
+		p := PackageInfo{Name: name, Product: name, Path: filepath.Join(mountDir, name, revision)}
+		for _, si := range s.Sequence {
+			if fmt.Sprint(si.Revision) == revision && si.Title != "" {
+				p.Product = si.Title
+			}
+		}
+		var meta snapYAML
+		if raw, err := os.ReadFile(filepath.Join(p.Path, "meta", "snap.yaml")); err == nil && yaml.Unmarshal(raw, &meta) == nil {
+			p.Version = meta.Version
+			if len(meta.Architectures) == 1 {
+				p.Arch = meta.Architectures[0]
+			}
+		}
+		if p.Version == "" {
+			p.Version = "r" + revision // not mounted; the revision still identifies the build
+		}
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs
+}
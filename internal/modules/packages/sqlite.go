@@ -0,0 +1,296 @@
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// A read-only walker for SQLite table b-trees, enough to read the rpm
+// database (rpmdb.sqlite) and dnf's history without a cgo or third-party
+// driver. It follows https://www.sqlite.org/fileformat2.html and sees what
+// was checkpointed into the main file; pages still in a -wal file are not
+// read.
+
+type sqliteDB struct {
+	f        *os.File
+	pageSize int
+	usable   int
+	pages    int
+}
+
+func openSQLite(path string) (*sqliteDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	hdr := make([]byte, 100)
+	if _, err := f.ReadAt(hdr, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !bytes.HasPrefix(hdr, []byte("SQLite format 3\x00")) {
+		f.Close()
+		return nil, errors.New("not an SQLite database")
+	}
+	if hdr[56+3] > 1 { // text encoding, big-endian uint32 at 56
+		f.Close()
+		return nil, errors.New("UTF-16 SQLite databases are not supported")
+	}
+	pageSize := int(binary.BigEndian.Uint16(hdr[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || pageSize-int(hdr[20]) < 480 {
+		f.Close()
+		return nil, errors.New("invalid SQLite page size")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &sqliteDB{
+		f:        f,
+		pageSize: pageSize,
+		usable:   pageSize - int(hdr[20]),
+		pages:    int(fi.Size() / int64(pageSize)),
+	}, nil
+}
+
+func (db *sqliteDB) Close() error {
+	return db.f.Close()
+}
+
+func (db *sqliteDB) page(n int) ([]byte, error) {
+	if n < 1 || n > db.pages {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	buf := make([]byte, db.pageSize)
+	_, err := db.f.ReadAt(buf, int64(n-1)*int64(db.pageSize))
+	return buf, err
+}
+
+// scanTable calls fn with the rowid and column values (nil, int64, float64,
+// string or []byte) of every row of a table. A column declared INTEGER
+// PRIMARY KEY is stored as NULL; its value is the rowid.
+func (db *sqliteDB) scanTable(name string, fn func(rowid int64, values []interface{})) error {
+	root := 0
+	err := db.walk(1, make(map[int]bool), func(_ int64, v []interface{}) {
+		if len(v) >= 4 && v[0] == "table" && v[1] == name {
+			if n, ok := v[3].(int64); ok {
+				root = int(n)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if root == 0 {
+		return fmt.Errorf("no table %s", name)
+	}
+	return db.walk(root, make(map[int]bool), fn)
+}
+
+// walk visits the rows of the table b-tree rooted at page n in rowid order.
+// The file may be corrupt or caught mid-write, so every offset read from it
+// is checked against the page; seen guards against page cycles.
+func (db *sqliteDB) walk(n int, seen map[int]bool, fn func(int64, []interface{})) error {
+	if seen[n] {
+		return fmt.Errorf("page %d is referenced twice", n)
+	}
+	seen[n] = true
+	p, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	off := 0
+	if n == 1 {
+		off = 100 // the file header precedes the first page's b-tree header
+	}
+	hdrLen := 8
+	if p[off] == 0x05 {
+		hdrLen = 12
+	}
+	cells := int(binary.BigEndian.Uint16(p[off+3:]))
+	if off+hdrLen+2*cells > len(p) {
+		return fmt.Errorf("page %d: cell count %d overflows the page", n, cells)
+	}
+	cell := func(i int, minLen int) (int, error) {
+		at := int(binary.BigEndian.Uint16(p[off+hdrLen+2*i:]))
+		if at < off+hdrLen+2*cells || at+minLen > len(p) {
+			return 0, fmt.Errorf("page %d: cell pointer %d out of range", n, at)
+		}
+		return at, nil
+	}
+
+	switch p[off] {
+	case 0x0d: // table leaf
+		for i := 0; i < cells; i++ {
+			at, err := cell(i, 2)
+			if err != nil {
+				return err
+			}
+			payloadLen, k := sqliteVarint(p[at:])
+			if k == 0 {
+				return fmt.Errorf("page %d: truncated cell", n)
+			}
+			at += k
+			rowid, k := sqliteVarint(p[at:])
+			if k == 0 {
+				return fmt.Errorf("page %d: truncated cell", n)
+			}
+			at += k
+			payload, err := db.payload(p, at, payloadLen)
+			if err != nil {
+				return err
+			}
+			values, err := sqliteRecord(payload)
+			if err != nil {
+				return err
+			}
+			fn(int64(rowid), values)
+		}
+	case 0x05: // table interior
+		for i := 0; i < cells; i++ {
+			at, err := cell(i, 4)
+			if err != nil {
+				return err
+			}
+			if err := db.walk(int(binary.BigEndian.Uint32(p[at:])), seen, fn); err != nil {
+				return err
+			}
+		}
+		return db.walk(int(binary.BigEndian.Uint32(p[off+8:])), seen, fn)
+	default:
+		return fmt.Errorf("page %d is not a table b-tree page", n)
+	}
+	return nil
+}
+
+// payload assembles a cell's payload from the page and its overflow chain.
+func (db *sqliteDB) payload(p []byte, at int, total uint64) ([]byte, error) {
+	u := db.usable
+	// No payload can be larger than the pages of the file it is stored in.
+	if total > uint64(db.pages)*uint64(u) {
+		return nil, errors.New("cell payload larger than the database")
+	}
+	size := int(total)
+	maxLocal := u - 35
+	local := size
+	if size > maxLocal {
+		minLocal := (u-12)*32/255 - 23
+		local = minLocal + (size-minLocal)%(u-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if at+local > len(p) || (local < size && at+local+4 > len(p)) {
+		return nil, errors.New("cell overflows its page")
+	}
+	out := append(make([]byte, 0, size), p[at:at+local]...)
+	if local == size {
+		return out, nil
+	}
+
+	next := int(binary.BigEndian.Uint32(p[at+local:]))
+	for len(out) < size {
+		if next == 0 {
+			return nil, errors.New("overflow chain ends early")
+		}
+		op, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		chunk := op[4:u]
+		if rest := size - len(out); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		out = append(out, chunk...)
+		next = int(binary.BigEndian.Uint32(op))
+	}
+	return out, nil
+}
+
+// sqliteRecord decodes a record: a header of serial types, then the values.
+func sqliteRecord(rec []byte) ([]interface{}, error) {
+	hdrLen, k := sqliteVarint(rec)
+	if k == 0 || hdrLen < uint64(k) || hdrLen > uint64(len(rec)) {
+		return nil, errors.New("malformed record")
+	}
+	var types []uint64
+	for at := k; at < int(hdrLen); {
+		t, n := sqliteVarint(rec[at:int(hdrLen)])
+		if n == 0 {
+			return nil, errors.New("malformed record")
+		}
+		types = append(types, t)
+		at += n
+	}
+
+	values := make([]interface{}, 0, len(types))
+	body := rec[hdrLen:]
+	for _, t := range types {
+		var size int
+		switch {
+		case t >= 12:
+			if (t-12)/2 > uint64(len(body)) {
+				return nil, errors.New("malformed record")
+			}
+			size = int(t-12) / 2
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6 || t == 7:
+			size = 8
+		}
+		if size > len(body) {
+			return nil, errors.New("malformed record")
+		}
+		v := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t <= 6:
+			n := int64(int8(v[0])) // sign-extend the big-endian integer
+			for _, b := range v[1:] {
+				n = n<<8 | int64(b)
+			}
+			values = append(values, n)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case t == 8 || t == 9:
+			values = append(values, int64(t-8))
+		case t >= 12 && t%2 == 0:
+			values = append(values, v)
+		case t >= 13:
+			values = append(values, string(v))
+		default:
+			values = append(values, nil)
+		}
+	}
+	return values, nil
+}
+
+// sqliteVarint decodes a big-endian base-128 varint of up to nine bytes,
+// the last of which contributes all eight bits. It returns a length of 0 if b
+// ends inside the varint.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 9; i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
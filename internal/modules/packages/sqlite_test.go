@@ -0,0 +1,158 @@
+package packages
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPageSize = 512
+
+// sqliteTestVarint encodes v (below 1<<14) as an SQLite varint.
+func sqliteTestVarint(v int) []byte {
+	if v < 0x80 {
+		return []byte{byte(v)}
+	}
+	return []byte{0x80 | byte(v>>7), byte(v & 0x7f)}
+}
+
+// sqliteTestRecord encodes strings and small integers as a record.
+func sqliteTestRecord(values ...interface{}) []byte {
+	var hdr, body []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			hdr = append(hdr, sqliteTestVarint(13+2*len(v))...)
+			body = append(body, v...)
+		case int:
+			hdr = append(hdr, 1)
+			body = append(body, byte(v))
+		}
+	}
+	return append(append([]byte{byte(len(hdr) + 1)}, hdr...), body...)
+}
+
+// sqliteTestLeaf lays out a table leaf page holding one cell per record, with
+// rowids counting from 1. Page 1 keeps room for the file header.
+func sqliteTestLeaf(first bool, records ...[]byte) []byte {
+	p := make([]byte, testPageSize)
+	off := 0
+	if first {
+		off = 100
+	}
+	p[off] = 0x0d
+	binary.BigEndian.PutUint16(p[off+3:], uint16(len(records)))
+	end := testPageSize
+	for i, rec := range records {
+		cell := append(sqliteTestVarint(len(rec)), sqliteTestVarint(i+1)...)
+		cell = append(cell, rec...)
+		end -= len(cell)
+		copy(p[end:], cell)
+		binary.BigEndian.PutUint16(p[off+8+2*i:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(p[off+5:], uint16(end))
+	return p
+}
+
+// sqliteTestFile returns a database with table t (name TEXT, n INTEGER) on
+// page 2 holding two rows.
+func sqliteTestFile() []byte {
+	page1 := sqliteTestLeaf(true, sqliteTestRecord("table", "t", "t", 2, "CREATE TABLE t (name TEXT, n INTEGER)"))
+	copy(page1, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(page1[16:], testPageSize)
+	binary.BigEndian.PutUint32(page1[56:], 1)
+	page2 := sqliteTestLeaf(false, sqliteTestRecord("a", 1), sqliteTestRecord("b", 2))
+	return append(page1, page2...)
+}
+
+func scanTestTable(t *testing.T, data []byte) ([][]interface{}, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var rows [][]interface{}
+	err = db.scanTable("t", func(_ int64, values []interface{}) {
+		rows = append(rows, values)
+	})
+	return rows, err
+}
+
+func TestSQLiteScanTable(t *testing.T) {
+	rows, err := scanTestTable(t, sqliteTestFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{{"a", int64(1)}, {"b", int64(2)}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestSQLiteCorruptPages(t *testing.T) {
+	page2 := testPageSize
+	tests := []struct {
+		name    string
+		corrupt func([]byte) []byte
+	}{
+		{"truncated file", func(b []byte) []byte { return b[:testPageSize] }},
+		{"torn last page", func(b []byte) []byte { return b[:testPageSize+100] }},
+		{"bad page size", func(b []byte) []byte {
+			binary.BigEndian.PutUint16(b[16:], 1000)
+			return b
+		}},
+		{"cell pointer past the page", func(b []byte) []byte {
+			binary.BigEndian.PutUint16(b[page2+8:], 60000)
+			return b
+		}},
+		{"cell pointer at the last byte", func(b []byte) []byte {
+			b[page2+testPageSize-1] = 0xff
+			binary.BigEndian.PutUint16(b[page2+8:], testPageSize-1)
+			return b
+		}},
+		{"cell count overflows the page", func(b []byte) []byte {
+			binary.BigEndian.PutUint16(b[page2+3:], 0xffff)
+			return b
+		}},
+		{"payload larger than the file", func(b []byte) []byte {
+			at := int(binary.BigEndian.Uint16(b[page2+8:]))
+			b[page2+at] = 0x7f
+			b[page2+at-1] = 0xff
+			binary.BigEndian.PutUint16(b[page2+8:], uint16(at-1))
+			return b
+		}},
+		{"record header past the payload", func(b []byte) []byte {
+			at := int(binary.BigEndian.Uint16(b[page2+8:]))
+			b[page2+at+2] = 0x7f
+			return b
+		}},
+		{"interior page referencing itself", func(b []byte) []byte {
+			p := b[page2 : page2+testPageSize]
+			p[0] = 0x05
+			binary.BigEndian.PutUint16(p[3:], 0)
+			binary.BigEndian.PutUint32(p[8:], 2)
+			return b
+		}},
+		{"child page past the file", func(b []byte) []byte {
+			p := b[page2 : page2+testPageSize]
+			p[0] = 0x05
+			binary.BigEndian.PutUint16(p[3:], 0)
+			binary.BigEndian.PutUint32(p[8:], 99)
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := scanTestTable(t, tt.corrupt(sqliteTestFile())); err == nil {
+				t.Error("corrupt database read without an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,480 @@
+package packages
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pending updates and restart state on Linux, so patch latency can be
+// tracked per host. Package manager metadata is never refreshed here: the
+// update lists reflect the host's last apt update / dnf makecache, which is
+// also what its administrators see.
+
+const updateTimeout = 2 * time.Minute
+
+// PackageUpdate is an installed package with a newer candidate version in
+// the configured repositories. Security is only classified for apt (a
+// candidate from a -security suite) and dnf/yum (updateinfo security
+// advisories); zypper and apk report false.
+type PackageUpdate struct {
+	Name             string `json:"name"`
+	Arch             string `json:"arch,omitempty"`
+	InstalledVersion string `json:"installed_version,omitempty"`
+	CandidateVersion string `json:"candidate_version"`
+	Repository       string `json:"repository,omitempty"`
+	Security         bool   `json:"security"`
+	Manager          string `json:"manager"` // apt, dnf, yum, zypper, apk
+}
+
+// RebootStatus tells whether installed updates are waiting for a reboot or
+// for services to be restarted.
+type RebootStatus struct {
+	Required       bool           `json:"required"`
+	Reasons        []string       `json:"reasons,omitempty"`  // reboot-required, needs-restarting, kernel
+	Packages       []string       `json:"packages,omitempty"` // from /var/run/reboot-required.pkgs
+	RunningKernel  string         `json:"running_kernel,omitempty"`
+	LatestKernel   string         `json:"latest_kernel,omitempty"` // newest kernel installed under /boot or /lib/modules
+	StaleProcesses []StaleProcess `json:"stale_processes,omitempty"`
+}
+
+// StaleProcess still maps shared libraries that were replaced or removed on
+// disk, typically by an upgrade, and keeps running the old code until it is
+// restarted.
+type StaleProcess struct {
+	PID       int32    `json:"pid"`
+	Name      string   `json:"name"`
+	Libraries []string `json:"libraries"`
+}
+
+// gatherUpdates asks the first package manager found for pending updates.
+// installed fills in the installed version where the manager does not
+// report it.
+func gatherUpdates(installed []PackageInfo) []PackageUpdate {
+	var updates []PackageUpdate
+	switch {
+	case hasCommand("apt-get"):
+		updates = aptUpdates()
+	case hasCommand("dnf"):
+		updates = dnfUpdates("dnf")
+	case hasCommand("yum"):
+		updates = dnfUpdates("yum")
+	case hasCommand("zypper"):
+		updates = zypperUpdates()
+	case hasCommand("apk"):
+		updates = apkUpdates()
+	}
+
+	versions := make(map[string]string, len(installed))
+	for _, p := range installed {
+		if p.Source != "snap" && p.Source != "flatpak" {
+			versions[p.Name] = p.Version
+		}
+	}
+	for i := range updates {
+		if updates[i].InstalledVersion == "" {
+			updates[i].InstalledVersion = versions[updates[i].Name]
+		}
+	}
+	return updates
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// runUpdateCommand runs a package manager query with a timeout, since one
+// waiting on another's lock would otherwise stall the whole inventory. The
+// output is returned even on a non-zero exit, which check-update uses to
+// signal pending updates.
+func runUpdateCommand(name string, args ...string) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil
+	}
+	return out
+}
+
+// --- apt ---
+
+// Inst libssl3 [3.0.2-0ubuntu1.10] (3.0.2-0ubuntu1.12 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+var aptInst = regexp.MustCompile(`^Inst (\S+) \[([^\]]+)\] \((\S+) (.*?) \[([^\]]+)\]\)`)
+
+// aptUpdates simulates a dist-upgrade, which needs no lock and lists every
+// upgrade with the suites offering the candidate. Packages the upgrade would
+// newly install have no installed version and are skipped.
+func aptUpdates() []PackageUpdate {
+	out := runUpdateCommand("apt-get", "-s", "-o", "Debug::NoLocking=1", "dist-upgrade")
+	var updates []PackageUpdate
+	for _, line := range strings.Split(string(out), "\n") {
+		m := aptInst.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		repos := m[4]
+		updates = append(updates, PackageUpdate{
+			Name:             m[1],
+			Arch:             m[5],
+			InstalledVersion: m[2],
+			CandidateVersion: m[3],
+			Repository:       repos,
+			Security:         strings.Contains(repos, "-security") || strings.Contains(repos, "Debian-Security"),
+			Manager:          "apt",
+		})
+	}
+	return updates
+}
+
+// --- dnf / yum ---
+
+// dnfUpdates parses check-update, whose lines are "name.arch version repo"
+// (wrapped onto two lines when the name is long), and marks the packages
+// named in security advisories.
+func dnfUpdates(manager string) []PackageUpdate {
+	out := runUpdateCommand(manager, "-q", "--cacheonly", "check-update")
+	var updates []PackageUpdate
+	var pending []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Obsoleting") || strings.HasPrefix(line, "Security:") {
+			break
+		}
+		pending = append(pending, strings.Fields(line)...)
+		if len(pending) < 3 {
+			continue
+		}
+		name, arch := splitArch(pending[0])
+		updates = append(updates, PackageUpdate{
+			Name:             name,
+			Arch:             arch,
+			CandidateVersion: pending[1],
+			Repository:       pending[2],
+			Manager:          manager,
+		})
+		pending = nil
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// ALSA-2024:1234 Important/Sec. openssl-libs-1:3.0.7-27.el9.x86_64
+	security := make(map[string]bool)
+	out = runUpdateCommand(manager, "-q", "--cacheonly", "updateinfo", "list", "--security")
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			continue
+		}
+		nevr, arch := splitArch(f[len(f)-1])
+		security[rpmName(nevr)+"."+arch] = true
+	}
+	for i := range updates {
+		updates[i].Security = security[updates[i].Name+"."+updates[i].Arch]
+	}
+	return updates
+}
+
+// splitArch splits "name.arch" at the last dot.
+func splitArch(s string) (string, string) {
+	if i := strings.LastIndexByte(s, '.'); i > 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// rpmName strips "-[epoch:]version-release" from a package NEVR.
+func rpmName(nevr string) string {
+	for i := 0; i < 2; i++ {
+		if j := strings.LastIndexByte(nevr, '-'); j > 0 {
+			nevr = nevr[:j]
+		}
+	}
+	return nevr
+}
+
+// --- zypper ---
+
+type zypperUpdateList struct {
+	Updates []struct {
+		Kind       string `xml:"kind,attr"`
+		Name       string `xml:"name,attr"`
+		Edition    string `xml:"edition,attr"`
+		EditionOld string `xml:"edition-old,attr"`
+		Arch       string `xml:"arch,attr"`
+		Source     struct {
+			Alias string `xml:"alias,attr"`
+		} `xml:"source"`
+	} `xml:"update-status>update-list>update"`
+}
+
+func zypperUpdates() []PackageUpdate {
+	out := runUpdateCommand("zypper", "--non-interactive", "--no-refresh", "--xmlout", "list-updates", "--type", "package")
+	var list zypperUpdateList
+	if err := xml.Unmarshal(out, &list); err != nil {
+		return nil
+	}
+	var updates []PackageUpdate
+	for _, u := range list.Updates {
+		if u.Kind != "package" {
+			continue
+		}
+		updates = append(updates, PackageUpdate{
+			Name:             u.Name,
+			Arch:             u.Arch,
+			InstalledVersion: u.EditionOld,
+			CandidateVersion: u.Edition,
+			Repository:       u.Source.Alias,
+			Manager:          "zypper",
+		})
+	}
+	return updates
+}
+
+// --- apk ---
+
+// apkUpdates parses "apk version -l <" lines: "busybox-1.36.1-r2 < 1.36.1-r5".
+func apkUpdates() []PackageUpdate {
+	out := runUpdateCommand("apk", "version", "-l", "<")
+	var updates []PackageUpdate
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 || f[1] != "<" {
+			continue
+		}
+		name, version := splitApkName(f[0])
+		updates = append(updates, PackageUpdate{
+			Name:             name,
+			InstalledVersion: version,
+			CandidateVersion: f[2],
+			Manager:          "apk",
+		})
+	}
+	return updates
+}
+
+// splitApkName splits "name-version-rN" before the version, whose first
+// character is a digit.
+func splitApkName(s string) (string, string) {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] == '-' && s[i+1] >= '0' && s[i+1] <= '9' {
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// --- reboot and restart state ---
+
+func gatherRebootStatus() *RebootStatus {
+	r := &RebootStatus{}
+
+	if _, err := os.Stat("/var/run/reboot-required"); err == nil {
+		r.Reasons = append(r.Reasons, "reboot-required")
+		if data, err := os.ReadFile("/var/run/reboot-required.pkgs"); err == nil {
+			seen := make(map[string]bool)
+			for _, p := range strings.Fields(string(data)) {
+				if !seen[p] {
+					seen[p] = true
+					r.Packages = append(r.Packages, p)
+				}
+			}
+		}
+	}
+
+	// needs-restarting -r exits 1 when a core package was updated since boot.
+	if hasCommand("needs-restarting") {
+		ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+		err := exec.CommandContext(ctx, "needs-restarting", "-r").Run()
+		cancel()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			r.Reasons = append(r.Reasons, "needs-restarting")
+		}
+	}
+
+	if data, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		r.RunningKernel = strings.TrimSpace(string(data))
+	}
+	r.LatestKernel = latestKernel(r.RunningKernel)
+	if r.RunningKernel != "" && r.LatestKernel != "" && compareKernels(r.LatestKernel, r.RunningKernel) > 0 {
+		r.Reasons = append(r.Reasons, "kernel")
+	}
+
+	r.StaleProcesses = staleProcesses()
+	r.Required = len(r.Reasons) > 0
+	return r
+}
+
+// latestKernel returns the newest kernel version of running's flavour with
+// an image in /boot or, where images are not named by version (Arch's
+// vmlinuz-linux, Alpine's vmlinuz-lts) or /boot is not visible
+// (containers), a module tree.
+func latestKernel(running string) string {
+	var versions []string
+	matches, _ := filepath.Glob("/boot/vmlinuz-*")
+	for _, m := range matches {
+		v := strings.TrimPrefix(filepath.Base(m), "vmlinuz-")
+		if v != "" && isDigit(v[0]) && !strings.Contains(v, "rescue") {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		if entries, err := os.ReadDir("/lib/modules"); err == nil {
+			for _, e := range entries {
+				if e.IsDir() && e.Name() != "" && isDigit(e.Name()[0]) {
+					versions = append(versions, e.Name())
+				}
+			}
+		}
+	}
+
+	// Another flavour (Alpine's -virt beside -lts, Arch's linux-lts beside
+	// linux) is not an update of the running kernel.
+	flavour := kernelFlavour(running)
+	var candidates []string
+	for _, v := range versions {
+		if running == "" || kernelFlavour(v) == flavour {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Slice(candidates, func(i, j int) bool { return compareKernels(candidates[i], candidates[j]) < 0 })
+	return candidates[len(candidates)-1]
+}
+
+// kernelFlavour returns the last dash-separated part of a kernel release
+// when it holds no digits: "lts" for 6.6.32-0-lts, "generic" for
+// 6.8.0-45-generic, "" for 6.9.7-arch1-1 or 5.14.0-427.el9.x86_64.
+func kernelFlavour(release string) string {
+	i := strings.LastIndex(release, "-")
+	if i < 0 {
+		return ""
+	}
+	suffix := release[i+1:]
+	if strings.IndexFunc(suffix, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0 {
+		return ""
+	}
+	return suffix
+}
+
+// compareKernels orders kernel release strings such as "6.8.0-45-generic"
+// or "5.14.0-427.13.1.el9_4.x86_64": runs of digits compare numerically,
+// anything else lexically.
+func compareKernels(a, b string) int {
+	ta, tb := kernelTokens(a), kernelTokens(b)
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		x, errX := strconv.Atoi(ta[i])
+		y, errY := strconv.Atoi(tb[i])
+		switch {
+		case errX == nil && errY == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case ta[i] != tb[i]:
+			if ta[i] < tb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(ta) - len(tb)
+}
+
+func kernelTokens(v string) []string {
+	var tokens []string
+	start := 0
+	for i := 1; i <= len(v); i++ {
+		if i == len(v) || isDigit(v[i]) != isDigit(v[i-1]) {
+			if t := strings.Trim(v[start:i], ".-_+~"); t != "" {
+				tokens = append(tokens, t)
+			}
+			start = i
+		}
+	}
+	return tokens
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// staleProcesses lists processes mapping shared libraries that /proc marks
+// "(deleted)", as needrestart and needs-restarting -s do.
+func staleProcesses() []StaleProcess {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var stale []StaleProcess
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		libs := deletedLibraries(filepath.Join("/proc", e.Name(), "maps"))
+		if len(libs) == 0 {
+			continue
+		}
+		name := ""
+		if data, err := os.ReadFile(filepath.Join("/proc", e.Name(), "comm")); err == nil {
+			name = strings.TrimSpace(string(data))
+		}
+		stale = append(stale, StaleProcess{PID: int32(pid), Name: name, Libraries: libs})
+	}
+	return stale
+}
+
+func deletedLibraries(mapsPath string) []string {
+	f, err := os.Open(mapsPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var libs []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasSuffix(line, " (deleted)") {
+			continue
+		}
+		// address perms offset dev inode path
+		f := strings.Fields(strings.TrimSuffix(line, " (deleted)"))
+		if len(f) < 6 {
+			continue
+		}
+		path := strings.Join(f[5:], " ")
+		if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "/dev/") || strings.HasPrefix(path, "/memfd:") {
+			continue
+		}
+		base := filepath.Base(path)
+		if !strings.HasSuffix(base, ".so") && !strings.Contains(base, ".so.") {
+			continue
+		}
+		if !seen[path] {
+			seen[path] = true
+			libs = append(libs, path)
+		}
+	}
+	return libs
+}
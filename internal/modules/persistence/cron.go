@@ -0,0 +1,198 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cronEntries reads the system crontab, /etc/cron.d, the per-user spools and
+// the run-parts directories (cron.hourly etc.).
+func cronEntries(accounts accountDB) []PersistenceEntry {
+	var entries []PersistenceEntry
+
+	// System tables carry a user field.
+	entries = append(entries, cronTable("/etc/crontab", "")...)
+	for _, path := range listDir("/etc/cron.d") {
+		entries = append(entries, cronTable(path, "")...)
+	}
+
+	// Per-user tables are named after their owner: Debian uses
+	// /var/spool/cron/crontabs, RHEL and SUSE /var/spool/cron(/tabs).
+	for _, dir := range []string{"/var/spool/cron/crontabs", "/var/spool/cron/tabs", "/var/spool/cron"} {
+		for _, path := range listDir(dir) {
+			entries = append(entries, cronTable(path, filepath.Base(path))...)
+		}
+	}
+
+	for _, period := range []string{"hourly", "daily", "weekly", "monthly", "yearly"} {
+		for _, path := range listDir("/etc/cron." + period) {
+			entries = append(entries, PersistenceEntry{
+				Type:     "cron-script",
+				Source:   path,
+				Owner:    "root",
+				Schedule: "@" + period,
+				Command:  path,
+				// run-parts only executes executable files.
+				Enabled: isExecutable(path),
+			})
+		}
+	}
+	return entries
+}
+
+// cronTable parses one crontab. user is empty for system tables, whose sixth
+// field names the user.
+func cronTable(path, user string) []PersistenceEntry {
+	var entries []PersistenceEntry
+	lines, numbers := readLines(path)
+	for i, line := range lines {
+		if isEnvAssignment(line) {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		var schedule []string
+		if strings.HasPrefix(fields[0], "@") {
+			schedule, fields = fields[:1], fields[1:]
+		} else if len(fields) >= 5 {
+			schedule, fields = fields[:5], fields[5:]
+		} else {
+			continue
+		}
+
+		owner := user
+		if owner == "" {
+			if len(fields) == 0 {
+				continue
+			}
+			owner, fields = fields[0], fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		entries = append(entries, PersistenceEntry{
+			Type:     "cron",
+			Source:   path,
+			Line:     numbers[i],
+			Owner:    owner,
+			Schedule: strings.Join(schedule, " "),
+			Command:  strings.Join(fields, " "),
+			Enabled:  true,
+		})
+	}
+	return entries
+}
+
+// anacronEntries parses /etc/anacrontab: period delay job-id command.
+func anacronEntries(path string) []PersistenceEntry {
+	var entries []PersistenceEntry
+	lines, numbers := readLines(path)
+	for i, line := range lines {
+		if isEnvAssignment(line) {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		schedule := fields[0]
+		if !strings.HasPrefix(schedule, "@") {
+			schedule += "d" // period in days
+		}
+		entries = append(entries, PersistenceEntry{
+			Type:     "anacron",
+			Source:   path,
+			Line:     numbers[i],
+			Owner:    "root",
+			Schedule: schedule + " delay=" + fields[1] + "m id=" + fields[2],
+			Command:  strings.Join(fields[3:], " "),
+			Enabled:  true,
+		})
+	}
+	return entries
+}
+
+// isEnvAssignment reports lines such as SHELL=/bin/sh or MAILTO="".
+func isEnvAssignment(line string) bool {
+	key, _, ok := strings.Cut(line, "=")
+	return ok && !strings.ContainsAny(strings.TrimSpace(key), " \t*/@")
+}
+
+// --- at ---
+
+// atJobs lists queued at jobs. The spool file is a shell script whose last
+// part is the user's command; its owner is the submitting user.
+func atJobs(accounts accountDB) []PersistenceEntry {
+	var entries []PersistenceEntry
+	for _, dir := range []string{"/var/spool/cron/atjobs", "/var/spool/at"} {
+		for _, path := range listDir(dir) {
+			name := filepath.Base(path)
+			if name == ".SEQ" || name == "spool" {
+				continue
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, PersistenceEntry{
+				Type:     "at",
+				Source:   path,
+				Owner:    accounts.name(fileUID(fi)),
+				Schedule: atRunTime(name),
+				Command:  atCommand(path),
+				// atd only runs jobs marked executable; done ones are cleared.
+				Enabled: fi.Mode()&0100 != 0,
+			})
+		}
+	}
+	return entries
+}
+
+// atRunTime decodes the run time from an at spool name: queue letter, five
+// hex digits of job number and eight hex digits of minutes since the epoch.
+func atRunTime(name string) string {
+	if len(name) != 14 {
+		return ""
+	}
+	var minutes int64
+	for _, c := range name[6:] {
+		var d int64
+		switch {
+		case c >= '0' && c <= '9':
+			d = int64(c - '0')
+		case c >= 'a' && c <= 'f':
+			d = int64(c-'a') + 10
+		default:
+			return ""
+		}
+		minutes = minutes*16 + d
+	}
+	return unixMinutes(minutes)
+}
+
+func atCommand(path string) string {
+	lines, _ := readLines(path)
+	// at wraps the command in a here-document after the environment setup:
+	//   ${SHELL:-/bin/sh} << 'marcinDELIMITER...'
+	//   <command>
+	//   marcinDELIMITER...
+	for i, line := range lines {
+		if idx := strings.Index(line, "<< '"); idx >= 0 {
+			delim := strings.Trim(line[idx+3:], " '")
+			var cmd []string
+			for _, l := range lines[i+1:] {
+				if l == delim {
+					break
+				}
+				cmd = append(cmd, l)
+			}
+			return strings.Join(cmd, "\n")
+		}
+	}
+	if len(lines) > 0 {
+		return lines[len(lines)-1]
+	}
+	return ""
+}
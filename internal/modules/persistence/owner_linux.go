@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package persistence
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileUID(fi os.FileInfo) int {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid)
+	}
+	return -1
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package persistence
+
+import "os"
+
+// The module only gathers on Linux.
+func fileUID(fi os.FileInfo) int {
+	return -1
+}
@@ -0,0 +1,212 @@
+package persistence
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PersistenceModule inventories the places a process can be (re)started from
+// without an interactive login: cron, anacron, systemd timers, at jobs,
+// rc.local, shell startup files and XDG autostart entries.
+type PersistenceModule struct{}
+
+type PersistenceEntry struct {
+	Type     string `json:"type"`               // cron, cron-script, anacron, systemd-timer, at, rc-local, shell-profile, xdg-autostart
+	Source   string `json:"source"`             // file the entry was read from
+	Line     int    `json:"line,omitempty"`     // line within Source for table formats
+	Owner    string `json:"owner,omitempty"`    // user the command runs as
+	Schedule string `json:"schedule,omitempty"` // cron expression, OnCalendar=, "login", ...
+	Command  string `json:"command"`
+	Unit     string `json:"unit,omitempty"` // systemd unit a timer activates
+	Enabled  bool   `json:"enabled"`
+
+	// Identity of Source, so responders can spot recent or changed files.
+	SHA256     string    `json:"sha256,omitempty"`
+	ModifiedAt time.Time `json:"modified_at,omitempty"`
+	FileOwner  string    `json:"file_owner,omitempty"`
+}
+
+func (m *PersistenceModule) Name() string {
+	return "persistence"
+}
+
+func (m *PersistenceModule) Gather() (interface{}, error) {
+	var entries []PersistenceEntry
+
+	if runtime.GOOS != "linux" {
+		return entries, nil
+	}
+
+	accounts := readAccounts("/etc/passwd")
+
+	entries = append(entries, cronEntries(accounts)...)
+	entries = append(entries, anacronEntries("/etc/anacrontab")...)
+	entries = append(entries, systemdTimers(accounts)...)
+	entries = append(entries, atJobs(accounts)...)
+	entries = append(entries, rcLocal()...)
+	entries = append(entries, shellProfiles(accounts)...)
+	entries = append(entries, xdgAutostart(accounts)...)
+
+	hashes := make(map[string]string)
+	for i := range entries {
+		e := &entries[i]
+		fi, err := os.Stat(e.Source)
+		if err != nil {
+			continue
+		}
+		e.ModifiedAt = fi.ModTime().UTC()
+		e.FileOwner = accounts.name(fileUID(fi))
+		if _, ok := hashes[e.Source]; !ok {
+			hashes[e.Source] = fileSHA256(e.Source)
+		}
+		e.SHA256 = hashes[e.Source]
+	}
+
+	return entries, nil
+}
+
+// --- accounts ---
+
+type account struct {
+	name string
+	uid  int
+	home string
+}
+
+type accountDB []account
+
+func readAccounts(path string) accountDB {
+	var db accountDB
+	f, err := os.Open(path)
+	if err != nil {
+		return db
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) < 7 {
+			continue
+		}
+		uid, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		db = append(db, account{name: parts[0], uid: uid, home: parts[5]})
+	}
+	return db
+}
+
+func (db accountDB) name(uid int) string {
+	if uid < 0 {
+		return ""
+	}
+	for _, a := range db {
+		if a.uid == uid {
+			return a.name
+		}
+	}
+	return strconv.Itoa(uid)
+}
+
+// homes returns each account with a real home directory once; system
+// accounts pointing at / or /nonexistent are skipped.
+func (db accountDB) homes() []account {
+	var out []account
+	seen := make(map[string]bool)
+	for _, a := range db {
+		if a.home == "" || a.home == "/" || seen[a.home] {
+			continue
+		}
+		if fi, err := os.Stat(a.home); err != nil || !fi.IsDir() {
+			continue
+		}
+		seen[a.home] = true
+		out = append(out, a)
+	}
+	return out
+}
+
+// --- helpers ---
+
+func fileSHA256(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readLines returns the non-blank, non-comment lines of a file with their
+// 1-based line numbers, joining backslash continuations.
+func readLines(path string) ([]string, []int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	var lines []string
+	var numbers []int
+	var pending string
+	start := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if pending == "" {
+			start = n
+		}
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSuffix(line, "\\")
+			continue
+		}
+		line = strings.TrimSpace(pending + line)
+		pending = ""
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+		numbers = append(numbers, start)
+	}
+	return lines, numbers
+}
+
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir() && fi.Mode()&0111 != 0
+}
+
+// listDir returns the regular files of a directory, skipping dotfiles and
+// package manager leftovers.
+func listDir(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") ||
+			strings.HasSuffix(name, ".dpkg-old") || strings.HasSuffix(name, ".dpkg-dist") ||
+			strings.HasSuffix(name, ".rpmsave") || strings.HasSuffix(name, ".rpmnew") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
@@ -0,0 +1,290 @@
+package persistence
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- systemd timers ---
+
+// System unit directories in precedence order; a unit in an earlier
+// directory masks one of the same name in a later one.
+var systemUnitDirs = []string{"/etc/systemd/system", "/run/systemd/system", "/usr/local/lib/systemd/system", "/usr/lib/systemd/system", "/lib/systemd/system"}
+
+var userUnitDirs = []string{"/etc/systemd/user", "/usr/lib/systemd/user"}
+
+// systemdTimers parses timer units from disk rather than asking systemctl so
+// that disabled timers and per-user timers are included.
+func systemdTimers(accounts accountDB) []PersistenceEntry {
+	entries := timersIn(systemUnitDirs, "root")
+	for _, a := range accounts.homes() {
+		entries = append(entries, timersIn([]string{filepath.Join(a.home, ".config/systemd/user")}, a.name)...)
+	}
+	return append(entries, timersIn(userUnitDirs, "")...)
+}
+
+func timersIn(dirs []string, owner string) []PersistenceEntry {
+	var entries []PersistenceEntry
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		timers, _ := filepath.Glob(filepath.Join(dir, "*.timer"))
+		for _, path := range timers {
+			name := filepath.Base(path)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if target, err := os.Readlink(path); err == nil && target == "/dev/null" {
+				continue // masked
+			}
+
+			timer := readUnit(path)
+			unit := timer.get("Timer", "Unit")
+			if unit == "" {
+				unit = strings.TrimSuffix(name, ".timer") + ".service"
+			}
+			e := PersistenceEntry{
+				Type:     "systemd-timer",
+				Source:   path,
+				Owner:    owner,
+				Schedule: timerSchedule(timer),
+				Unit:     unit,
+				Enabled:  unitEnabled(dirs, name),
+			}
+			if svcPath := findUnit(dirs, unit); svcPath != "" {
+				svc := readUnit(svcPath)
+				e.Command = svc.get("Service", "ExecStart")
+				if u := svc.get("Service", "User"); u != "" {
+					e.Owner = u
+				}
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// timerSchedule joins the trigger settings, e.g. "OnCalendar=daily; OnBootSec=15min".
+func timerSchedule(u unitFile) string {
+	var parts []string
+	for _, key := range []string{"OnCalendar", "OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec"} {
+		for _, v := range u.all("Timer", key) {
+			parts = append(parts, key+"="+v)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// unitEnabled looks for the install symlink in any *.wants directory.
+func unitEnabled(dirs []string, name string) bool {
+	for _, dir := range dirs {
+		if matches, _ := filepath.Glob(filepath.Join(dir, "*.wants", name)); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func findUnit(dirs []string, name string) string {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// unitFile maps "Section.Key" to its values in order. Drop-ins are not
+// merged.
+type unitFile map[string][]string
+
+func readUnit(path string) unitFile {
+	u := make(unitFile)
+	lines, _ := readLines(path)
+	section := ""
+	for _, line := range lines {
+		if strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			key := section + "." + strings.TrimSpace(k)
+			u[key] = append(u[key], strings.TrimSpace(v))
+		}
+	}
+	return u
+}
+
+func (u unitFile) get(section, key string) string {
+	values := u[section+"."+key]
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] != "" {
+			return strings.TrimLeft(values[i], "-@:+!") // ExecStart prefixes
+		}
+	}
+	return ""
+}
+
+func (u unitFile) all(section, key string) []string {
+	var out []string
+	for _, v := range u[section+"."+key] {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// --- rc.local ---
+
+func rcLocal() []PersistenceEntry {
+	var entries []PersistenceEntry
+	for _, path := range []string{"/etc/rc.local", "/etc/rc.d/rc.local"} {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+			continue // RHEL links /etc/rc.local to /etc/rc.d/rc.local
+		}
+		lines, numbers := readLines(path)
+		for i, line := range lines {
+			if line == "exit 0" {
+				continue
+			}
+			entries = append(entries, PersistenceEntry{
+				Type:     "rc-local",
+				Source:   path,
+				Line:     numbers[i],
+				Owner:    "root",
+				Schedule: "boot",
+				Command:  line,
+				// systemd's rc-local generator only runs it when executable.
+				Enabled: isExecutable(path),
+			})
+		}
+	}
+	return entries
+}
+
+// --- shell startup files ---
+
+var systemProfiles = []string{
+	"/etc/profile", "/etc/bash.bashrc", "/etc/bashrc", "/etc/bash.bash_logout",
+	"/etc/zsh/zshenv", "/etc/zsh/zprofile", "/etc/zsh/zshrc", "/etc/zsh/zlogin",
+	"/etc/zshenv", "/etc/zprofile", "/etc/zshrc", "/etc/zlogin",
+	"/etc/environment",
+}
+
+var userProfiles = []string{
+	".profile", ".bash_profile", ".bash_login", ".bashrc", ".bash_logout",
+	".zshenv", ".zprofile", ".zshrc", ".zlogin", ".config/fish/config.fish",
+}
+
+// shellProfiles reports each startup file as one entry. Their content is
+// too free-form to split into commands; the hash and mtime identify changes.
+func shellProfiles(accounts accountDB) []PersistenceEntry {
+	var entries []PersistenceEntry
+	add := func(path, owner, schedule string) {
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		entries = append(entries, PersistenceEntry{
+			Type:     "shell-profile",
+			Source:   path,
+			Owner:    owner,
+			Schedule: schedule,
+			Command:  path,
+			Enabled:  true,
+		})
+	}
+
+	for _, path := range systemProfiles {
+		add(path, "", profileSchedule(path))
+	}
+	for _, path := range listDir("/etc/profile.d") {
+		add(path, "", "login")
+	}
+	for _, a := range accounts.homes() {
+		for _, name := range userProfiles {
+			add(filepath.Join(a.home, name), a.name, profileSchedule(name))
+		}
+	}
+	return entries
+}
+
+func profileSchedule(path string) string {
+	base := filepath.Base(path)
+	switch {
+	case strings.Contains(base, "logout"):
+		return "logout"
+	case strings.Contains(base, "rc") || strings.HasSuffix(base, "zshenv") || base == "config.fish":
+		return "shell"
+	}
+	return "login"
+}
+
+// --- XDG autostart ---
+
+func xdgAutostart(accounts accountDB) []PersistenceEntry {
+	var entries []PersistenceEntry
+	for _, path := range listDir("/etc/xdg/autostart") {
+		if e, ok := desktopEntry(path, ""); ok {
+			entries = append(entries, e)
+		}
+	}
+	for _, a := range accounts.homes() {
+		for _, path := range listDir(filepath.Join(a.home, ".config/autostart")) {
+			if e, ok := desktopEntry(path, a.name); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries
+}
+
+func desktopEntry(path, owner string) (PersistenceEntry, bool) {
+	if !strings.HasSuffix(path, ".desktop") {
+		return PersistenceEntry{}, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return PersistenceEntry{}, false
+	}
+	defer f.Close()
+
+	e := PersistenceEntry{Type: "xdg-autostart", Source: path, Owner: owner, Schedule: "session", Enabled: true}
+	inEntry := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !inEntry || !ok {
+			continue
+		}
+		switch strings.TrimSpace(k) {
+		case "Exec":
+			e.Command = strings.TrimSpace(v)
+		case "Hidden":
+			if strings.EqualFold(strings.TrimSpace(v), "true") {
+				e.Enabled = false
+			}
+		case "X-GNOME-Autostart-enabled":
+			if strings.EqualFold(strings.TrimSpace(v), "false") {
+				e.Enabled = false
+			}
+		}
+	}
+	return e, e.Command != ""
+}
+
+func unixMinutes(minutes int64) string {
+	return time.Unix(minutes*60, 0).UTC().Format(time.RFC3339)
+}
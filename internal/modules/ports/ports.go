@@ -0,0 +1,409 @@
+package ports
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	gnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// maxConnections caps the established connections reported per push; busy
+// servers can hold far more than are useful for attack-surface review.
+const maxConnections = 2000
+
+type PortsModule struct{}
+
+type SocketInfo struct {
+	Protocol      string `json:"protocol"` // tcp, tcp6, udp, udp6
+	LocalAddress  string `json:"local_address"`
+	LocalPort     int    `json:"local_port"`
+	RemoteAddress string `json:"remote_address,omitempty"`
+	RemotePort    int    `json:"remote_port,omitempty"`
+	State         string `json:"state"`
+	Exposed       bool   `json:"exposed"` // bound to a wildcard or non-loopback address
+	ProcessInfo
+}
+
+type UnixSocketInfo struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"` // stream, dgram, seqpacket
+	State string `json:"state"`
+	ProcessInfo
+}
+
+// ProcessInfo identifies the process owning a socket. It is empty when the
+// owner cannot be determined (e.g. sockets of other users without root).
+type ProcessInfo struct {
+	PID        int32  `json:"pid,omitempty"`
+	Process    string `json:"process,omitempty"`
+	Executable string `json:"executable,omitempty"`
+	User       string `json:"user,omitempty"`
+}
+
+type PortsData struct {
+	Listening            []SocketInfo     `json:"listening"`
+	Established          []SocketInfo     `json:"established"`
+	EstablishedTruncated bool             `json:"established_truncated,omitempty"`
+	UnixListening        []UnixSocketInfo `json:"unix_listening,omitempty"`
+}
+
+func (m *PortsModule) Name() string {
+	return "ports"
+}
+
+func (m *PortsModule) Gather() (interface{}, error) {
+	if runtime.GOOS == "linux" {
+		return gatherProc("/proc"), nil
+	}
+	return gatherPsutil()
+}
+
+// Sockets returns the sockets of the host in the same shape as Gather, for
+// other modules that attribute sockets to processes.
+func Sockets() PortsData {
+	data, err := (&PortsModule{}).Gather()
+	if err != nil {
+		return PortsData{}
+	}
+	return data.(PortsData)
+}
+
+// --- Linux: /proc/net ---
+
+// tcpStates maps the hex state column of /proc/net/tcp{,6}.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+type procSocket struct {
+	SocketInfo
+	inode string
+	uid   string
+}
+
+func gatherProc(procRoot string) PortsData {
+	var data PortsData
+	owners := socketOwners(procRoot)
+	resolver := newOwnerResolver(procRoot)
+
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		for _, s := range parseNetFile(filepath.Join(procRoot, "net", proto), proto) {
+			if pid, ok := owners[s.inode]; ok {
+				s.ProcessInfo = resolver.process(pid)
+			} else if s.uid != "" {
+				s.User = resolver.username(s.uid)
+			}
+
+			switch {
+			case s.State == "LISTEN":
+				data.Listening = append(data.Listening, s.SocketInfo)
+			case strings.HasPrefix(proto, "udp") && s.RemotePort == 0:
+				// Unconnected UDP sockets receive from anyone: they are the
+				// UDP equivalent of a listener.
+				s.State = "LISTEN"
+				data.Listening = append(data.Listening, s.SocketInfo)
+			case s.State == "ESTABLISHED":
+				if len(data.Established) >= maxConnections {
+					data.EstablishedTruncated = true
+					continue
+				}
+				data.Established = append(data.Established, s.SocketInfo)
+			}
+		}
+	}
+
+	data.UnixListening = parseUnix(filepath.Join(procRoot, "net", "unix"), owners, resolver)
+	return data
+}
+
+// parseNetFile reads /proc/net/{tcp,tcp6,udp,udp6}:
+//
+//	sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...
+func parseNetFile(path, proto string) []procSocket {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var sockets []procSocket
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		localIP, localPort, err := parseHexAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		remoteIP, remotePort, err := parseHexAddr(fields[2])
+		if err != nil {
+			continue
+		}
+
+		state := tcpStates[fields[3]]
+		if strings.HasPrefix(proto, "udp") {
+			state = "UNCONN"
+			if remotePort != 0 {
+				state = "ESTABLISHED"
+			}
+		}
+
+		s := procSocket{
+			SocketInfo: SocketInfo{
+				Protocol:     proto,
+				LocalAddress: localIP.String(),
+				LocalPort:    localPort,
+				State:        state,
+				Exposed:      isExposed(localIP),
+			},
+			uid:   fields[7],
+			inode: fields[9],
+		}
+		if remotePort != 0 || !remoteIP.IsUnspecified() {
+			s.RemoteAddress = remoteIP.String()
+			s.RemotePort = remotePort
+		}
+		sockets = append(sockets, s)
+	}
+	return sockets
+}
+
+// parseHexAddr decodes "0100007F:0035". Addresses are stored as 32-bit words
+// in host byte order (little-endian on every platform we ship for), so each
+// 4-byte group is reversed; the port is big-endian hex.
+func parseHexAddr(s string) (net.IP, int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed port %q", s)
+	}
+
+	ip := net.IP(raw)
+	if v4 := ip.To4(); v4 != nil && len(raw) == 16 && !ip.IsUnspecified() {
+		ip = v4 // IPv4-mapped IPv6 address
+	}
+	return ip, int(port), nil
+}
+
+func isExposed(ip net.IP) bool {
+	return !ip.IsLoopback()
+}
+
+// parseUnix lists listening unix sockets from /proc/net/unix:
+//
+//	Num RefCount Protocol Flags Type St Inode Path
+func parseUnix(path string, owners map[string]int32, resolver *ownerResolver) []UnixSocketInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const soAcceptCon = 0x10000
+	types := map[string]string{"0001": "stream", "0002": "dgram", "0005": "seqpacket"}
+
+	var sockets []UnixSocketInfo
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue // unnamed socket
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&soAcceptCon == 0 {
+			continue
+		}
+		s := UnixSocketInfo{
+			Path:  fields[7],
+			Type:  types[fields[4]],
+			State: "LISTEN",
+		}
+		if pid, ok := owners[fields[6]]; ok {
+			s.ProcessInfo = resolver.process(pid)
+		}
+		sockets = append(sockets, s)
+	}
+	return sockets
+}
+
+// socketOwners maps socket inodes to the PID holding them by reading every
+// /proc/<pid>/fd/* link of the form "socket:[<inode>]".
+func socketOwners(procRoot string) map[string]int32 {
+	owners := make(map[string]int32)
+	fdDirs, _ := filepath.Glob(filepath.Join(procRoot, "[0-9]*", "fd"))
+	for _, dir := range fdDirs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(dir)))
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			link, err := os.Readlink(filepath.Join(dir, e.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if _, seen := owners[inode]; !seen {
+				owners[inode] = int32(pid)
+			}
+		}
+	}
+	return owners
+}
+
+// ownerResolver caches process and user lookups for one gather.
+type ownerResolver struct {
+	procRoot  string
+	processes map[int32]ProcessInfo
+	users     map[string]string
+}
+
+func newOwnerResolver(procRoot string) *ownerResolver {
+	return &ownerResolver{
+		procRoot:  procRoot,
+		processes: make(map[int32]ProcessInfo),
+		users:     make(map[string]string),
+	}
+}
+
+func (r *ownerResolver) process(pid int32) ProcessInfo {
+	if p, ok := r.processes[pid]; ok {
+		return p
+	}
+
+	dir := filepath.Join(r.procRoot, strconv.Itoa(int(pid)))
+	info := ProcessInfo{PID: pid}
+	if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+		info.Process = strings.TrimSpace(string(comm))
+	}
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		info.Executable = exe
+	}
+	if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			if strings.HasPrefix(line, "Uid:") {
+				if f := strings.Fields(line); len(f) >= 2 {
+					info.User = r.username(f[1])
+				}
+				break
+			}
+		}
+	}
+
+	r.processes[pid] = info
+	return info
+}
+
+func (r *ownerResolver) username(uid string) string {
+	if name, ok := r.users[uid]; ok {
+		return name
+	}
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	r.users[uid] = name
+	return name
+}
+
+// --- macOS / Windows: gopsutil ---
+
+func gatherPsutil() (PortsData, error) {
+	var data PortsData
+
+	conns, err := gnet.Connections("inet")
+	if err != nil {
+		return data, err
+	}
+
+	processes := make(map[int32]ProcessInfo)
+	lookup := func(pid int32) ProcessInfo {
+		if pid <= 0 {
+			return ProcessInfo{}
+		}
+		if p, ok := processes[pid]; ok {
+			return p
+		}
+		info := ProcessInfo{PID: pid}
+		if p, err := process.NewProcess(pid); err == nil {
+			info.Process, _ = p.Name()
+			info.Executable, _ = p.Exe()
+			info.User, _ = p.Username()
+		}
+		processes[pid] = info
+		return info
+	}
+
+	for _, c := range conns {
+		proto := "tcp"
+		if c.Type == 2 { // SOCK_DGRAM
+			proto = "udp"
+		}
+		if c.Family == 23 || c.Family == 30 || strings.Contains(c.Laddr.IP, ":") { // AF_INET6 on Windows / macOS
+			proto += "6"
+		}
+
+		ip := net.ParseIP(c.Laddr.IP)
+		s := SocketInfo{
+			Protocol:      proto,
+			LocalAddress:  c.Laddr.IP,
+			LocalPort:     int(c.Laddr.Port),
+			RemoteAddress: c.Raddr.IP,
+			RemotePort:    int(c.Raddr.Port),
+			State:         c.Status,
+			Exposed:       ip != nil && isExposed(ip),
+			ProcessInfo:   lookup(c.Pid),
+		}
+
+		switch {
+		case s.State == "LISTEN" || (strings.HasPrefix(proto, "udp") && s.RemotePort == 0):
+			s.State = "LISTEN"
+			s.RemoteAddress = ""
+			data.Listening = append(data.Listening, s)
+		case s.State == "ESTABLISHED":
+			if len(data.Established) >= maxConnections {
+				data.EstablishedTruncated = true
+				continue
+			}
+			data.Established = append(data.Established, s)
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package processes
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID identifies a file by device and inode, so that every process of
+// one executable shares a cache entry whatever path it was started by.
+func fileID(path string, fi os.FileInfo) string {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", uint64(st.Dev), uint64(st.Ino))
+	}
+	return path
+}
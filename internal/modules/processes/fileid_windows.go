@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package processes
+
+import "os"
+
+// os.Stat does not expose the file index on Windows; the path is unique
+// enough for the cache.
+func fileID(path string, fi os.FileInfo) string {
+	return path
+}
@@ -0,0 +1,64 @@
+package processes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// maxHashSize skips hashing unusually large executables.
+const maxHashSize = 512 << 20
+
+// hashCache remembers executable hashes across gathers. Entries are keyed by
+// file identity (device and inode where available) and reused while size and
+// mtime are unchanged, so each binary is read once rather than every push.
+type hashCache struct {
+	entries map[string]hashEntry
+	used    map[string]bool
+}
+
+type hashEntry struct {
+	size    int64
+	modTime int64
+	sum     string
+}
+
+func newHashCache() *hashCache {
+	return &hashCache{entries: make(map[string]hashEntry), used: make(map[string]bool)}
+}
+
+func (c *hashCache) sum(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() > maxHashSize {
+		return ""
+	}
+	key := fileID(path, fi)
+	c.used[key] = true
+	if e, ok := c.entries[key]; ok && e.size == fi.Size() && e.modTime == fi.ModTime().UnixNano() {
+		return e.sum
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.entries[key] = hashEntry{size: fi.Size(), modTime: fi.ModTime().UnixNano(), sum: sum}
+	return sum
+}
+
+// prune drops the entries no process used since the last prune.
+func (c *hashCache) prune() {
+	for key := range c.entries {
+		if !c.used[key] {
+			delete(c.entries, key)
+		}
+	}
+	c.used = make(map[string]bool)
+}
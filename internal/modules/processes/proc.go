@@ -0,0 +1,104 @@
+package processes
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Linux-only details read from /proc/<pid>.
+
+func procPath(pid int32, name string) string {
+	return filepath.Join("/proc", strconv.Itoa(int(pid)), name)
+}
+
+// cgroupPath returns the cgroup v2 path, or on v1 hosts the path of the
+// systemd hierarchy (which the container runtimes also name after the
+// container).
+func cgroupPath(pid int32) string {
+	data, err := os.ReadFile(procPath(pid, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	var v1 string
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			return parts[2]
+		case parts[1] == "name=systemd" || v1 == "":
+			v1 = parts[2]
+		}
+	}
+	return v1
+}
+
+// containerRuntimes maps cgroup path markers to the runtime that creates
+// them, most specific first.
+var containerRuntimes = []struct{ marker, runtime string }{
+	{"cri-containerd-", "containerd"},
+	{"crio-", "cri-o"},
+	{"libpod-", "podman"},
+	{"docker", "docker"},
+	{"kubepods", "kubernetes"},
+	{"lxc", "lxc"},
+	{"containerd", "containerd"},
+}
+
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerFromCgroup extracts the 64-hex container ID that docker,
+// containerd, CRI-O and podman put in the cgroup path.
+func containerFromCgroup(cgroup string) (id, runtime string) {
+	matches := containerIDPattern.FindAllString(cgroup, -1)
+	if len(matches) == 0 {
+		return "", ""
+	}
+	id = matches[len(matches)-1] // the innermost cgroup is the container
+	for _, r := range containerRuntimes {
+		if strings.Contains(cgroup, r.marker) {
+			return id, r.runtime
+		}
+	}
+	return id, ""
+}
+
+var namespaceTypes = []string{"cgroup", "ipc", "mnt", "net", "pid", "time", "user", "uts"}
+
+// namespaces reads the namespace links, "net:[4026531840]", as name -> inode.
+func namespaces(pid int32) map[string]string {
+	ns := make(map[string]string)
+	for _, name := range namespaceTypes {
+		link, err := os.Readlink(procPath(pid, filepath.Join("ns", name)))
+		if err != nil {
+			continue
+		}
+		if i := strings.IndexByte(link, '['); i >= 0 {
+			ns[name] = strings.TrimSuffix(link[i+1:], "]")
+		}
+	}
+	return ns
+}
+
+// nonInitNamespaces keeps the namespaces that differ from PID 1's. Without
+// PID 1's namespaces (no permission) nothing can be compared.
+func nonInitNamespaces(ns, initNS map[string]string) map[string]string {
+	if len(initNS) == 0 {
+		return nil
+	}
+	var out map[string]string
+	for name, inode := range ns {
+		if ref, ok := initNS[name]; ok && ref != inode {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			out[name] = inode
+		}
+	}
+	return out
+}
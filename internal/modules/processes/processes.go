@@ -1,12 +1,26 @@
 package processes
 
 import (
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"snapsec-agent/internal/modules/ports"
+
 	"github.com/shirou/gopsutil/v3/process"
 )
 
-type ProcessesModule struct{}
+// ProcessesModule inventories running processes. RedactPatterns are regular
+// expressions matched against option and variable names in command lines, in
+// addition to defaultRedactPatterns; matching values are replaced.
+type ProcessesModule struct {
+	RedactPatterns []string
+
+	mu       sync.Mutex // gathers may overlap; the hash cache is shared
+	redactor *Redactor
+	hashes   *hashCache
+}
 
 type ProcessInfo struct {
 	Pid        int32   `json:"pid"`
@@ -16,6 +30,26 @@ type ProcessInfo struct {
 	CPUPercent float64 `json:"cpu_percent"`
 	MemoryMB   uint64  `json:"memory_mb"`
 	StartedAt  string  `json:"started_at"`
+	Cmdline    string  `json:"cmdline,omitempty"` // arguments joined by spaces, secrets redacted
+	Exe        string  `json:"exe,omitempty"`
+	ExeSHA256  string  `json:"exe_sha256,omitempty"`
+	ExeDeleted bool    `json:"exe_deleted,omitempty"` // the file was replaced or removed after start
+	Cwd        string  `json:"cwd,omitempty"`
+	Cgroup     string  `json:"cgroup,omitempty"`
+	// ContainerID and ContainerRuntime are derived from the cgroup path.
+	ContainerID      string `json:"container_id,omitempty"`
+	ContainerRuntime string `json:"container_runtime,omitempty"`
+	// Namespaces lists the namespaces (name -> inode) the process does not
+	// share with PID 1.
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+	Listening  []ListenSocket    `json:"listening,omitempty"`
+}
+
+// ListenSocket is a socket the process accepts connections or datagrams on.
+type ListenSocket struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
 }
 
 type ProcessesData struct {
@@ -28,11 +62,30 @@ func (m *ProcessesModule) Name() string {
 }
 
 func (m *ProcessesModule) Gather() (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hashes == nil {
+		m.redactor = NewRedactor(m.RedactPatterns)
+		m.hashes = newHashCache()
+	}
+
 	procs, err := process.Processes()
 	if err != nil {
 		return nil, err
 	}
 
+	listening := make(map[int32][]ListenSocket)
+	for _, s := range ports.Sockets().Listening {
+		if s.PID != 0 {
+			listening[s.PID] = append(listening[s.PID], ListenSocket{Protocol: s.Protocol, Address: s.LocalAddress, Port: s.LocalPort})
+		}
+	}
+
+	var initNS map[string]string
+	if runtime.GOOS == "linux" {
+		initNS = namespaces(1)
+	}
+
 	var list []ProcessInfo
 	for _, p := range procs {
 		ppid, _ := p.Ppid()
@@ -41,6 +94,9 @@ func (m *ProcessesModule) Gather() (interface{}, error) {
 		cpu, _ := p.CPUPercent()
 		mem, _ := p.MemoryInfo()
 		createTime, _ := p.CreateTime()
+		args, _ := p.CmdlineSlice()
+		exe, _ := p.Exe()
+		cwd, _ := p.Cwd()
 
 		startedAt := ""
 		if createTime > 0 {
@@ -52,7 +108,7 @@ func (m *ProcessesModule) Gather() (interface{}, error) {
 			memMB = mem.RSS / 1024 / 1024
 		}
 
-		list = append(list, ProcessInfo{
+		info := ProcessInfo{
 			Pid:        p.Pid,
 			PPid:       ppid,
 			Name:       name,
@@ -60,8 +116,33 @@ func (m *ProcessesModule) Gather() (interface{}, error) {
 			CPUPercent: cpu,
 			MemoryMB:   memMB,
 			StartedAt:  startedAt,
-		})
+			Cmdline:    m.redactor.Cmdline(args),
+			Cwd:        cwd,
+			Listening:  listening[p.Pid],
+		}
+
+		if exe != "" {
+			info.Exe = exe
+			hashPath := exe
+			if runtime.GOOS == "linux" {
+				// The exe link opens the running image even when the file
+				// on disk was deleted or replaced.
+				info.Exe = strings.TrimSuffix(exe, " (deleted)")
+				info.ExeDeleted = info.Exe != exe
+				hashPath = procPath(p.Pid, "exe")
+			}
+			info.ExeSHA256 = m.hashes.sum(hashPath)
+		}
+
+		if runtime.GOOS == "linux" {
+			info.Cgroup = cgroupPath(p.Pid)
+			info.ContainerID, info.ContainerRuntime = containerFromCgroup(info.Cgroup)
+			info.Namespaces = nonInitNamespaces(namespaces(p.Pid), initNS)
+		}
+
+		list = append(list, info)
 	}
+	m.hashes.prune()
 
 	return ProcessesData{
 		Count: len(list),
@@ -0,0 +1,132 @@
+package processes
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultRedactPatterns match option and variable names whose values are
+// secrets: --password=x, --api-key x, PGPASSWORD=x, -Djavax.net.ssl.keyStorePassword=x.
+var defaultRedactPatterns = []string{
+	`(?i)passw|pwd|secret|token|api[-_.]?key|access[-_.]?key|private[-_.]?key|credential|^auth$|authorization`,
+}
+
+// shortSecretFlags are the single-letter password options of tools often
+// given a password on the command line. The value is attached (-pSECRET) or,
+// where the tool accepts it, the next argument (-p SECRET): mysql reads
+// "-p name" as a prompt for the password of database name. Short options of
+// other tools are not recognised; their values are only redacted through a
+// long option name or URL.
+var shortSecretFlags = map[string]map[byte]bool{
+	"mysql":        {'p': false},
+	"mysqldump":    {'p': false},
+	"mysqladmin":   {'p': false},
+	"mariadb":      {'p': false},
+	"mariadb-dump": {'p': false},
+	"7z":           {'p': false},
+	"sshpass":      {'p': true},
+	"redis-cli":    {'a': true},
+	"mongo":        {'p': true},
+	"mongosh":      {'p': true},
+	"sqlcmd":       {'P': true},
+	"ldapsearch":   {'w': true},
+	"ldapmodify":   {'w': true},
+	"ldapadd":      {'w': true},
+	"ldapdelete":   {'w': true},
+	"curl":         {'u': true}, // user:password
+}
+
+// maxCmdline caps the reported command line; some JVMs and shells carry
+// classpaths of hundreds of kilobytes.
+const maxCmdline = 4096
+
+const redacted = "[REDACTED]"
+
+// urlCredentials matches the password of user:password@ in URLs.
+var urlCredentials = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+
+// Redactor removes secrets from command lines. It is shared with the
+// process event collector so that both report the same redacted form.
+type Redactor struct {
+	names []*regexp.Regexp
+}
+
+// NewRedactor compiles patterns in addition to defaultRedactPatterns.
+// Invalid patterns are logged and skipped.
+func NewRedactor(patterns []string) *Redactor {
+	r := &Redactor{}
+	for _, p := range append(append([]string(nil), defaultRedactPatterns...), patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			log.Printf("Ignoring invalid cmdline redaction pattern %q: %v", p, err)
+			continue
+		}
+		r.names = append(r.names, re)
+	}
+	return r
+}
+
+func (r *Redactor) sensitive(name string) bool {
+	for _, re := range r.names {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cmdline joins args after replacing secret values: the value of a matching
+// name=value argument, the argument after a matching --option, the password
+// of a known tool's short option (see shortSecretFlags), and URL passwords.
+func (r *Redactor) Cmdline(args []string) string {
+	var short map[byte]bool
+	if len(args) > 0 {
+		tool := strings.TrimSuffix(strings.ToLower(filepath.Base(args[0])), ".exe")
+		short = shortSecretFlags[tool]
+	}
+
+	out := make([]string, len(args))
+	redactNext := false
+	for i, arg := range args {
+		if redactNext && !strings.HasPrefix(arg, "-") {
+			out[i] = redacted
+			redactNext = false
+			continue
+		}
+		redactNext = false
+
+		if i > 0 && len(arg) >= 2 && arg[0] == '-' && arg[1] != '-' {
+			if separate, ok := short[arg[1]]; ok {
+				if len(arg) > 2 {
+					out[i] = arg[:2] + redacted
+					continue
+				}
+				redactNext = separate
+				out[i] = arg
+				continue
+			}
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if strings.HasPrefix(arg, "-D") {
+			name = arg[2:] // Java system property
+		}
+		k, _, hasValue := strings.Cut(name, "=")
+		if hasValue && i > 0 && r.sensitive(k) {
+			out[i] = arg[:strings.Index(arg, "=")+1] + redacted
+			continue
+		}
+		if !hasValue && strings.HasPrefix(arg, "-") && r.sensitive(name) {
+			redactNext = true
+		}
+		out[i] = urlCredentials.ReplaceAllString(arg, "${1}"+redacted+"@")
+	}
+
+	s := strings.Join(out, " ")
+	if len(s) > maxCmdline {
+		s = s[:maxCmdline]
+	}
+	return s
+}
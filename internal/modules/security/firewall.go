@@ -0,0 +1,202 @@
+package security
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gatherFirewall reads the kernel rule sets and the ufw/firewalld
+// configuration. iptables-nft rules are nftables rules and are counted there;
+// iptables-legacy is read separately.
+func gatherFirewall() FirewallData {
+	var data FirewallData
+
+	nft := nftablesBackend()
+	if nft.Error == "" && nft.Tables > 0 {
+		data.Backends = append(data.Backends, nft)
+	}
+	for _, b := range iptablesLegacyBackends() {
+		if b.Error == "" && b.Chains > 0 {
+			data.Backends = append(data.Backends, b)
+		}
+	}
+
+	data.UFW = gatherUFW()
+	data.Firewalld = gatherFirewalld()
+
+	switch {
+	case data.Firewalld != nil && data.Firewalld.Running:
+		data.Type, data.Status = "firewalld", "enabled"
+	case data.UFW != nil:
+		data.Type, data.Status = "ufw", "disabled"
+		if data.UFW.Enabled {
+			data.Status = "enabled"
+		}
+	case len(data.Backends) > 0:
+		data.Type = data.Backends[0].Name
+		data.Status = "disabled"
+		for _, b := range data.Backends {
+			if filters(b) {
+				data.Status = "enabled"
+			}
+		}
+	default:
+		data.Status = "disabled"
+		if nft.Error != "" {
+			// Without a readable rule set the state is not known.
+			data.Status = "unknown"
+		}
+	}
+	return data
+}
+
+// filters reports whether a backend actually restricts traffic: it has rules
+// or a base chain that drops by default.
+func filters(b FirewallBackend) bool {
+	if b.Rules > 0 {
+		return true
+	}
+	for _, policy := range b.Policies {
+		if policy == "drop" || policy == "DROP" {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherUFW reads ufw's own configuration; nil when ufw is not installed.
+func gatherUFW() *UFWData {
+	if _, err := os.Stat("/etc/ufw/ufw.conf"); err != nil {
+		return nil
+	}
+	data := &UFWData{
+		Enabled:         strings.EqualFold(configValue("/etc/ufw/ufw.conf", "ENABLED"), "yes"),
+		DefaultIncoming: ufwPolicy(configValue("/etc/default/ufw", "DEFAULT_INPUT_POLICY")),
+		DefaultOutgoing: ufwPolicy(configValue("/etc/default/ufw", "DEFAULT_OUTPUT_POLICY")),
+		DefaultRouted:   ufwPolicy(configValue("/etc/default/ufw", "DEFAULT_FORWARD_POLICY")),
+	}
+	// Each user rule is stored with a "### tuple ###" comment.
+	for _, path := range []string{"/etc/ufw/user.rules", "/etc/ufw/user6.rules"} {
+		data.Rules += countLines(path, func(line string) bool {
+			return strings.HasPrefix(line, "### tuple ###")
+		})
+	}
+	return data
+}
+
+// ufwPolicy maps iptables targets in /etc/default/ufw to ufw's wording.
+func ufwPolicy(v string) string {
+	switch strings.ToUpper(v) {
+	case "DROP":
+		return "deny"
+	case "ACCEPT":
+		return "allow"
+	case "REJECT":
+		return "reject"
+	}
+	return strings.ToLower(v)
+}
+
+// gatherFirewalld reads firewalld's configuration and checks its pid file;
+// nil when firewalld is not installed.
+func gatherFirewalld() *FirewalldData {
+	const conf = "/etc/firewalld/firewalld.conf"
+	if _, err := os.Stat(conf); err != nil {
+		return nil
+	}
+	return &FirewalldData{
+		Running:     pidFileAlive("/run/firewalld.pid") || processRunning("firewalld"),
+		DefaultZone: configValue(conf, "DefaultZone"),
+		Backend:     configValue(conf, "FirewallBackend"),
+	}
+}
+
+// --- helpers shared by the posture checks ---
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
+
+// configValue returns KEY's value from a shell-style KEY=value file.
+func configValue(path, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if ok && strings.TrimSpace(k) == key {
+			return strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return ""
+}
+
+func countLines(path string, match func(string) bool) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if match(strings.TrimSpace(scanner.Text())) {
+			n++
+		}
+	}
+	return n
+}
+
+func pidFileAlive(path string) bool {
+	pid, err := readTrimmed(path)
+	if err != nil || pid == "" {
+		return false
+	}
+	_, err = os.Stat(filepath.Join("/proc", pid))
+	return err == nil
+}
+
+// processRunning looks for a process by its kernel command name.
+func processRunning(names ...string) bool {
+	return len(runningProcesses(names...)) > 0
+}
+
+// runningProcesses returns which of the given command names have a live
+// process.
+func runningProcesses(names ...string) []string {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		// /proc/<pid>/comm is truncated to 15 characters.
+		if len(n) > 15 {
+			n = n[:15]
+		}
+		want[n] = true
+	}
+	found := make(map[string]bool)
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	for _, path := range comms {
+		comm, err := readTrimmed(path)
+		if err == nil && want[comm] {
+			found[comm] = true
+		}
+	}
+	var out []string
+	for _, n := range names {
+		short := n
+		if len(short) > 15 {
+			short = short[:15]
+		}
+		if found[short] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
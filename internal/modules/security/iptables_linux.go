@@ -0,0 +1,167 @@
+//go:build linux && (amd64 || arm64)
+// +build linux
+// +build amd64 arm64
+
+package security
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// iptables-legacy keeps its rules in the x_tables kernel modules rather than
+// nftables. They are fetched with the same getsockopt calls iptables-save
+// uses. Only tables that are loaded (listed under /proc/net) are queried, so
+// reading never loads a module.
+
+const (
+	xtSoGetInfo    = 64 // IPT_SO_GET_INFO, IP6T_SO_GET_INFO
+	xtSoGetEntries = 65
+	xtTableMaxLen  = 32
+	xtTargetHeader = 32 // u16 target_size, char name[29], u8 revision
+)
+
+var xtHooks = []string{"PREROUTING", "INPUT", "FORWARD", "OUTPUT", "POSTROUTING"}
+
+type xtFamily struct {
+	name      string
+	namesFile string
+	domain    int
+	level     int
+	// Offsets of target_offset and next_offset within ipt_entry/ip6t_entry.
+	targetOffsetAt int
+}
+
+var xtFamilies = []xtFamily{
+	{"iptables-legacy", "/proc/net/ip_tables_names", syscall.AF_INET, syscall.IPPROTO_IP, 88},
+	{"ip6tables-legacy", "/proc/net/ip6_tables_names", syscall.AF_INET6, syscall.IPPROTO_IPV6, 140},
+}
+
+func iptablesLegacyBackends() []FirewallBackend {
+	var backends []FirewallBackend
+	for _, fam := range xtFamilies {
+		data, err := readTrimmed(fam.namesFile)
+		if err != nil || data == "" {
+			continue
+		}
+		b := FirewallBackend{Name: fam.name, Policies: make(map[string]string)}
+		for _, table := range strings.Fields(data) {
+			if err := xtReadTable(fam, table, &b); err != nil {
+				b.Error = err.Error()
+				break
+			}
+			b.Tables++
+		}
+		backends = append(backends, b)
+	}
+	return backends
+}
+
+func xtReadTable(fam xtFamily, table string, b *FirewallBackend) error {
+	fd, err := syscall.Socket(fam.domain, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.IPPROTO_RAW)
+	if err != nil {
+		return fmt.Errorf("raw socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	// struct ipt_getinfo: name[32], valid_hooks, hook_entry[5], underflow[5],
+	// num_entries, size
+	info := make([]byte, xtTableMaxLen+4+20+20+4+4)
+	copy(info, table)
+	if err := getsockopt(fd, fam.level, xtSoGetInfo, info); err != nil {
+		return fmt.Errorf("%s: %w", table, err)
+	}
+	u32 := func(off int) uint32 { return binary.NativeEndian.Uint32(info[off:]) }
+	validHooks := u32(32)
+	numEntries := int(u32(76))
+	size := int(u32(80))
+
+	// struct ipt_get_entries: name[32], size, then the entries aligned for
+	// their 64-bit counters.
+	header := (xtTableMaxLen + 4 + 7) &^ 7
+	buf := make([]byte, header+size)
+	copy(buf, table)
+	binary.NativeEndian.PutUint32(buf[xtTableMaxLen:], uint32(size))
+	if err := getsockopt(fd, fam.level, xtSoGetEntries, buf); err != nil {
+		return fmt.Errorf("%s: %w", table, err)
+	}
+	entries := buf[header:]
+
+	builtin, userChains := 0, 0
+	for hook, name := range xtHooks {
+		if validHooks&(1<<uint(hook)) == 0 {
+			continue
+		}
+		builtin++
+		if verdict, ok := xtVerdict(fam, entries, int(u32(56+4*hook))); ok {
+			b.Policies[table+" "+name] = verdict
+		}
+	}
+
+	// User-defined chains start with an ERROR target naming the chain; the
+	// table ends with one more ERROR entry.
+	for off := 0; off < len(entries); {
+		targetOff, nextOff := xtOffsets(fam, entries, off)
+		if nextOff == 0 {
+			break
+		}
+		if name := xtTargetName(entries, off+targetOff); name == "ERROR" {
+			userChains++
+		}
+		off += nextOff
+	}
+	userChains-- // the terminating entry
+
+	b.Chains += builtin + userChains
+	// Each built-in chain ends in its policy entry; each user chain has its
+	// head and a trailing RETURN.
+	if rules := numEntries - 1 - builtin - 2*userChains; rules > 0 {
+		b.Rules += rules
+	}
+	return nil
+}
+
+func xtOffsets(fam xtFamily, entries []byte, off int) (target, next int) {
+	at := off + fam.targetOffsetAt
+	if at+4 > len(entries) {
+		return 0, 0
+	}
+	return int(binary.NativeEndian.Uint16(entries[at:])), int(binary.NativeEndian.Uint16(entries[at+2:]))
+}
+
+func xtTargetName(entries []byte, at int) string {
+	if at+xtTargetHeader > len(entries) {
+		return ""
+	}
+	return cString(entries[at+2 : at+2+29])
+}
+
+// xtVerdict reads the standard target of the policy entry at off. Verdicts
+// are stored as -NF_<verdict> - 1.
+func xtVerdict(fam xtFamily, entries []byte, off int) (string, bool) {
+	targetOff, _ := xtOffsets(fam, entries, off)
+	at := off + targetOff + xtTargetHeader
+	if targetOff == 0 || at+4 > len(entries) {
+		return "", false
+	}
+	switch int32(binary.NativeEndian.Uint32(entries[at:])) {
+	case -1:
+		return "DROP", true
+	case -2:
+		return "ACCEPT", true
+	}
+	return "", false
+}
+
+func getsockopt(fd, level, opt int, buf []byte) error {
+	l := uint32(len(buf))
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&l)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
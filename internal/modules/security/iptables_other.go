@@ -0,0 +1,10 @@
+//go:build !linux || !(amd64 || arm64)
+// +build !linux !amd64,!arm64
+
+package security
+
+// iptables-legacy is only read on the architectures the agent ships for on
+// Linux; elsewhere its rules are not reported.
+func iptablesLegacyBackends() []FirewallBackend {
+	return nil
+}
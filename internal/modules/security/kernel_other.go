@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package security
+
+// The posture checks only run on Linux; these keep the package building on
+// the other platforms.
+
+func nftablesBackend() FirewallBackend {
+	return FirewallBackend{Name: "nftables", Error: "not supported on this platform"}
+}
+
+func kernelClockSynced() (bool, bool) {
+	return false, false
+}
@@ -0,0 +1,193 @@
+//go:build linux
+// +build linux
+
+package security
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+)
+
+// nftables is read over NETLINK_NETFILTER the same way `nft list ruleset`
+// does, so no binary is needed. iptables-nft rules live here too.
+
+const (
+	nfnlSubsysNftables = 10
+	nftMsgGetTable     = 1
+	nftMsgGetChain     = 4
+	nftMsgGetRule      = 7
+
+	nftaChainTable  = 1
+	nftaChainName   = 3
+	nftaChainHook   = 4
+	nftaChainPolicy = 5
+	nftaHookHooknum = 1
+
+	nlaTypeMask = 0x3fff // strips NLA_F_NESTED and NLA_F_NET_BYTEORDER
+)
+
+var nfFamilies = map[uint8]string{1: "inet", 2: "ip", 3: "arp", 5: "netdev", 7: "bridge", 10: "ip6"}
+
+func nftablesBackend() FirewallBackend {
+	b := FirewallBackend{Name: "nftables", Policies: make(map[string]string)}
+
+	tables, err := nftDump(nftMsgGetTable)
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	b.Tables = len(tables)
+
+	chains, err := nftDump(nftMsgGetChain)
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	b.Chains = len(chains)
+	for _, c := range chains {
+		attrs := nlAttrs(c.data)
+		hook, isBase := attrs[nftaChainHook]
+		policy, hasPolicy := attrs[nftaChainPolicy]
+		if !isBase || !hasPolicy || len(policy) < 4 {
+			continue
+		}
+		hookName := nfHookName(c.family, nlAttrs(hook)[nftaHookHooknum])
+		key := fmt.Sprintf("%s %s %s (%s)", nfFamilyName(c.family),
+			cString(attrs[nftaChainTable]), cString(attrs[nftaChainName]), hookName)
+		// NF_DROP = 0, NF_ACCEPT = 1
+		if binary.BigEndian.Uint32(policy) == 0 {
+			b.Policies[key] = "drop"
+		} else {
+			b.Policies[key] = "accept"
+		}
+	}
+
+	rules, err := nftDump(nftMsgGetRule)
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	b.Rules = len(rules)
+	return b
+}
+
+type nfMessage struct {
+	family uint8
+	data   []byte // attributes after the nfgenmsg header
+}
+
+// nftDump sends a dump request for one object type across all families and
+// collects the replies.
+func nftDump(msgType uint16) ([]nfMessage, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	// Never let a silent kernel stall the reporting loop.
+	tv := syscall.Timeval{Sec: 2}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+
+	// nlmsghdr followed by nfgenmsg{family: NFPROTO_UNSPEC, version: 0, res_id: 0}
+	req := make([]byte, syscall.NLMSG_HDRLEN+4)
+	binary.NativeEndian.PutUint16(req[4:6], nfnlSubsysNftables<<8|msgType)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send: %w", err)
+	}
+
+	var out []nfMessage
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("netlink receive: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return out, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(m.Data[0:4])); errno != 0 {
+						return nil, fmt.Errorf("nftables: %w", syscall.Errno(-errno))
+					}
+				}
+				return out, nil
+			}
+			if len(m.Data) < 4 {
+				continue
+			}
+			out = append(out, nfMessage{family: m.Data[0], data: m.Data[4:]})
+		}
+	}
+}
+
+// nlAttrs indexes a flat list of netlink attributes by type.
+func nlAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= 4 {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		if l < 4 || l > len(b) {
+			break
+		}
+		attrs[binary.NativeEndian.Uint16(b[2:4])&nlaTypeMask] = b[4:l]
+		aligned := (l + 3) &^ 3
+		if aligned > len(b) {
+			break
+		}
+		b = b[aligned:]
+	}
+	return attrs
+}
+
+// cString trims the NUL terminator of a netlink string attribute.
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+func nfFamilyName(f uint8) string {
+	if name, ok := nfFamilies[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("family-%d", f)
+}
+
+func nfHookName(family uint8, raw []byte) string {
+	if len(raw) < 4 {
+		return "unknown"
+	}
+	hook := binary.BigEndian.Uint32(raw)
+	var names []string
+	switch family {
+	case 3: // arp
+		names = []string{"input", "output", "forward"}
+	case 5: // netdev
+		names = []string{"ingress", "egress"}
+	default:
+		names = []string{"prerouting", "input", "forward", "output", "postrouting"}
+	}
+	if int(hook) < len(names) {
+		return names[hook]
+	}
+	return fmt.Sprintf("hook-%d", hook)
+}
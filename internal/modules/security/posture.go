@@ -0,0 +1,257 @@
+package security
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- AppArmor ---
+
+func gatherAppArmor() AppArmorData {
+	var data AppArmorData
+	enabled, err := readTrimmed("/sys/module/apparmor/parameters/enabled")
+	if err != nil || enabled != "Y" {
+		return data
+	}
+	data.Enabled = true
+
+	// One "name (mode)" line per loaded profile.
+	f, err := os.Open("/sys/kernel/security/apparmor/profiles")
+	if err != nil {
+		return data
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		data.Profiles++
+		switch {
+		case strings.HasSuffix(line, "(enforce)"):
+			data.Enforce++
+		case strings.HasSuffix(line, "(complain)"):
+			data.Complain++
+		default:
+			data.Other++
+		}
+	}
+	return data
+}
+
+// --- Secure Boot ---
+
+const efiGlobalVariable = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// gatherSecureBoot reads the SecureBoot and SetupMode EFI variables. Each
+// efivarfs file is a 4-byte attribute mask followed by the value.
+func gatherSecureBoot() SecureBootData {
+	if _, err := os.Stat("/sys/firmware/efi"); err != nil {
+		return SecureBootData{Status: "unsupported"}
+	}
+	data := SecureBootData{UEFI: true, Status: "unknown"}
+	if v, ok := efiVariable("SecureBoot"); ok {
+		data.Status = "disabled"
+		if v == 1 {
+			data.Status = "enabled"
+		}
+	}
+	if v, ok := efiVariable("SetupMode"); ok {
+		data.SetupMode = v == 1
+	}
+	return data
+}
+
+func efiVariable(name string) (byte, bool) {
+	b, err := os.ReadFile(filepath.Join("/sys/firmware/efi/efivars", name+"-"+efiGlobalVariable))
+	if err != nil || len(b) < 5 {
+		return 0, false
+	}
+	return b[4], true
+}
+
+// --- Disk encryption ---
+
+// gatherEncryption checks every mounted block device, following device-mapper
+// stacks (LVM on LUKS, etc.) down to a dm-crypt mapping.
+func gatherEncryption() []VolumeEncryption {
+	f, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var volumes []VolumeEncryption
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		device, mountPoint := fields[0], unescapeMount(fields[1])
+		if seen[mountPoint] {
+			continue
+		}
+		seen[mountPoint] = true
+
+		v := VolumeEncryption{MountPoint: mountPoint, Device: device, FSType: fields[2]}
+		if resolved, err := filepath.EvalSymlinks(device); err == nil {
+			v.Type = cryptType(filepath.Base(resolved), 0)
+		}
+		v.Encrypted = v.Type != ""
+		volumes = append(volumes, v)
+	}
+	return volumes
+}
+
+// cryptType returns LUKS1, LUKS2 or PLAIN when the block device or anything
+// beneath it is a dm-crypt mapping. dm-crypt UUIDs look like
+// CRYPT-LUKS2-<uuid>-<name>.
+func cryptType(dev string, depth int) string {
+	if depth > 8 {
+		return ""
+	}
+	base := filepath.Join("/sys/class/block", dev)
+	if uuid, err := readTrimmed(filepath.Join(base, "dm", "uuid")); err == nil && strings.HasPrefix(uuid, "CRYPT-") {
+		parts := strings.SplitN(uuid, "-", 3)
+		if len(parts) >= 2 {
+			return parts[1]
+		}
+		return "PLAIN"
+	}
+	slaves, _ := os.ReadDir(filepath.Join(base, "slaves"))
+	for _, s := range slaves {
+		if t := cryptType(s.Name(), depth+1); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// unescapeMount undoes the octal escaping of spaces etc. in /proc/mounts.
+func unescapeMount(s string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(s)
+}
+
+// --- auditd ---
+
+func gatherAuditd() AuditdData {
+	var data AuditdData
+	for _, p := range []string{"/sbin/auditd", "/usr/sbin/auditd"} {
+		if _, err := os.Stat(p); err == nil {
+			data.Installed = true
+		}
+	}
+	data.Running = pidFileAlive("/run/auditd.pid") || processRunning("auditd")
+
+	// augenrules compiles rules.d into audit.rules; count either.
+	isRule := func(line string) bool {
+		return strings.HasPrefix(line, "-a") || strings.HasPrefix(line, "-w") || strings.HasPrefix(line, "-A")
+	}
+	data.Rules = countLines("/etc/audit/audit.rules", isRule)
+	if data.Rules == 0 {
+		files, _ := filepath.Glob("/etc/audit/rules.d/*.rules")
+		for _, f := range files {
+			data.Rules += countLines(f, isRule)
+		}
+	}
+	return data
+}
+
+// --- kernel lockdown, sysctls ---
+
+// gatherLockdown returns the bracketed mode from "none [integrity] confidentiality".
+func gatherLockdown() string {
+	data, err := readTrimmed("/sys/kernel/security/lockdown")
+	if err != nil {
+		return ""
+	}
+	if i := strings.Index(data, "["); i >= 0 {
+		if j := strings.Index(data[i:], "]"); j > 0 {
+			return data[i+1 : i+j]
+		}
+	}
+	return ""
+}
+
+// securitySysctls are the kernel settings hardening benchmarks (CIS, KSPP)
+// check. Missing keys are left out.
+var securitySysctls = []string{
+	"kernel.randomize_va_space",
+	"kernel.kptr_restrict",
+	"kernel.dmesg_restrict",
+	"kernel.yama.ptrace_scope",
+	"kernel.unprivileged_bpf_disabled",
+	"kernel.unprivileged_userns_clone",
+	"kernel.kexec_load_disabled",
+	"kernel.sysrq",
+	"kernel.core_uses_pid",
+	"kernel.perf_event_paranoid",
+	"fs.suid_dumpable",
+	"fs.protected_symlinks",
+	"fs.protected_hardlinks",
+	"fs.protected_fifos",
+	"fs.protected_regular",
+	"net.core.bpf_jit_harden",
+	"net.ipv4.ip_forward",
+	"net.ipv4.tcp_syncookies",
+	"net.ipv4.conf.all.accept_redirects",
+	"net.ipv4.conf.all.secure_redirects",
+	"net.ipv4.conf.all.send_redirects",
+	"net.ipv4.conf.all.accept_source_route",
+	"net.ipv4.conf.all.rp_filter",
+	"net.ipv4.conf.all.log_martians",
+	"net.ipv4.icmp_echo_ignore_broadcasts",
+	"net.ipv6.conf.all.forwarding",
+	"net.ipv6.conf.all.accept_redirects",
+	"net.ipv6.conf.all.accept_source_route",
+	"net.ipv6.conf.all.accept_ra",
+}
+
+func gatherSysctls() map[string]string {
+	values := make(map[string]string)
+	for _, key := range securitySysctls {
+		path := filepath.Join("/proc/sys", strings.ReplaceAll(key, ".", "/"))
+		if v, err := readTrimmed(path); err == nil {
+			values[key] = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	return values
+}
+
+func aslrMode(v string) string {
+	switch v {
+	case "2":
+		return "full"
+	case "1":
+		return "partial"
+	case "0":
+		return "disabled"
+	}
+	return ""
+}
+
+// --- time synchronisation ---
+
+var timeSyncDaemons = []string{"systemd-timesyncd", "chronyd", "ntpd", "openntpd", "ntpsec"}
+
+// gatherTimeSync asks the kernel whether its clock is being disciplined,
+// which holds regardless of the daemon, and lists the daemons running.
+func gatherTimeSync() TimeSyncData {
+	data := TimeSyncData{Status: "unknown", Daemons: runningProcesses(timeSyncDaemons...)}
+	if synced, ok := kernelClockSynced(); ok {
+		data.Synchronized = synced
+		data.Status = "unsynchronized"
+		if synced {
+			data.Status = "synchronized"
+		}
+	} else if _, err := os.Stat("/run/systemd/timesync/synchronized"); err == nil {
+		data.Synchronized = true
+		data.Status = "synchronized"
+	}
+	return data
+}
@@ -8,18 +8,95 @@ import (
 
 type SecurityModule struct{}
 
+// FirewallData summarises the active packet filter. Type is the front end
+// that manages it when one is active (ufw, firewalld), otherwise the kernel
+// backend (nftables, iptables). Backends lists every backend with rules.
 type FirewallData struct {
-	Type   string `json:"type"`
-	Status string `json:"status"`
+	Type      string            `json:"type"`
+	Status    string            `json:"status"`
+	Backends  []FirewallBackend `json:"backends,omitempty"`
+	UFW       *UFWData          `json:"ufw,omitempty"`
+	Firewalld *FirewalldData    `json:"firewalld,omitempty"`
+}
+
+// FirewallBackend is the kernel rule set of one backend. Policies maps a
+// base chain (e.g. "ip filter INPUT") to its default verdict.
+type FirewallBackend struct {
+	Name     string            `json:"name"` // nftables, iptables-legacy, ip6tables-legacy
+	Tables   int               `json:"tables"`
+	Chains   int               `json:"chains"`
+	Rules    int               `json:"rules"`
+	Policies map[string]string `json:"policies,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type UFWData struct {
+	Enabled         bool   `json:"enabled"`
+	DefaultIncoming string `json:"default_incoming,omitempty"`
+	DefaultOutgoing string `json:"default_outgoing,omitempty"`
+	DefaultRouted   string `json:"default_routed,omitempty"`
+	Rules           int    `json:"rules"`
+}
+
+type FirewalldData struct {
+	Running     bool   `json:"running"`
+	DefaultZone string `json:"default_zone,omitempty"`
+	Backend     string `json:"backend,omitempty"` // nftables or iptables
 }
 
 type SELinuxData struct {
 	Status string `json:"status"`
+	Mode   string `json:"mode,omitempty"`   // enforcing, permissive
+	Policy string `json:"policy,omitempty"` // e.g. targeted
+}
+
+type AppArmorData struct {
+	Enabled  bool `json:"enabled"`
+	Profiles int  `json:"profiles"`
+	Enforce  int  `json:"enforce"`
+	Complain int  `json:"complain"`
+	Other    int  `json:"other"` // kill, unconfined, prompt modes
+}
+
+type SecureBootData struct {
+	UEFI      bool   `json:"uefi"`
+	Status    string `json:"status"` // enabled, disabled, unsupported, unknown
+	SetupMode bool   `json:"setup_mode"`
+}
+
+// VolumeEncryption reports whether the block device behind a mount point is
+// (or sits on) a dm-crypt mapping.
+type VolumeEncryption struct {
+	MountPoint string `json:"mount_point"`
+	Device     string `json:"device"`
+	FSType     string `json:"fs_type"`
+	Encrypted  bool   `json:"encrypted"`
+	Type       string `json:"type,omitempty"` // LUKS1, LUKS2, PLAIN
+}
+
+type AuditdData struct {
+	Installed bool `json:"installed"`
+	Running   bool `json:"running"`
+	Rules     int  `json:"rules"` // persistent rules in /etc/audit
+}
+
+type TimeSyncData struct {
+	Synchronized bool     `json:"synchronized"` // kernel clock is disciplined (adjtimex)
+	Daemons      []string `json:"daemons,omitempty"`
+	Status       string   `json:"status"` // synchronized, unsynchronized, unknown
 }
 
 type SecurityData struct {
-	Firewall FirewallData `json:"firewall"`
-	SELinux  SELinuxData  `json:"selinux"`
+	Firewall   FirewallData       `json:"firewall"`
+	SELinux    SELinuxData        `json:"selinux"`
+	AppArmor   AppArmorData       `json:"apparmor"`
+	SecureBoot SecureBootData     `json:"secure_boot"`
+	Encryption []VolumeEncryption `json:"encryption,omitempty"`
+	Auditd     AuditdData         `json:"auditd"`
+	Lockdown   string             `json:"lockdown,omitempty"` // none, integrity, confidentiality
+	ASLR       string             `json:"aslr,omitempty"`     // full, partial, disabled
+	Sysctls    map[string]string  `json:"sysctls,omitempty"`
+	TimeSync   TimeSyncData       `json:"time_sync"`
 }
 
 func (m *SecurityModule) Name() string {
@@ -30,26 +107,41 @@ func (m *SecurityModule) Gather() (interface{}, error) {
 	var data SecurityData
 
 	if runtime.GOOS == "linux" {
-		// Firewall (UFW)
-		if _, err := exec.LookPath("ufw"); err == nil {
-			out, _ := exec.Command("sudo", "ufw", "status").Output()
-			if strings.Contains(string(out), "Status: active") {
-				data.Firewall = FirewallData{Type: "ufw", Status: "enabled"}
-			} else {
-				data.Firewall = FirewallData{Type: "ufw", Status: "disabled"}
-			}
-		}
+		// Everything is read from the kernel and config files directly; the
+		// agent runs as root and must not depend on sudo.
+		data.Firewall = gatherFirewall()
+		data.SELinux = gatherSELinux()
+		data.AppArmor = gatherAppArmor()
+		data.SecureBoot = gatherSecureBoot()
+		data.Encryption = gatherEncryption()
+		data.Auditd = gatherAuditd()
+		data.Lockdown = gatherLockdown()
+		data.Sysctls = gatherSysctls()
+		data.ASLR = aslrMode(data.Sysctls["kernel.randomize_va_space"])
+		data.TimeSync = gatherTimeSync()
+	}
 
-		// SELinux
-		if _, err := exec.LookPath("sestatus"); err == nil {
-			out, _ := exec.Command("sestatus").Output()
-			if strings.Contains(string(out), "SELinux status:                 enabled") {
-				data.SELinux = SELinuxData{Status: "enabled"}
-			} else {
-				data.SELinux = SELinuxData{Status: "disabled"}
-			}
+	return data, nil
+}
+
+// gatherSELinux reads selinuxfs and falls back to sestatus where it is not
+// mounted (e.g. inside some containers).
+func gatherSELinux() SELinuxData {
+	if enforce, err := readTrimmed("/sys/fs/selinux/enforce"); err == nil {
+		data := SELinuxData{Status: "enabled", Mode: "permissive"}
+		if enforce == "1" {
+			data.Mode = "enforcing"
 		}
+		data.Policy = configValue("/etc/selinux/config", "SELINUXTYPE")
+		return data
 	}
 
-	return data, nil
+	if _, err := exec.LookPath("sestatus"); err == nil {
+		out, _ := exec.Command("sestatus").Output()
+		if strings.Contains(string(out), "SELinux status:                 enabled") {
+			return SELinuxData{Status: "enabled"}
+		}
+		return SELinuxData{Status: "disabled"}
+	}
+	return SELinuxData{}
 }
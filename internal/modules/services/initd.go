@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Non-systemd hosts (Alpine and Gentoo with OpenRC, older Debian/RHEL with
+// SysV init) are read from their init scripts and runlevel links. Init
+// scripts have no sandboxing, so a service running as root is unhardened.
+
+const initDir = "/etc/init.d"
+
+// initScript holds the variables of an init script that matter here.
+type initScript struct {
+	path    string
+	binary  string
+	user    string
+	pidfile string
+}
+
+// --- OpenRC ---
+
+func openrcServices() []ServiceInfo {
+	var services []ServiceInfo
+	for _, script := range initScripts("openrc-run") {
+		name := filepath.Base(script.path)
+		s := ServiceInfo{
+			Name:     name,
+			Init:     "openrc",
+			UnitFile: script.path,
+			Binary:   script.binary,
+			Status:   "stopped",
+		}
+		for _, level := range []string{"sysinit", "boot", "default"} {
+			if fileExists(filepath.Join("/etc/runlevels", level, name)) {
+				s.Enabled = true
+				s.Enablement = level
+				break
+			}
+		}
+		if fileExists(filepath.Join("/run/openrc/started", name)) {
+			s.Status = "started"
+		} else if fileExists(filepath.Join("/run/openrc/failed", name)) {
+			s.Status = "failed"
+		}
+
+		// command_user=user[:group]
+		s.User, s.Group, _ = strings.Cut(script.user, ":")
+		finishInitService(&s, script)
+		services = append(services, s)
+	}
+	return services
+}
+
+// --- SysV ---
+
+func sysvServices() []ServiceInfo {
+	enabled := make(map[string]bool)
+	for _, pattern := range []string{"/etc/rc[2345].d/S*", "/etc/rc.d/rc[2345].d/S*"} {
+		links, _ := filepath.Glob(pattern)
+		for _, link := range links {
+			// S20ssh -> ssh
+			name := strings.TrimLeft(strings.TrimPrefix(filepath.Base(link), "S"), "0123456789")
+			enabled[name] = true
+		}
+	}
+
+	var services []ServiceInfo
+	for _, script := range initScripts("") {
+		name := filepath.Base(script.path)
+		s := ServiceInfo{
+			Name:     name,
+			Init:     "sysv",
+			UnitFile: script.path,
+			Binary:   script.binary,
+			Enabled:  enabled[name],
+			Status:   "unknown",
+		}
+		if s.Enabled {
+			s.Enablement = "enabled"
+		} else {
+			s.Enablement = "disabled"
+		}
+		finishInitService(&s, script)
+		services = append(services, s)
+	}
+	return services
+}
+
+// finishInitService fills the PID and owner from the pidfile when the
+// service is running.
+func finishInitService(s *ServiceInfo, script initScript) {
+	if script.pidfile != "" {
+		if data, err := os.ReadFile(script.pidfile); err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 {
+				if uid, ok := processUID(pid); ok {
+					s.MainPID = pid
+					s.Status = "running"
+					if s.User == "" {
+						s.User = strconv.Itoa(uid)
+					}
+				} else if s.Status == "unknown" {
+					s.Status = "stopped"
+				}
+			}
+		}
+	}
+	s.ExecStart = s.Binary
+	s.RunsAsRoot = s.User == "" || s.User == "root" || s.User == "0"
+	s.Unhardened = s.RunsAsRoot
+}
+
+// initScripts lists the executable scripts in /etc/init.d. When interpreter
+// is set, only scripts whose shebang names it are returned.
+func initScripts(interpreter string) []initScript {
+	entries, err := os.ReadDir(initDir)
+	if err != nil {
+		return nil
+	}
+	var scripts []initScript
+	for _, e := range entries {
+		name := e.Name()
+		switch name {
+		case "README", "skeleton", "functions", "rc", "rcS", "halt", "reboot", "single":
+			continue
+		}
+		if e.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".sh") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			continue
+		}
+		script, shebang, ok := readInitScript(filepath.Join(initDir, name))
+		if !ok || (interpreter != "" && !strings.Contains(shebang, interpreter)) {
+			continue
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts
+}
+
+// readInitScript picks up the literal assignments OpenRC (command=,
+// command_user=, pidfile=) and Debian-style scripts (DAEMON=, PIDFILE=) use.
+func readInitScript(path string) (initScript, string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return initScript{}, "", false
+	}
+	defer f.Close()
+
+	script := initScript{path: path}
+	name := filepath.Base(path)
+	vars := map[string]string{"RC_SVCNAME": name, "SVCNAME": name}
+	var shebang string
+
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			shebang = line
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.ContainsAny(key, " \t") {
+			continue
+		}
+		value, ok = expandVars(strings.Trim(value, `"'`), vars)
+		if !ok {
+			continue // depends on runtime variables or command output
+		}
+		vars[key] = value
+		switch key {
+		case "command", "DAEMON":
+			if script.binary == "" && strings.HasPrefix(value, "/") {
+				script.binary = value
+			}
+		case "command_user":
+			script.user = value
+		case "pidfile", "PIDFILE":
+			if script.pidfile == "" {
+				script.pidfile = value
+			}
+		}
+	}
+	return script, shebang, true
+}
+
+// expandVars substitutes variables assigned earlier in the script (e.g.
+// DAEMON=/usr/sbin/$NAME). It fails on anything it cannot resolve.
+func expandVars(value string, vars map[string]string) (string, bool) {
+	if strings.ContainsAny(value, "`(") {
+		return "", false
+	}
+	ok := true
+	expanded := os.Expand(value, func(v string) string {
+		if val, found := vars[v]; found {
+			return val
+		}
+		ok = false
+		return ""
+	})
+	return expanded, ok
+}
+
+// processUID returns the real UID of a live process.
+func processUID(pid int) (int, bool) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Uid:") {
+			if f := strings.Fields(line); len(f) > 1 {
+				uid, err := strconv.Atoi(f[1])
+				return uid, err == nil
+			}
+		}
+	}
+	return 0, false
+}
@@ -1,17 +1,60 @@
 package services
 
 import (
+	"os"
 	"os/exec"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+
+	"snapsec-agent/internal/modules/packages"
 )
 
 type ServicesModule struct{}
 
 type ServiceInfo struct {
-	Name    string `json:"name"`
-	Status  string `json:"status"`
-	Enabled bool   `json:"enabled"`
+	Name        string   `json:"name"`
+	Status      string   `json:"status"`
+	Enabled     bool     `json:"enabled"`
+	Init        string   `json:"init"`                 // systemd, openrc, sysv
+	Enablement  string   `json:"enablement,omitempty"` // systemd UnitFileState: enabled, disabled, static, masked, ...
+	Description string   `json:"description,omitempty"`
+	UnitFile    string   `json:"unit_file,omitempty"` // unit file or init script
+	MainPID     int      `json:"main_pid,omitempty"`
+	ExecStart   string   `json:"exec_start,omitempty"` // full command line
+	Binary      string   `json:"binary,omitempty"`
+	Package     string   `json:"package,omitempty"` // package owning Binary
+	User        string   `json:"user,omitempty"`
+	Group       string   `json:"group,omitempty"`
+	RunsAsRoot  bool     `json:"runs_as_root"`
+	Sandbox     *Sandbox `json:"sandbox,omitempty"` // systemd only
+	Unhardened  bool     `json:"unhardened"`        // runs as root with no confinement directives
+}
+
+// Sandbox holds the systemd hardening directives in effect for a service.
+type Sandbox struct {
+	DynamicUser            bool   `json:"dynamic_user"`
+	NoNewPrivileges        bool   `json:"no_new_privileges"`
+	ProtectSystem          string `json:"protect_system"` // no, yes, full, strict
+	ProtectHome            string `json:"protect_home"`   // no, yes, read-only, tmpfs
+	PrivateTmp             bool   `json:"private_tmp"`
+	PrivateDevices         bool   `json:"private_devices"`
+	PrivateNetwork         bool   `json:"private_network"`
+	ProtectKernelTunables  bool   `json:"protect_kernel_tunables"`
+	ProtectKernelModules   bool   `json:"protect_kernel_modules"`
+	ProtectControlGroups   bool   `json:"protect_control_groups"`
+	RestrictNamespaces     bool   `json:"restrict_namespaces"`
+	CapabilitiesRestricted bool   `json:"capabilities_restricted"` // CapabilityBoundingSet drops CAP_SYS_ADMIN
+	SystemCallFilter       bool   `json:"system_call_filter"`
+}
+
+// confines reports whether any directive limits what a compromised root
+// process can do to the host. PrivateTmp and the like are hygiene, not
+// confinement.
+func (s *Sandbox) confines() bool {
+	return s.NoNewPrivileges || (s.ProtectSystem != "" && s.ProtectSystem != "no") ||
+		s.CapabilitiesRestricted || s.SystemCallFilter
 }
 
 func (m *ServicesModule) Name() string {
@@ -23,23 +66,159 @@ func (m *ServicesModule) Gather() (interface{}, error) {
 
 	if runtime.GOOS == "linux" {
 		// Use systemctl if available
-		if _, err := exec.LookPath("systemctl"); err == nil {
-			out, _ := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-legend").Output()
-			lines := strings.Split(string(out), "\n")
-			for _, line := range lines {
-				parts := strings.Fields(line)
-				if len(parts) >= 4 {
-					name := strings.TrimSuffix(parts[0], ".service")
-					status := parts[3]
-					services = append(services, ServiceInfo{
-						Name:   name,
-						Status: status,
-						// Enabled check would require 'systemctl is-enabled'
-					})
-				}
+		if _, err := exec.LookPath("systemctl"); err == nil && isDir("/run/systemd/system") {
+			services = systemdServices()
+		} else if isDir("/run/openrc") || fileExists("/sbin/openrc-run") {
+			services = openrcServices()
+		} else {
+			services = sysvServices()
+		}
+
+		var binaries []string
+		for _, s := range services {
+			if s.Binary != "" {
+				binaries = append(binaries, s.Binary)
 			}
 		}
+		owners := packages.FileOwners(binaries)
+		for i := range services {
+			services[i].Package = owners[services[i].Binary]
+		}
 	}
 
 	return services, nil
 }
+
+// --- systemd ---
+
+var systemdProperties = []string{
+	"Id", "Description", "LoadState", "ActiveState", "SubState", "UnitFileState",
+	"FragmentPath", "MainPID", "ExecStart", "User", "Group", "DynamicUser",
+	"NoNewPrivileges", "ProtectSystem", "ProtectHome", "PrivateTmp", "PrivateDevices",
+	"PrivateNetwork", "ProtectKernelTunables", "ProtectKernelModules",
+	"ProtectControlGroups", "RestrictNamespaces", "CapabilityBoundingSet", "SystemCallFilter",
+}
+
+func systemdServices() []ServiceInfo {
+	// Loaded units (running or not) plus installed unit files that are not
+	// loaded, e.g. disabled services.
+	names := make(map[string]bool)
+	out, _ := exec.Command("systemctl", "list-units", "--type=service", "--all", "--no-legend", "--plain").Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		if f := strings.Fields(line); len(f) > 0 && strings.HasSuffix(f[0], ".service") {
+			names[f[0]] = true
+		}
+	}
+	out, _ = exec.Command("systemctl", "list-unit-files", "--type=service", "--no-legend").Output()
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		// Templates (foo@.service) only exist as instances.
+		if len(f) > 0 && strings.HasSuffix(f[0], ".service") && !strings.HasSuffix(f[0], "@.service") {
+			names[f[0]] = true
+		}
+	}
+
+	units := make([]string, 0, len(names))
+	for name := range names {
+		units = append(units, name)
+	}
+	sort.Strings(units)
+
+	var services []ServiceInfo
+	const batch = 200
+	for start := 0; start < len(units); start += batch {
+		end := start + batch
+		if end > len(units) {
+			end = len(units)
+		}
+		args := append([]string{"show", "-p", strings.Join(systemdProperties, ",")}, units[start:end]...)
+		out, err := exec.Command("systemctl", args...).Output()
+		if err != nil {
+			continue
+		}
+		for _, block := range strings.Split(string(out), "\n\n") {
+			if s, ok := parseSystemdUnit(block); ok {
+				services = append(services, s)
+			}
+		}
+	}
+	return services
+}
+
+func parseSystemdUnit(block string) (ServiceInfo, bool) {
+	props := make(map[string]string)
+	for _, line := range strings.Split(block, "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			props[k] = v
+		}
+	}
+	if props["Id"] == "" || props["LoadState"] == "not-found" {
+		return ServiceInfo{}, false
+	}
+
+	s := ServiceInfo{
+		Name:        strings.TrimSuffix(props["Id"], ".service"),
+		Status:      props["SubState"],
+		Init:        "systemd",
+		Enablement:  props["UnitFileState"],
+		Description: props["Description"],
+		UnitFile:    props["FragmentPath"],
+		User:        props["User"],
+		Group:       props["Group"],
+	}
+	switch s.Enablement {
+	case "enabled", "enabled-runtime", "alias":
+		s.Enabled = true
+	}
+	s.MainPID, _ = strconv.Atoi(props["MainPID"])
+	s.Binary, s.ExecStart = parseExecStart(props["ExecStart"])
+
+	yes := func(k string) bool { return props[k] == "yes" }
+	sb := &Sandbox{
+		DynamicUser:           yes("DynamicUser"),
+		NoNewPrivileges:       yes("NoNewPrivileges"),
+		ProtectSystem:         props["ProtectSystem"],
+		ProtectHome:           props["ProtectHome"],
+		PrivateTmp:            yes("PrivateTmp"),
+		PrivateDevices:        yes("PrivateDevices"),
+		PrivateNetwork:        yes("PrivateNetwork"),
+		ProtectKernelTunables: yes("ProtectKernelTunables"),
+		ProtectKernelModules:  yes("ProtectKernelModules"),
+		ProtectControlGroups:  yes("ProtectControlGroups"),
+		// "no" or an empty list means every namespace type is allowed.
+		RestrictNamespaces:     props["RestrictNamespaces"] != "" && props["RestrictNamespaces"] != "no",
+		CapabilitiesRestricted: !strings.Contains(props["CapabilityBoundingSet"], "cap_sys_admin"),
+		SystemCallFilter:       props["SystemCallFilter"] != "",
+	}
+	s.Sandbox = sb
+
+	s.RunsAsRoot = !sb.DynamicUser && (s.User == "" || s.User == "root" || s.User == "0")
+	s.Unhardened = s.RunsAsRoot && !sb.confines()
+	return s, true
+}
+
+// parseExecStart extracts the binary and command line from systemctl show's
+// ExecStart={ path=/usr/sbin/sshd ; argv[]=/usr/sbin/sshd -D $OPTS ; ... }.
+// Only the first command is used when there are several.
+func parseExecStart(v string) (binary, cmdline string) {
+	for _, part := range strings.Split(v, " ; ") {
+		part = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(part), "{"))
+		switch {
+		case strings.HasPrefix(part, "path=") && binary == "":
+			binary = strings.TrimPrefix(part, "path=")
+		case strings.HasPrefix(part, "argv[]=") && cmdline == "":
+			cmdline = strings.TrimPrefix(part, "argv[]=")
+		}
+	}
+	return binary, cmdline
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
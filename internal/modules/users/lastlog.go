@@ -0,0 +1,69 @@
+package users
+
+import (
+	"encoding/binary"
+	"os"
+	"time"
+
+	"snapsec-agent/internal/utmp"
+)
+
+// Record layout of glibc's struct lastlog on Linux; the timestamp is 32-bit
+// on every architecture for compatibility between 32- and 64-bit binaries.
+const lastlogSize = 292 // int32 time, char line[32], char host[256]
+
+// readLastlog reads the entries of uids from /var/log/lastlog, a sparse file
+// indexed by UID. Reading it to the end would walk the holes left below
+// large UIDs (nfsnobody, LDAP and container ranges), so each entry is read at
+// its offset instead.
+func readLastlog(path string, uids []int) map[int]LoginInfo {
+	logins := make(map[int]LoginInfo)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return logins
+	}
+	defer f.Close()
+
+	buf := make([]byte, lastlogSize)
+	for _, uid := range uids {
+		if uid < 0 {
+			continue
+		}
+		if _, err := f.ReadAt(buf, int64(uid)*lastlogSize); err != nil {
+			continue
+		}
+		sec := int32(binary.LittleEndian.Uint32(buf[0:4]))
+		if sec <= 0 {
+			continue
+		}
+		logins[uid] = LoginInfo{
+			Time:   time.Unix(int64(sec), 0).UTC(),
+			TTY:    utmp.CString(buf[4:36]),
+			Host:   utmp.CString(buf[36:292]),
+			Source: "lastlog",
+		}
+	}
+	return logins
+}
+
+// readWtmpLastLogins returns the most recent login of each user recorded in
+// wtmp. It is the fallback for distributions that dropped lastlog.
+func readWtmpLastLogins(path string) map[string]LoginInfo {
+	logins := make(map[string]LoginInfo)
+	utmp.Read(path, func(r utmp.Record) {
+		if r.Type != utmp.UserProcess || r.User == "" {
+			return
+		}
+		if prev, ok := logins[r.User]; ok && !r.Time.After(prev.Time) {
+			return
+		}
+		logins[r.User] = LoginInfo{
+			Time:   r.Time,
+			TTY:    r.Line,
+			Host:   r.Host,
+			Source: "wtmp",
+		}
+	})
+	return logins
+}
@@ -0,0 +1,237 @@
+package users
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type AuthorizedKeyInfo struct {
+	File        string   `json:"file"`
+	Line        int      `json:"line"`
+	Type        string   `json:"type"`
+	Bits        int      `json:"bits,omitempty"`
+	Fingerprint string   `json:"fingerprint"`   // SHA256:<base64>, as printed by ssh-keygen -l
+	MD5         string   `json:"md5,omitempty"` // legacy colon-separated form
+	Comment     string   `json:"comment,omitempty"`
+	Options     []string `json:"options,omitempty"` // e.g. from="...", command="...", no-pty
+}
+
+var defaultAuthorizedKeysFiles = []string{".ssh/authorized_keys", ".ssh/authorized_keys2"}
+
+// authorizedKeysFiles returns the AuthorizedKeysFile patterns configured in
+// sshd_config, or OpenSSH's defaults.
+func authorizedKeysFiles(sshdConfig string) []string {
+	f, err := os.Open(sshdConfig)
+	if err != nil {
+		return defaultAuthorizedKeysFiles
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// The first occurrence wins; Match blocks are not evaluated.
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "AuthorizedKeysFile") {
+			if strings.EqualFold(fields[1], "none") {
+				return nil
+			}
+			return fields[1:]
+		}
+		if len(fields) > 0 && strings.EqualFold(fields[0], "Match") {
+			break
+		}
+	}
+	return defaultAuthorizedKeysFiles
+}
+
+// readAuthorizedKeys expands the sshd patterns (%h, %u, %%) for a user and
+// parses each file that exists. Relative patterns are relative to home.
+func readAuthorizedKeys(patterns []string, user, home string) []AuthorizedKeyInfo {
+	var keys []AuthorizedKeyInfo
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		path := strings.NewReplacer("%h", home, "%u", user, "%%", "%").Replace(pattern)
+		if !filepath.IsAbs(path) {
+			if home == "" {
+				continue
+			}
+			path = filepath.Join(home, path)
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		keys = append(keys, parseAuthorizedKeys(path)...)
+	}
+	return keys
+}
+
+func parseAuthorizedKeys(path string) []AuthorizedKeyInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var keys []AuthorizedKeyInfo
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, ok := parseAuthorizedKeyLine(line)
+		if !ok {
+			continue
+		}
+		key.File = path
+		key.Line = lineNo
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// parseAuthorizedKeyLine parses "[options] type base64 [comment]". Options
+// are recognised by the key type that follows them.
+func parseAuthorizedKeyLine(line string) (AuthorizedKeyInfo, bool) {
+	var key AuthorizedKeyInfo
+
+	fields := splitOptionsAware(line)
+	for i := 0; i+1 < len(fields); i++ {
+		if !isKeyType(fields[i]) {
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil {
+			return key, false
+		}
+		if i > 0 {
+			key.Options = splitOptions(strings.Join(fields[:i], " "))
+		}
+		key.Type = fields[i]
+		key.Bits = keyBits(key.Type, blob)
+		sum := sha256.Sum256(blob)
+		key.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+		key.MD5 = md5Fingerprint(blob)
+		key.Comment = strings.Join(fields[i+2:], " ")
+		return key, true
+	}
+	return key, false
+}
+
+func isKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-sha2-") ||
+		strings.HasPrefix(s, "sk-ssh-") || strings.HasPrefix(s, "sk-ecdsa-")
+}
+
+// splitOptionsAware splits on whitespace outside double quotes, so an option
+// such as command="echo hi" stays one field.
+func splitOptionsAware(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && inQuote && i+1 < len(line):
+			cur.WriteByte(c)
+			i++
+			cur.WriteByte(line[i])
+		case c == '"':
+			inQuote = !inQuote
+			cur.WriteByte(c)
+		case (c == ' ' || c == '\t') && !inQuote:
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// splitOptions splits the comma-separated option list outside quotes.
+func splitOptions(s string) []string {
+	var opts []string
+	start, inQuote := 0, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				opts = append(opts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(opts, s[start:])
+}
+
+// keyBits reads the key size from the SSH wire-format public key blob.
+func keyBits(keyType string, blob []byte) int {
+	switch {
+	case keyType == "ssh-ed25519" || keyType == "sk-ssh-ed25519@openssh.com":
+		return 256
+	case keyType == "ssh-ed448":
+		return 456
+	case strings.Contains(keyType, "nistp256"):
+		return 256
+	case strings.Contains(keyType, "nistp384"):
+		return 384
+	case strings.Contains(keyType, "nistp521"):
+		return 521
+	case keyType == "ssh-rsa":
+		// string "ssh-rsa", mpint e, mpint n
+		fields := wireStrings(blob, 3)
+		if len(fields) == 3 {
+			return new(big.Int).SetBytes(fields[2]).BitLen()
+		}
+	case keyType == "ssh-dss":
+		// string "ssh-dss", mpint p, q, g, y
+		fields := wireStrings(blob, 2)
+		if len(fields) == 2 {
+			return new(big.Int).SetBytes(fields[1]).BitLen()
+		}
+	}
+	return 0
+}
+
+// wireStrings reads up to n length-prefixed strings.
+func wireStrings(b []byte, n int) [][]byte {
+	var out [][]byte
+	for len(out) < n && len(b) >= 4 {
+		l := binary.BigEndian.Uint32(b)
+		if uint64(l) > uint64(len(b)-4) {
+			break
+		}
+		out = append(out, b[4:4+l])
+		b = b[4+l:]
+	}
+	return out
+}
+
+func md5Fingerprint(blob []byte) string {
+	sum := md5.Sum(blob)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return "MD5:" + strings.Join(parts, ":")
+}
@@ -0,0 +1,303 @@
+package users
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SudoRule is one sudoers user specification that applies to a user, either
+// directly (by name or #uid), through a group (%group or %#gid) or through a
+// User_Alias.
+type SudoRule struct {
+	Source     string   `json:"source"`    // file the rule came from
+	Principal  string   `json:"principal"` // as written: alice, #1000, %wheel, %#10, ADMINS, ALL
+	Hosts      string   `json:"hosts"`
+	RunAs      string   `json:"run_as,omitempty"`
+	Commands   []string `json:"commands"`
+	NoPassword bool     `json:"nopasswd"`
+}
+
+type sudoSpec struct {
+	SudoRule
+	principals []string
+}
+
+type sudoersDB struct {
+	userAliases map[string][]string
+	specs       []sudoSpec
+}
+
+// readSudoers parses the main sudoers file and everything it includes
+// (@include/@includedir and their legacy # forms).
+func readSudoers(path string) *sudoersDB {
+	db := &sudoersDB{userAliases: make(map[string][]string)}
+	db.parseFile(path, 0)
+	return db
+}
+
+func (db *sudoersDB) parseFile(path string, depth int) {
+	// sudo itself stops at 128 levels; anything near that is a loop.
+	if depth > 8 {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var logical string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			logical += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		db.parseLine(path, strings.TrimSpace(logical+line), depth)
+		logical = ""
+	}
+}
+
+func (db *sudoersDB) parseLine(path, line string, depth int) {
+	switch {
+	case line == "":
+		return
+	case strings.HasPrefix(line, "#include") || strings.HasPrefix(line, "@include"):
+		db.parseInclude(path, line, depth)
+		return
+	}
+	line = stripComment(line)
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	switch fields[0] {
+	case "Defaults", "Host_Alias", "Runas_Alias", "Cmnd_Alias", "Cmd_Alias":
+		return
+	case "User_Alias":
+		// User_Alias NAME = a, b, %g : OTHER = c
+		for _, def := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "User_Alias")), ":") {
+			name, members, ok := strings.Cut(def, "=")
+			if ok {
+				db.userAliases[strings.TrimSpace(name)] = splitList(members)
+			}
+		}
+		return
+	}
+	if strings.HasPrefix(fields[0], "Defaults") {
+		return // Defaults:user, Defaults@host, ...
+	}
+
+	// users hosts = (runas) TAG: commands [: hosts = ...]
+	users, rest, ok := splitUserSpec(line)
+	if !ok {
+		return
+	}
+	for _, hostSpec := range strings.Split(rest, " : ") {
+		hosts, cmdSpec, ok := strings.Cut(hostSpec, "=")
+		if !ok {
+			continue
+		}
+		rule := SudoRule{
+			Source:    path,
+			Principal: strings.Join(users, ","),
+			Hosts:     strings.TrimSpace(hosts),
+		}
+		rule.RunAs, rule.Commands, rule.NoPassword = parseCommands(cmdSpec)
+		db.specs = append(db.specs, sudoSpec{SudoRule: rule, principals: users})
+	}
+}
+
+func (db *sudoersDB) parseInclude(path, line string, depth int) {
+	directive, target, _ := strings.Cut(line, " ")
+	target = strings.Trim(strings.TrimSpace(target), `"`)
+	if target == "" {
+		return
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+
+	if strings.HasSuffix(directive, "includedir") {
+		entries, err := os.ReadDir(target)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			// sudo skips names containing a dot or ending in ~ (editor and
+			// package manager leftovers).
+			name := e.Name()
+			if e.IsDir() || strings.Contains(name, ".") || strings.HasSuffix(name, "~") {
+				continue
+			}
+			db.parseFile(filepath.Join(target, name), depth+1)
+		}
+		return
+	}
+	db.parseFile(target, depth+1)
+}
+
+// splitUserSpec separates the user list from the host specifications. The
+// user list ends at the first whitespace not following a comma.
+func splitUserSpec(line string) ([]string, string, bool) {
+	i := 0
+	for i < len(line) {
+		if line[i] == ' ' || line[i] == '\t' {
+			j := i
+			for j < len(line) && (line[j] == ' ' || line[j] == '\t') {
+				j++
+			}
+			if j < len(line) && line[j] != ',' && (i == 0 || line[i-1] != ',') {
+				break
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	if i >= len(line) {
+		return nil, "", false
+	}
+	return splitList(line[:i]), strings.TrimSpace(line[i:]), true
+}
+
+// parseCommands reads "(runas) TAG: TAG: cmd, cmd". Tags carry over to later
+// commands in the same spec, so NOPASSWD is reported if any command has it.
+func parseCommands(spec string) (runAs string, commands []string, noPassword bool) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "(") {
+		if end := strings.Index(spec, ")"); end > 0 {
+			runAs = strings.TrimSpace(spec[1:end])
+			spec = strings.TrimSpace(spec[end+1:])
+		}
+	}
+	for _, cmd := range splitList(spec) {
+		for {
+			tag, rest, ok := strings.Cut(cmd, ":")
+			if !ok || !isSudoTag(tag) {
+				break
+			}
+			if tag == "NOPASSWD" {
+				noPassword = true
+			}
+			cmd = strings.TrimSpace(rest)
+		}
+		if cmd != "" {
+			commands = append(commands, cmd)
+		}
+	}
+	return runAs, commands, noPassword
+}
+
+func isSudoTag(tag string) bool {
+	switch strings.TrimSpace(tag) {
+	case "NOPASSWD", "PASSWD", "NOEXEC", "EXEC", "SETENV", "NOSETENV",
+		"LOG_INPUT", "NOLOG_INPUT", "LOG_OUTPUT", "NOLOG_OUTPUT",
+		"MAIL", "NOMAIL", "FOLLOW", "NOFOLLOW", "INTERCEPT", "NOINTERCEPT":
+		return true
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// sudoUser is who a user list is matched against.
+type sudoUser struct {
+	name   string
+	uid    int
+	groups []string
+	gids   []int
+}
+
+// rulesFor returns the rules whose user list matches the user by name, UID,
+// group, GID or alias, in sudoers evaluation order. Negated entries (!alice)
+// exclude the user from that rule.
+func (db *sudoersDB) rulesFor(user sudoUser) []SudoRule {
+	var rules []SudoRule
+	for _, spec := range db.specs {
+		if db.matches(spec.principals, user, 0) {
+			rules = append(rules, spec.SudoRule)
+		}
+	}
+	return rules
+}
+
+func (db *sudoersDB) matches(principals []string, user sudoUser, depth int) bool {
+	matched := false
+	for _, p := range principals {
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+
+		hit := false
+		switch {
+		case p == "ALL" || p == user.name:
+			hit = true
+		case strings.HasPrefix(p, "%#"):
+			gid, err := strconv.Atoi(p[2:])
+			hit = err == nil && containsID(user.gids, gid)
+		case strings.HasPrefix(p, "%"):
+			hit = contains(user.groups, strings.TrimPrefix(p, "%"))
+		case strings.HasPrefix(p, "#"):
+			uid, err := strconv.Atoi(p[1:])
+			hit = err == nil && uid == user.uid
+		default:
+			if members, ok := db.userAliases[p]; ok && depth < 8 {
+				hit = db.matches(members, user, depth+1)
+			}
+		}
+		if hit {
+			matched = !negate
+		}
+	}
+	return matched
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(values []int, want int) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// stripComment removes a trailing comment. "#" starts one only at the start
+// of the line or after whitespace, and not when followed by digits, which
+// sudoers reads as a UID (#1000, (#0) in a runas list).
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] != '#' {
+			continue
+		}
+		if i > 0 && line[i-1] != ' ' && line[i-1] != '\t' {
+			continue
+		}
+		if i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+			continue
+		}
+		return strings.TrimSpace(line[:i])
+	}
+	return line
+}
@@ -0,0 +1,58 @@
+package users
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSudoersRulesByID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sudoers")
+	sudoers := `# comment
+#1000 ALL=(ALL) NOPASSWD: ALL
+%#2000 ALL=(#0) /usr/bin/systemctl
+User_Alias OPS = #1001, %#2001
+OPS ALL=(ALL) ALL
+alice,!#1002 ALL=(ALL) /bin/true
+`
+	if err := os.WriteFile(path, []byte(sudoers), 0600); err != nil {
+		t.Fatal(err)
+	}
+	db := readSudoers(path)
+
+	tests := []struct {
+		name       string
+		user       sudoUser
+		principals []string
+		noPassword bool
+	}{
+		{"uid", sudoUser{name: "bob", uid: 1000, gids: []int{1000}}, []string{"#1000"}, true},
+		{"gid", sudoUser{name: "carol", uid: 1500, gids: []int{1500, 2000}}, []string{"%#2000"}, false},
+		{"alias uid", sudoUser{name: "dave", uid: 1001, gids: []int{1001}}, []string{"OPS"}, false},
+		{"alias gid", sudoUser{name: "erin", uid: 1600, gids: []int{2001}}, []string{"OPS"}, false},
+		{"negated uid", sudoUser{name: "alice", uid: 1002, gids: []int{1002}}, nil, false},
+		{"no match", sudoUser{name: "frank", uid: 1700, gids: []int{1700}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := db.rulesFor(tt.user)
+			var principals []string
+			noPassword := false
+			for _, r := range rules {
+				principals = append(principals, r.Principal)
+				noPassword = noPassword || r.NoPassword
+			}
+			if len(principals) != len(tt.principals) {
+				t.Fatalf("rules = %+v, want principals %v", rules, tt.principals)
+			}
+			for i := range principals {
+				if principals[i] != tt.principals[i] {
+					t.Errorf("principal %d = %q, want %q", i, principals[i], tt.principals[i])
+				}
+			}
+			if noPassword != tt.noPassword {
+				t.Errorf("nopasswd = %v, want %v", noPassword, tt.noPassword)
+			}
+		})
+	}
+}
@@ -3,17 +3,52 @@ package users
 import (
 	"bufio"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type UsersModule struct{}
 
 type UserInfo struct {
-	Username string   `json:"username"`
-	UID      int      `json:"uid"`
-	Groups   []string `json:"groups"`
-	Shell    string   `json:"shell"`
+	Username       string              `json:"username"`
+	UID            int                 `json:"uid"`
+	GID            int                 `json:"gid"`
+	PrimaryGroup   string              `json:"primary_group,omitempty"`
+	Groups         []string            `json:"groups"`
+	Gecos          string              `json:"gecos,omitempty"`
+	Home           string              `json:"home"`
+	Shell          string              `json:"shell"`
+	System         bool                `json:"system"`             // UID below 1000, except root
+	LoginShell     bool                `json:"login_shell"`        // shell is not nologin/false
+	Password       *PasswordInfo       `json:"password,omitempty"` // nil when /etc/shadow is unreadable
+	Sudo           []SudoRule          `json:"sudo,omitempty"`
+	SudoNoPassword bool                `json:"sudo_nopasswd"`
+	LastLogin      *LoginInfo          `json:"last_login,omitempty"`
+	AuthorizedKeys []AuthorizedKeyInfo `json:"authorized_keys,omitempty"`
+}
+
+// PasswordInfo is the aging and lock state from /etc/shadow. The hash itself
+// is never read into it.
+type PasswordInfo struct {
+	Status         string `json:"status"`                 // set, locked, empty, none
+	Algorithm      string `json:"algorithm,omitempty"`    // md5, sha256, sha512, yescrypt, scrypt, bcrypt, des
+	LastChanged    string `json:"last_changed,omitempty"` // YYYY-MM-DD
+	MustChange     bool   `json:"must_change"`            // last change field is 0
+	MinDays        *int   `json:"min_days,omitempty"`
+	MaxDays        *int   `json:"max_days,omitempty"`
+	WarnDays       *int   `json:"warn_days,omitempty"`
+	InactiveDays   *int   `json:"inactive_days,omitempty"`
+	AccountExpires string `json:"account_expires,omitempty"` // YYYY-MM-DD
+	Expired        bool   `json:"expired"`                   // password older than MaxDays
+}
+
+type LoginInfo struct {
+	Time   time.Time `json:"time"`
+	TTY    string    `json:"tty,omitempty"`
+	Host   string    `json:"host,omitempty"`
+	Source string    `json:"source"` // lastlog or wtmp
 }
 
 func (m *UsersModule) Name() string {
@@ -33,20 +68,241 @@ func (m *UsersModule) Gather() (interface{}, error) {
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
 		parts := strings.Split(line, ":")
 		if len(parts) >= 7 {
 			uid, _ := strconv.Atoi(parts[2])
-			// Only include real users (UID >= 1000) for brevity, or all
-			if uid >= 1000 || uid == 0 {
-				users = append(users, UserInfo{
-					Username: parts[0],
-					UID:      uid,
-					Groups:   []string{}, // Groups would require parsing /etc/group
-					Shell:    parts[6],
-				})
+			gid, _ := strconv.Atoi(parts[3])
+			users = append(users, UserInfo{
+				Username:   parts[0],
+				UID:        uid,
+				GID:        gid,
+				Groups:     []string{},
+				Gecos:      parts[4],
+				Home:       parts[5],
+				Shell:      parts[6],
+				System:     uid != 0 && uid < 1000,
+				LoginShell: isLoginShell(parts[6]),
+			})
+		}
+	}
+
+	groups := readGroups("/etc/group")
+	shadow := readShadow("/etc/shadow")
+	sudo := readSudoers("/etc/sudoers")
+	uids := make([]int, len(users))
+	for i, u := range users {
+		uids[i] = u.UID
+	}
+	lastlog := readLastlog("/var/log/lastlog", uids)
+	wtmp := readWtmpLastLogins("/var/log/wtmp")
+	keyFiles := authorizedKeysFiles("/etc/ssh/sshd_config")
+
+	for i := range users {
+		u := &users[i]
+
+		u.PrimaryGroup = groups.names[u.GID]
+		u.Groups = groups.membership(u.Username, u.PrimaryGroup)
+
+		if p, ok := shadow[u.Username]; ok {
+			u.Password = &p
+		}
+
+		u.Sudo = sudo.rulesFor(sudoUser{name: u.Username, uid: u.UID, groups: u.Groups, gids: groups.ids(u.GID, u.Groups)})
+		for _, r := range u.Sudo {
+			if r.NoPassword {
+				u.SudoNoPassword = true
 			}
 		}
+
+		// lastlog is authoritative when present; wtmp covers systems that no
+		// longer maintain it.
+		if l, ok := lastlog[u.UID]; ok {
+			u.LastLogin = &l
+		} else if l, ok := wtmp[u.Username]; ok {
+			u.LastLogin = &l
+		}
+
+		u.AuthorizedKeys = readAuthorizedKeys(keyFiles, u.Username, u.Home)
 	}
 
 	return users, nil
 }
+
+func isLoginShell(shell string) bool {
+	switch {
+	case shell == "", strings.HasSuffix(shell, "/nologin"), strings.HasSuffix(shell, "/false"),
+		shell == "/bin/sync", shell == "/sbin/shutdown", shell == "/sbin/halt":
+		return false
+	}
+	return true
+}
+
+// --- /etc/group ---
+
+type groupDB struct {
+	names   map[int]string      // gid -> name
+	gids    map[string]int      // name -> gid
+	members map[string][]string // user -> supplementary groups
+}
+
+func readGroups(path string) groupDB {
+	db := groupDB{names: make(map[int]string), gids: make(map[string]int), members: make(map[string][]string)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return db
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:password:gid:member,member
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) < 4 || strings.HasPrefix(parts[0], "#") {
+			continue
+		}
+		gid, err := strconv.Atoi(parts[2])
+		if err != nil {
+			continue
+		}
+		db.names[gid] = parts[0]
+		db.gids[parts[0]] = gid
+		for _, member := range strings.Split(parts[3], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				db.members[member] = append(db.members[member], parts[0])
+			}
+		}
+	}
+	return db
+}
+
+// membership returns the primary group followed by the sorted supplementary
+// groups.
+func (db groupDB) membership(user, primary string) []string {
+	groups := []string{}
+	if primary != "" {
+		groups = append(groups, primary)
+	}
+	var extra []string
+	for _, g := range db.members[user] {
+		if g != primary {
+			extra = append(extra, g)
+		}
+	}
+	sort.Strings(extra)
+	return append(groups, extra...)
+}
+
+// ids returns the primary GID followed by the GIDs of the named groups.
+func (db groupDB) ids(primary int, groups []string) []int {
+	ids := []int{primary}
+	for _, g := range groups {
+		if gid, ok := db.gids[g]; ok && gid != primary {
+			ids = append(ids, gid)
+		}
+	}
+	return ids
+}
+
+// --- /etc/shadow ---
+
+func readShadow(path string) map[string]PasswordInfo {
+	entries := make(map[string]PasswordInfo)
+
+	f, err := os.Open(path)
+	if err != nil {
+		// Only readable by root.
+		return entries
+	}
+	defer f.Close()
+
+	today := int(time.Now().Unix() / 86400)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name:hash:lastchg:min:max:warn:inactive:expire:reserved
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) < 8 {
+			continue
+		}
+		p := PasswordInfo{}
+		p.Status, p.Algorithm = passwordState(parts[1])
+		parts[1] = ""
+
+		if lastchg, ok := shadowDays(parts[2]); ok {
+			if *lastchg == 0 {
+				p.MustChange = true
+			} else {
+				p.LastChanged = epochDay(*lastchg)
+			}
+		}
+		p.MinDays, _ = shadowDays(parts[3])
+		p.MaxDays, _ = shadowDays(parts[4])
+		p.WarnDays, _ = shadowDays(parts[5])
+		p.InactiveDays, _ = shadowDays(parts[6])
+		if expire, ok := shadowDays(parts[7]); ok {
+			p.AccountExpires = epochDay(*expire)
+		}
+
+		if lastchg, ok := shadowDays(parts[2]); ok && p.MaxDays != nil && *p.MaxDays < 99999 && p.Status == "set" {
+			p.Expired = *lastchg+*p.MaxDays < today
+		}
+
+		entries[parts[0]] = p
+	}
+	return entries
+}
+
+// passwordState classifies a shadow password field without keeping it.
+func passwordState(hash string) (status, algorithm string) {
+	switch {
+	case hash == "":
+		return "empty", ""
+	case hash == "*" || hash == "!" || hash == "!!" || hash == "!*":
+		return "none", ""
+	case strings.HasPrefix(hash, "!"):
+		status = "locked"
+		hash = strings.TrimLeft(hash, "!")
+	default:
+		status = "set"
+	}
+
+	switch {
+	case strings.HasPrefix(hash, "$1$"):
+		algorithm = "md5"
+	case strings.HasPrefix(hash, "$5$"):
+		algorithm = "sha256"
+	case strings.HasPrefix(hash, "$6$"):
+		algorithm = "sha512"
+	case strings.HasPrefix(hash, "$y$"):
+		algorithm = "yescrypt"
+	case strings.HasPrefix(hash, "$7$"):
+		algorithm = "scrypt"
+	case strings.HasPrefix(hash, "$2"):
+		algorithm = "bcrypt"
+	case strings.HasPrefix(hash, "$"):
+		algorithm = "unknown"
+	case len(hash) == 13:
+		algorithm = "des"
+	}
+	return status, algorithm
+}
+
+// shadowDays parses a shadow day-count field; empty means "not set".
+func shadowDays(s string) (*int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, false
+	}
+	return &n, true
+}
+
+func epochDay(days int) string {
+	return time.Unix(int64(days)*86400, 0).UTC().Format("2006-01-02")
+}
@@ -0,0 +1,91 @@
+package utmp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// Record layout of glibc's struct utmp on Linux, shared by utmp, wtmp and
+// btmp. Timestamps are 32-bit on every architecture for compatibility
+// between 32- and 64-bit binaries.
+const (
+	RecordSize = 384
+
+	RunLevel     = 1
+	BootTime     = 2
+	LoginProcess = 6
+	UserProcess  = 7
+	DeadProcess  = 8
+)
+
+type Record struct {
+	Type int16
+	PID  int32
+	Line string
+	User string
+	Host string
+	Addr string
+	Time time.Time
+}
+
+// Read calls fn for each record of a utmp-format file, oldest first, without
+// holding the file in memory: wtmp and btmp grow to hundreds of megabytes on
+// busy or attacked hosts.
+func Read(path string, fn func(Record)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 64*RecordSize)
+	buf := make([]byte, RecordSize)
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		fn(parse(buf))
+	}
+}
+
+func parse(buf []byte) Record {
+	sec := int32(binary.LittleEndian.Uint32(buf[340:344]))
+	usec := int32(binary.LittleEndian.Uint32(buf[344:348]))
+	return Record{
+		Type: int16(binary.LittleEndian.Uint16(buf[0:2])),
+		PID:  int32(binary.LittleEndian.Uint32(buf[4:8])),
+		Line: CString(buf[8:40]),
+		User: CString(buf[44:76]),
+		Host: CString(buf[76:332]),
+		Addr: addr(buf[348:364]),
+		Time: time.Unix(int64(sec), int64(usec)*1000).UTC(),
+	}
+}
+
+// addr formats ut_addr_v6, which holds an IPv4 address in its first word
+// only.
+func addr(b []byte) string {
+	if bytes.Equal(b, make([]byte, 16)) {
+		return ""
+	}
+	if bytes.Equal(b[4:], make([]byte, 12)) {
+		return net.IP(b[:4]).String()
+	}
+	return net.IP(b).String()
+}
+
+// CString returns a NUL-terminated string from a fixed-size field.
+func CString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
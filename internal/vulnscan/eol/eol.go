@@ -0,0 +1,143 @@
+package eol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	inventory "snapsec-agent/internal/modules/eol"
+	"snapsec-agent/internal/vulnscan"
+)
+
+// EOLScanner is a native plugin: it reports the OS and major software the
+// eol module finds to be out of support, or about to be, as compliance
+// findings separate from vulnerabilities. Targets are ignored; the whole
+// host is checked. No external binary is needed.
+type EOLScanner struct {
+	config      vulnscan.PluginConfig
+	datasetPath string
+}
+
+func (e *EOLScanner) Init(config vulnscan.PluginConfig) error {
+	e.config = config
+	e.datasetPath = filepath.Join(config.CacheDir, inventory.DatasetFile)
+	return nil
+}
+
+func (e *EOLScanner) Capabilities() []vulnscan.ScanType {
+	return []vulnscan.ScanType{"compliance"}
+}
+
+func (e *EOLScanner) OptionSchema() []vulnscan.OptionSpec {
+	return nil
+}
+
+func (e *EOLScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vulnscan.ScanResult, error) {
+	result := vulnscan.ScanResult{JobID: job.ID}
+
+	data := inventory.Check(e.datasetPath, time.Now())
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// As with the java plugin, the module's report is the raw output.
+	rawOutput, err := json.Marshal(data)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode eol report: %w", err)
+	}
+	result.Findings, err = e.Normalize(rawOutput)
+	return result, err
+}
+
+// Normalize takes a JSON inventory.EOLData and reports one finding per
+// product that is past or near its end of life. Distribution kernels are
+// covered by the finding for their OS.
+func (e *EOLScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFinding, error) {
+	var findings []vulnscan.NormalizedFinding
+	if len(rawOutput) == 0 {
+		return findings, nil
+	}
+
+	var data inventory.EOLData
+	if err := json.Unmarshal(rawOutput, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse eol report: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	for _, s := range data.Software {
+		if s.SupportedBy != "" {
+			continue
+		}
+		switch s.Status {
+		case inventory.StatusEOL, inventory.StatusExtendedSupport, inventory.StatusApproaching:
+			findings = append(findings, newFinding(s, data.Dataset, hostname))
+		}
+	}
+	return findings, nil
+}
+
+func newFinding(s inventory.Software, dataset, hostname string) vulnscan.NormalizedFinding {
+	release := s.Name + " " + s.Cycle
+	asset := s.Path
+	if asset == "" {
+		asset = hostname
+	}
+	evidence := fmt.Sprintf("Found %s %s", s.Name, s.Version)
+	if s.Path != "" {
+		evidence += " at " + s.Path
+	}
+
+	var title, severity, description, remediation string
+	switch s.Status {
+	case inventory.StatusEOL:
+		title = release + " is end-of-life"
+		severity = "high"
+		description = fmt.Sprintf("%s reached its end of life on %s and no longer receives security updates.", release, s.EOLDate)
+		remediation = fmt.Sprintf("Upgrade to a supported %s release", s.Name)
+	case inventory.StatusExtendedSupport:
+		title = release + " is in extended support only"
+		severity = "medium"
+		description = fmt.Sprintf("%s reached its end of life on %s. Security updates continue until %s only through extended support (e.g. Debian LTS, Ubuntu Pro, RHEL ELS), which may need a subscription.", release, s.EOLDate, s.ExtendedEnd)
+		remediation = fmt.Sprintf("Upgrade to a supported %s release, or make sure the host is covered by extended support", s.Name)
+	default:
+		title = release + " reaches end-of-life on " + s.EOLDate
+		severity = "low"
+		description = fmt.Sprintf("%s stops receiving security updates on %s.", release, s.EOLDate)
+		remediation = fmt.Sprintf("Plan an upgrade to a supported %s release before %s", s.Name, s.EOLDate)
+	}
+
+	var references []string
+	if s.Link != "" {
+		references = []string{s.Link}
+	}
+
+	return vulnscan.NormalizedFinding{
+		FindingID:     fmt.Sprintf("eol-%s-%s-%s", s.Product, s.Cycle, asset),
+		Scanner:       "eol",
+		Category:      "eol",
+		Title:         title,
+		Severity:      severity,
+		Description:   description,
+		Evidence:      evidence,
+		Remediation:   remediation,
+		References:    references,
+		AffectedAsset: asset,
+		Metadata: map[string]interface{}{
+			"product":              s.Product,
+			"category":             s.Category,
+			"installed_version":    s.Version,
+			"cycle":                s.Cycle,
+			"status":               s.Status,
+			"eol_date":             s.EOLDate,
+			"extended_support_end": s.ExtendedEnd,
+			"dataset":              dataset,
+		},
+	}
+}
+
+func (e *EOLScanner) Cleanup() error {
+	return nil
+}
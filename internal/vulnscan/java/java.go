@@ -0,0 +1,126 @@
+package java
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"snapsec-agent/internal/modules/packages"
+	"snapsec-agent/internal/vulnscan"
+)
+
+// JavaScanner is a native plugin: it finds libraries in Java archives with
+// the packages module's inspector and matches them against a built-in table
+// of high-profile vulnerabilities. No external binary is needed.
+type JavaScanner struct {
+	config vulnscan.PluginConfig
+}
+
+func (j *JavaScanner) Init(config vulnscan.PluginConfig) error {
+	j.config = config
+	return nil
+}
+
+func (j *JavaScanner) Capabilities() []vulnscan.ScanType {
+	return []vulnscan.ScanType{"fs"}
+}
+
+func (j *JavaScanner) OptionSchema() []vulnscan.OptionSpec {
+	return []vulnscan.OptionSpec{
+		{Name: "excludes", Type: vulnscan.OptionList, Description: "Comma-separated directories to skip"},
+	}
+}
+
+func (j *JavaScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vulnscan.ScanResult, error) {
+	result := vulnscan.ScanResult{JobID: job.ID}
+
+	if len(job.Targets) == 0 {
+		return result, fmt.Errorf("no targets specified for java scan")
+	}
+
+	var excludes []string
+	if ex, ok := job.Options["excludes"]; ok && ex != "" {
+		for _, e := range strings.Split(ex, ",") {
+			excludes = append(excludes, strings.TrimSpace(e))
+		}
+	}
+
+	libs := packages.FindJavaLibraries(ctx, job.Targets, excludes)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// The library list is the plugin's raw output, so that Normalize can be
+	// fed the same data from elsewhere (e.g. a saved inventory).
+	rawOutput, err := json.Marshal(libs)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode java libraries: %w", err)
+	}
+	result.Findings, err = j.Normalize(rawOutput)
+	return result, err
+}
+
+// Normalize takes a JSON list of packages.JavaLibrary and reports one finding
+// per vulnerability and archive.
+func (j *JavaScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFinding, error) {
+	var findings []vulnscan.NormalizedFinding
+	if len(rawOutput) == 0 {
+		return findings, nil
+	}
+
+	var libs []packages.JavaLibrary
+	if err := json.Unmarshal(rawOutput, &libs); err != nil {
+		return nil, fmt.Errorf("failed to parse java library list: %w", err)
+	}
+
+	for _, lib := range libs {
+		for _, v := range matchVulns(lib) {
+			findings = append(findings, newFinding(lib, v))
+		}
+	}
+	return findings, nil
+}
+
+func newFinding(lib packages.JavaLibrary, v javaVuln) vulnscan.NormalizedFinding {
+	name := lib.ArtifactID
+	if lib.GroupID != "" {
+		name = lib.GroupID + ":" + lib.ArtifactID
+	}
+
+	version := lib.Version
+	evidence := fmt.Sprintf("Found %s %s in %s", name, version, lib.Path)
+	if version == "" {
+		version = "unknown"
+		evidence = fmt.Sprintf("Found %s in %s (version unknown, identified by %s)", name, lib.Path, strings.Join(lib.Classes, ", "))
+	}
+
+	return vulnscan.NormalizedFinding{
+		FindingID:     fmt.Sprintf("java-%s-%s", v.id, lib.Path),
+		Scanner:       "java",
+		Category:      "vulnerability",
+		Title:         v.title,
+		Severity:      v.severity,
+		Description:   v.description,
+		Evidence:      evidence,
+		Remediation:   fmt.Sprintf("Upgrade %s to %s or later", name, v.fixed),
+		References:    []string{"https://nvd.nist.gov/vuln/detail/" + v.id},
+		CVEs:          []string{v.id},
+		CWEs:          v.cwes,
+		CVSSScore:     v.cvssScore,
+		CVSSVector:    v.cvssVector,
+		AffectedAsset: lib.Path,
+		Metadata: map[string]interface{}{
+			"vulnerability_id":  v.id,
+			"pkg_name":          name,
+			"installed_version": version,
+			"fixed_version":     v.fixed,
+			"identified_by":     lib.Source,
+			"purl":              lib.PURL(),
+		},
+	}
+}
+
+func (j *JavaScanner) Cleanup() error {
+	return nil
+}
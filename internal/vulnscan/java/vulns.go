@@ -0,0 +1,228 @@
+package java
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"snapsec-agent/internal/modules/packages"
+)
+
+// javaVuln is one entry of the built-in vulnerability table. A library is
+// affected when its version falls in one of the ranges. When requires is
+// set, the vulnerable code lives in that class and archives that had it
+// removed (the usual Log4Shell mitigation) are not reported; libraries of
+// unknown version are reported only when the class is present.
+type javaVuln struct {
+	id              string
+	group, artifact string
+	ranges          []versionRange
+	requires        string
+	fixed           string
+	severity        string
+	cvssScore       float64
+	cvssVector      string
+	cwes            []string
+	title           string
+	description     string
+}
+
+// versionRange is [introduced, fixed); an empty bound is open.
+type versionRange struct {
+	introduced, fixed string
+}
+
+const (
+	jndiLookup   = "org/apache/logging/log4j/core/lookup/JndiLookup.class"
+	jmsAppender  = "org/apache/log4j/net/JMSAppender.class"
+	socketServer = "org/apache/log4j/net/SocketServer.class"
+	jdbcAppender = "org/apache/log4j/jdbc/JDBCAppender.class"
+	scriptLookup = "org/apache/commons/text/lookup/ScriptStringLookup.class"
+)
+
+var javaVulns = []javaVuln{
+	{
+		id: "CVE-2021-44228", group: "org.apache.logging.log4j", artifact: "log4j-core",
+		ranges:   []versionRange{{"2.0-beta9", "2.3.1"}, {"2.4", "2.12.2"}, {"2.13.0", "2.15.0"}},
+		requires: jndiLookup, fixed: "2.17.1",
+		severity: "critical", cvssScore: 10.0, cvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+		cwes:        []string{"CWE-502", "CWE-917"},
+		title:       "Log4Shell: remote code execution in Apache Log4j 2 via JNDI lookups",
+		description: "Log4j 2 evaluates ${jndi:...} lookups in logged data, letting an attacker who controls a logged string load and execute code from a remote LDAP or RMI server.",
+	},
+	{
+		id: "CVE-2021-45046", group: "org.apache.logging.log4j", artifact: "log4j-core",
+		ranges:   []versionRange{{"2.0-beta9", "2.3.1"}, {"2.4", "2.12.2"}, {"2.13.0", "2.16.0"}},
+		requires: jndiLookup, fixed: "2.17.1",
+		severity: "critical", cvssScore: 9.0, cvssVector: "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:C/C:H/I:H/A:H",
+		cwes:        []string{"CWE-917"},
+		title:       "Apache Log4j 2 JNDI lookups in Thread Context patterns",
+		description: "The fix for CVE-2021-44228 in Log4j 2.15.0 was incomplete: non-default pattern layouts using Thread Context lookups still allow JNDI injection and remote code execution.",
+	},
+	{
+		id: "CVE-2021-45105", group: "org.apache.logging.log4j", artifact: "log4j-core",
+		ranges:   []versionRange{{"2.0-alpha1", "2.3.1"}, {"2.4", "2.12.3"}, {"2.13.0", "2.17.0"}},
+		fixed:    "2.17.1",
+		severity: "high", cvssScore: 5.9, cvssVector: "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:N/I:N/A:H",
+		cwes:        []string{"CWE-20", "CWE-674"},
+		title:       "Apache Log4j 2 denial of service via recursive lookups",
+		description: "Log4j 2 does not protect against uncontrolled recursion from self-referential lookups, so attacker-controlled Thread Context data can crash the application.",
+	},
+	{
+		id: "CVE-2021-44832", group: "org.apache.logging.log4j", artifact: "log4j-core",
+		ranges:   []versionRange{{"2.0-beta7", "2.3.2"}, {"2.4", "2.12.4"}, {"2.13.0", "2.17.1"}},
+		fixed:    "2.17.1",
+		severity: "medium", cvssScore: 6.6, cvssVector: "CVSS:3.1/AV:N/AC:H/PR:H/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-20", "CWE-74"},
+		title:       "Apache Log4j 2 remote code execution via JDBCAppender configuration",
+		description: "An attacker able to modify the logging configuration can make the JDBC Appender load a JNDI data source that executes remote code.",
+	},
+	{
+		id: "CVE-2021-4104", group: "log4j", artifact: "log4j",
+		ranges:   []versionRange{{"1.0", "2.0"}},
+		requires: jmsAppender, fixed: "org.apache.logging.log4j:log4j-core 2.17.1",
+		severity: "high", cvssScore: 7.5, cvssVector: "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-502"},
+		title:       "Apache Log4j 1.2 JMSAppender deserialization",
+		description: "Log4j 1.x is end of life. When configured with JMSAppender it performs JNDI lookups that can lead to remote code execution, similar to Log4Shell.",
+	},
+	{
+		id: "CVE-2019-17571", group: "log4j", artifact: "log4j",
+		ranges:   []versionRange{{"1.2", "2.0"}},
+		requires: socketServer, fixed: "org.apache.logging.log4j:log4j-core 2.17.1",
+		severity: "critical", cvssScore: 9.8, cvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-502"},
+		title:       "Apache Log4j 1.2 SocketServer deserialization",
+		description: "The SocketServer class in Log4j 1.2 deserializes untrusted log events from the network, allowing remote code execution.",
+	},
+	{
+		id: "CVE-2022-23305", group: "log4j", artifact: "log4j",
+		ranges:   []versionRange{{"1.0", "2.0"}},
+		requires: jdbcAppender, fixed: "org.apache.logging.log4j:log4j-core 2.17.1",
+		severity: "critical", cvssScore: 9.8, cvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-89"},
+		title:       "Apache Log4j 1.2 JDBCAppender SQL injection",
+		description: "The JDBCAppender in Log4j 1.x builds SQL statements from logged values without escaping, allowing SQL injection.",
+	},
+	{
+		id: "CVE-2022-22965", group: "org.springframework", artifact: "spring-beans",
+		ranges:   []versionRange{{"", "5.2.20"}, {"5.3.0", "5.3.18"}},
+		fixed:    "5.3.18",
+		severity: "critical", cvssScore: 9.8, cvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-94"},
+		title:       "Spring4Shell: remote code execution in Spring Framework data binding",
+		description: "Spring MVC and WebFlux applications running on JDK 9+ can be made to write arbitrary files via class loader access in data binding, leading to remote code execution (notably when deployed as a WAR on Tomcat).",
+	},
+	{
+		id: "CVE-2022-42889", group: "org.apache.commons", artifact: "commons-text",
+		ranges:   []versionRange{{"1.5", "1.10.0"}},
+		requires: scriptLookup, fixed: "1.10.0",
+		severity: "critical", cvssScore: 9.8, cvssVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		cwes:        []string{"CWE-94"},
+		title:       "Text4Shell: remote code execution in Apache Commons Text interpolation",
+		description: "StringSubstitutor's default lookups include script, dns and url, so interpolating untrusted input can execute code or reach remote servers.",
+	},
+}
+
+// matchVulns returns the table entries that affect lib.
+func matchVulns(lib packages.JavaLibrary) []javaVuln {
+	var out []javaVuln
+	for _, v := range javaVulns {
+		if lib.ArtifactID != v.artifact || (lib.GroupID != "" && lib.GroupID != v.group) {
+			continue
+		}
+		// Every archive is listed class by class, so a missing marker
+		// means the class was stripped (or never shipped).
+		if v.requires != "" && !contains(lib.Classes, v.requires) {
+			continue
+		}
+		if lib.Version == "" && v.requires == "" || lib.Version != "" && !v.affects(lib.Version) {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func (v javaVuln) affects(version string) bool {
+	for _, r := range v.ranges {
+		if (r.introduced == "" || compareVersions(version, r.introduced) >= 0) &&
+			(r.fixed == "" || compareVersions(version, r.fixed) < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions orders Maven versions well enough for the table: numeric
+// components compare numerically and a qualifier after them ("-beta9",
+// "-rc1") sorts before the plain release, except the release markers
+// "Final", "GA" and "RELEASE".
+func compareVersions(a, b string) int {
+	na, qa := splitVersion(a)
+	nb, qb := splitVersion(b)
+	for i := 0; i < len(na) || i < len(nb); i++ {
+		var x, y int
+		if i < len(na) {
+			x = na[i]
+		}
+		if i < len(nb) {
+			y = nb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case qa == qb:
+		return 0
+	case qa == "":
+		return 1
+	case qb == "":
+		return -1
+	case qa < qb:
+		return -1
+	}
+	return 1
+}
+
+// splitVersion returns the leading dotted numbers and the remaining
+// qualifier, lower-cased.
+func splitVersion(v string) ([]int, string) {
+	var nums []int
+	rest := v
+	for rest != "" {
+		end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+		if end == 0 {
+			break
+		}
+		if end < 0 {
+			end = len(rest)
+		}
+		n, _ := strconv.Atoi(rest[:end])
+		nums = append(nums, n)
+		rest = rest[end:]
+		if !strings.HasPrefix(rest, ".") || len(rest) < 2 || !unicode.IsDigit(rune(rest[1])) {
+			break
+		}
+		rest = rest[1:]
+	}
+	q := strings.ToLower(strings.TrimLeft(rest, ".-_"))
+	switch q {
+	case "final", "ga", "release":
+		q = ""
+	}
+	return nums, q
+}
@@ -2,47 +2,276 @@ package vulnscan
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// schedulerTick is how often the run loop checks for due scan profiles.
+const schedulerTick = time.Minute
+
+// ScanProfile is a scheduled scan of one plugin. Empty Targets fall back to the
+// manager's include list and a zero Interval to the global scan interval.
+type ScanProfile struct {
+	Name     string
+	Tool     string
+	Targets  []string
+	Interval time.Duration
+	Options  map[string]string
+}
+
+// PluginStatus reports whether a configured plugin could be initialized, so
+// the backend can see hosts whose scanners are not running.
+type PluginStatus struct {
+	Name         string        `json:"name"`
+	Enabled      bool          `json:"enabled"`
+	Initialized  bool          `json:"initialized"`
+	Error        string        `json:"error,omitempty"`
+	Capabilities []ScanType    `json:"capabilities,omitempty"`
+	Schema       *PluginSchema `json:"schema,omitempty"`
+	UpdatedAt    string        `json:"updated_at"`
+}
+
 type ScanManager struct {
+	mu            sync.Mutex
 	plugins       map[string]ScannerPlugin
+	status        map[string]PluginStatus
+	options       map[string]map[string]string
+	profiles      []ScanProfile
+	lastRun       map[string]time.Time
 	config        PluginConfig
 	scanInterval  time.Duration
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
 	resultHandler func([]NormalizedFinding)
+	jobReporter   func(ScanResult)
 	includes      []string
 	excludes      []string
+
+	// Template syncs download bundles, so they run in the background, one at
+	// a time; a sync requested meanwhile replaces any still waiting.
+	syncMu      sync.Mutex
+	syncing     bool
+	syncPending *[]TemplateBundle
 }
 
 func NewScanManager(config PluginConfig, handler func([]NormalizedFinding)) *ScanManager {
 	return &ScanManager{
 		plugins:       make(map[string]ScannerPlugin),
+		status:        make(map[string]PluginStatus),
+		options:       make(map[string]map[string]string),
+		lastRun:       make(map[string]time.Time),
 		config:        config,
 		stopCh:        make(chan struct{}),
 		resultHandler: handler,
 	}
 }
 
+// RegisterPlugin initializes a plugin and makes it available to jobs. The
+// outcome is recorded in the plugin status either way.
 func (m *ScanManager) RegisterPlugin(name string, plugin ScannerPlugin) error {
-	if err := plugin.Init(m.config); err != nil {
+	err := plugin.Init(m.config)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := PluginStatus{
+		Name:      name,
+		Enabled:   true,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err != nil {
+		status.Error = err.Error()
+		m.status[name] = status
 		return err
 	}
+
+	schema := SchemaOf(name, plugin)
+	status.Initialized = true
+	status.Capabilities = schema.ScanTypes
+	status.Schema = &schema
+	m.status[name] = status
 	m.plugins[name] = plugin
 	return nil
 }
 
+// UnregisterPlugin cleans up and removes a plugin that has been disabled.
+func (m *ScanManager) UnregisterPlugin(name string) {
+	m.mu.Lock()
+	plugin, ok := m.plugins[name]
+	delete(m.plugins, name)
+	delete(m.options, name)
+	m.status[name] = PluginStatus{
+		Name:      name,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+	m.mu.Unlock()
+
+	if ok {
+		plugin.Cleanup()
+	}
+}
+
+// HasPlugin reports whether the named plugin is registered and initialized.
+func (m *ScanManager) HasPlugin(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.plugins[name]
+	return ok
+}
+
+// PluginStatuses returns the status of every plugin that was ever configured,
+// sorted by name.
+func (m *ScanManager) PluginStatuses() []PluginStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]PluginStatus, 0, len(m.status))
+	for _, s := range m.status {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Schemas returns the schema of every initialized plugin, sorted by name.
+func (m *ScanManager) Schemas() []PluginSchema {
+	var schemas []PluginSchema
+	for _, s := range m.PluginStatuses() {
+		if s.Schema != nil {
+			schemas = append(schemas, *s.Schema)
+		}
+	}
+	return schemas
+}
+
+// ValidateJob checks a job against the schema of the plugin it names.
+func (m *ScanManager) ValidateJob(job ScanJob) error {
+	m.mu.Lock()
+	plugin, ok := m.plugins[job.Tool]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("tool %s not registered", job.Tool)
+	}
+	return SchemaOf(job.Tool, plugin).Validate(job)
+}
+
+// SetJobReporter sets the callback told about jobs that could not run or
+// failed, with the reason in the result's Error.
+func (m *ScanManager) SetJobReporter(reporter func(ScanResult)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobReporter = reporter
+}
+
+// reportJob hands a failed job's result to the job reporter, if any.
+func (m *ScanManager) reportJob(jobID string, err error) {
+	m.mu.Lock()
+	reporter := m.jobReporter
+	m.mu.Unlock()
+	if reporter != nil {
+		reporter(ScanResult{JobID: jobID, Error: err.Error()})
+	}
+}
+
+// SetPluginOptions sets options applied to every job of the named plugin.
+// Job options take precedence over them.
+func (m *ScanManager) SetPluginOptions(name string, options map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.options[name] = options
+}
+
+// SetProfiles replaces the scheduled scan profiles. Profiles that already
+// existed keep their last run time so a config refresh does not trigger or
+// postpone scans.
+func (m *ScanManager) SetProfiles(profiles []ScanProfile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	lastRun := make(map[string]time.Time)
+	for _, p := range profiles {
+		key := profileKey(p)
+		if t, ok := m.lastRun[key]; ok {
+			lastRun[key] = t
+		} else {
+			lastRun[key] = now
+		}
+	}
+	m.profiles = profiles
+	m.lastRun = lastRun
+}
+
+// SyncTemplates hands each plugin that supports template bundles the bundles
+// addressed to it. It returns at once; the sync runs in the background.
+func (m *ScanManager) SyncTemplates(bundles []TemplateBundle) {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	m.syncPending = &bundles
+	if !m.syncing {
+		m.syncing = true
+		go m.syncLoop()
+	}
+}
+
+func (m *ScanManager) syncLoop() {
+	for {
+		m.syncMu.Lock()
+		pending := m.syncPending
+		m.syncPending = nil
+		if pending == nil {
+			m.syncing = false
+			m.syncMu.Unlock()
+			return
+		}
+		m.syncMu.Unlock()
+		m.syncTemplates(*pending)
+	}
+}
+
+func (m *ScanManager) syncTemplates(bundles []TemplateBundle) {
+	m.mu.Lock()
+	plugins := make(map[string]ScannerPlugin, len(m.plugins))
+	for name, plugin := range m.plugins {
+		plugins[name] = plugin
+	}
+	m.mu.Unlock()
+
+	for name, plugin := range plugins {
+		syncer, ok := plugin.(TemplateSyncer)
+		if !ok {
+			continue
+		}
+
+		var own []TemplateBundle
+		for _, b := range bundles {
+			if b.Tool == name {
+				own = append(own, b)
+			}
+		}
+
+		if err := syncer.SyncTemplates(own); err != nil {
+			log.Printf("Failed to sync %s templates: %v", name, err)
+		}
+	}
+}
+
 func (m *ScanManager) UpdateTargets(includes []string, excludes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.includes = includes
 	m.excludes = excludes
 }
 
 func (m *ScanManager) SetScanInterval(intervalSeconds int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if intervalSeconds > 0 {
 		m.scanInterval = time.Duration(intervalSeconds) * time.Second
 	} else {
@@ -58,6 +287,9 @@ func (m *ScanManager) Start() {
 func (m *ScanManager) Stop() {
 	close(m.stopCh)
 	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, plugin := range m.plugins {
 		plugin.Cleanup()
 	}
@@ -65,75 +297,121 @@ func (m *ScanManager) Stop() {
 
 func (m *ScanManager) runLoop() {
 	defer m.wg.Done()
-	
-	if m.scanInterval <= 0 {
-		return
-	}
-	
-	ticker := time.NewTicker(m.scanInterval)
+
+	ticker := time.NewTicker(schedulerTick)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			m.RunScheduledScan()
+			m.runDueProfiles()
 		case <-m.stopCh:
 			return
 		}
 	}
 }
 
-func (m *ScanManager) RunScheduledScan() {
-	log.Println("Starting scheduled vulnerability scan...")
-	
-	targets := m.includes
+// runDueProfiles starts every profile whose interval has elapsed since its
+// last run. Profiles without an interval (and no global default) never run.
+func (m *ScanManager) runDueProfiles() {
+	now := time.Now()
+
+	m.mu.Lock()
+	var due []ScanProfile
+	for _, p := range m.profiles {
+		interval := p.Interval
+		if interval <= 0 {
+			interval = m.scanInterval
+		}
+		if interval <= 0 {
+			continue
+		}
+		key := profileKey(p)
+		if now.Sub(m.lastRun[key]) >= interval {
+			m.lastRun[key] = now
+			due = append(due, p)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range due {
+		m.runProfile(p)
+	}
+}
+
+func (m *ScanManager) runProfile(p ScanProfile) {
+	m.mu.Lock()
+	plugin, ok := m.plugins[p.Tool]
+	targets := p.Targets
+	if len(targets) == 0 {
+		targets = m.includes
+	}
 	excludes := m.excludes
-	
+	m.mu.Unlock()
+
+	if !ok {
+		log.Printf("Skipping scan profile %s: Tool %s not registered", p.Name, p.Tool)
+		return
+	}
+
 	if len(targets) == 0 {
-		if runtime.GOOS == "windows" {
-			targets = []string{"C:\\"}
-		} else {
-			targets = []string{"/"}
-		}
+		targets = defaultTargets()
 	}
 
-	for name, plugin := range m.plugins {
-		job := ScanJob{
-			ID:      "scheduled-" + name + "-" + time.Now().Format("20060102150405"),
-			Tool:    name,
-			Targets: targets,
-			Options: map[string]string{
-				"protocol": "file",
-				"tags":     "secrets,keys,tokens,credentials,misconfiguration",
-			},
-		}
-		
-		if len(excludes) > 0 {
-			job.Options["excludes"] = strings.Join(excludes, ",")
-		}
-		
-		go m.RunJob(plugin, job)
+	options := make(map[string]string)
+	for k, v := range p.Options {
+		options[k] = v
+	}
+	if len(excludes) > 0 && SchemaOf(p.Tool, plugin).HasOption("excludes") {
+		options["excludes"] = strings.Join(excludes, ",")
+	}
+
+	job := ScanJob{
+		ID:      "scheduled-" + p.Tool + "-" + p.Name + "-" + time.Now().Format("20060102150405"),
+		Tool:    p.Tool,
+		Targets: targets,
+		Options: options,
 	}
+
+	log.Printf("Starting scheduled scan profile %s for %s", p.Name, p.Tool)
+	go m.RunJob(plugin, job)
 }
 
 func (m *ScanManager) RunJobs(jobs []ScanJob) {
 	for _, job := range jobs {
+		m.mu.Lock()
 		plugin, ok := m.plugins[job.Tool]
+		m.mu.Unlock()
 		if !ok {
 			log.Printf("Cannot run job %s: Tool %s not registered", job.ID, job.Tool)
+			m.reportJob(job.ID, fmt.Errorf("tool %s not registered", job.Tool))
 			continue
 		}
 		go m.RunJob(plugin, job)
 	}
 }
 
+// RunJob validates the job against the plugin's schema and executes it.
+// Invalid jobs are rejected before the scanner is started; rejected and
+// failed jobs are reported to the job reporter.
 func (m *ScanManager) RunJob(plugin ScannerPlugin, job ScanJob) {
 	ctx := context.Background()
 
+	job.Options = m.withPluginOptions(job.Tool, job.Options)
+
+	schema := SchemaOf(job.Tool, plugin)
+	if err := schema.Validate(job); err != nil {
+		log.Printf("Rejected scan job %s: %v", job.ID, err)
+		m.reportJob(job.ID, err)
+		return
+	}
+	job.Options = schema.Normalize(job.Options)
+
 	log.Printf("Executing scan job %s with tool %s", job.ID, job.Tool)
 	result, err := plugin.Execute(ctx, job)
 	if err != nil {
 		log.Printf("Scan job %s failed: %v", job.ID, err)
+		m.reportJob(job.ID, err)
 		return
 	}
 
@@ -141,46 +419,87 @@ func (m *ScanManager) RunJob(plugin ScannerPlugin, job ScanJob) {
 	if m.resultHandler != nil {
 		m.resultHandler(result.Findings)
 	}
+	// A partial failure still delivers the findings but is reported too.
+	if result.Error != "" {
+		log.Printf("Scan job %s partially failed: %s", job.ID, result.Error)
+		m.reportJob(job.ID, errors.New(result.Error))
+	}
+}
+
+// withPluginOptions layers the job's options over the plugin-wide options.
+func (m *ScanManager) withPluginOptions(tool string, jobOptions map[string]string) map[string]string {
+	m.mu.Lock()
+	defaults := m.options[tool]
+	m.mu.Unlock()
+
+	if len(defaults) == 0 {
+		return jobOptions
+	}
+
+	merged := make(map[string]string, len(defaults)+len(jobOptions))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range jobOptions {
+		merged[k] = v
+	}
+	return merged
 }
 
 func (m *ScanManager) RunCLI(tool string, target string, resume string) {
+	m.mu.Lock()
 	var targets []string
 	var excludes []string
 	if target == "" {
 		targets = m.includes
 		excludes = m.excludes
-		if len(targets) == 0 {
-			if runtime.GOOS == "windows" {
-				targets = []string{"C:\\"}
-			} else {
-				targets = []string{"/"}
-			}
-		}
 	} else {
 		targets = []string{target}
 	}
+	if len(targets) == 0 {
+		targets = defaultTargets()
+	}
 
+	plugins := make(map[string]ScannerPlugin, len(m.plugins))
 	for name, plugin := range m.plugins {
+		plugins[name] = plugin
+	}
+	profiles := append([]ScanProfile(nil), m.profiles...)
+	m.mu.Unlock()
+
+	for name, plugin := range plugins {
 		if tool != "" && name != tool {
 			continue
 		}
 
+		// The CLI runs the plugin's first configured profile against the
+		// requested targets.
+		options := make(map[string]string)
+		for _, p := range profiles {
+			if p.Tool == name {
+				for k, v := range p.Options {
+					options[k] = v
+				}
+				break
+			}
+		}
+		schema := SchemaOf(name, plugin)
+		if schema.HasOption("verbose") {
+			options["verbose"] = "true"
+		}
+
 		job := ScanJob{
 			ID:      "cli-" + name + "-" + time.Now().Format("20060102150405"),
 			Tool:    name,
 			Targets: targets,
-			Options: map[string]string{
-				"protocol": "file",
-				"tags":     "secrets,keys,tokens,credentials,misconfiguration",
-				"verbose":  "true",
-			},
+			Options: options,
 		}
 
-		if resume != "" {
+		if resume != "" && schema.HasOption("resume") {
 			job.Options["resume"] = resume
 		}
 
-		if len(excludes) > 0 {
+		if len(excludes) > 0 && schema.HasOption("excludes") {
 			job.Options["excludes"] = strings.Join(excludes, ",")
 		}
 
@@ -192,3 +511,14 @@ func (m *ScanManager) RunCLI(tool string, target string, resume string) {
 		}()
 	}
 }
+
+func defaultTargets() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"C:\\"}
+	}
+	return []string{"/"}
+}
+
+func profileKey(p ScanProfile) string {
+	return p.Tool + "/" + p.Name
+}
@@ -12,7 +12,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"snapsec-agent/internal/vulnscan"
 )
@@ -20,6 +22,9 @@ import (
 type NucleiScanner struct {
 	config vulnscan.PluginConfig
 	binPath string
+
+	mu      sync.Mutex
+	bundles map[string]vulnscan.TemplateBundle // keyed by name@version
 }
 
 func (n *NucleiScanner) Init(config vulnscan.PluginConfig) error {
@@ -58,6 +63,8 @@ func (n *NucleiScanner) Init(config vulnscan.PluginConfig) error {
 		}
 	}
 
+	n.loadBundles()
+
 	return nil
 }
 
@@ -140,6 +147,18 @@ func (n *NucleiScanner) Capabilities() []vulnscan.ScanType {
 	return []vulnscan.ScanType{"cve", "misconfiguration", "network", "local-file"}
 }
 
+func (n *NucleiScanner) OptionSchema() []vulnscan.OptionSpec {
+	return []vulnscan.OptionSpec{
+		{Name: "protocol", Type: vulnscan.OptionEnum, Description: "Template protocol type to run (nuclei -pt)",
+			Values: []string{"dns", "file", "http", "headless", "tcp", "workflow", "ssl", "websocket", "whois", "code", "javascript"}},
+		{Name: "tags", Type: vulnscan.OptionList, Description: "Comma-separated template tags to run (nuclei -tags)"},
+		{Name: "resume", Type: vulnscan.OptionString, Description: "Path to a nuclei resume.cfg to continue a crashed scan"},
+		{Name: "verbose", Type: vulnscan.OptionBool, Description: "Stream nuclei output to the agent's stdout", Default: "false"},
+		{Name: "template_bundle", Type: vulnscan.OptionString, Description: "Name of a backend-managed template bundle to run instead of the default templates"},
+		{Name: "template_version", Type: vulnscan.OptionString, Description: "Version of template_bundle; may be omitted when one version is installed"},
+	}
+}
+
 func (n *NucleiScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vulnscan.ScanResult, error) {
 	result := vulnscan.ScanResult{JobID: job.ID}
 	
@@ -166,17 +185,33 @@ func (n *NucleiScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vuln
 	defer os.Remove(outputFile.Name())
 	outputFile.Close()
 
-	templatesPath := filepath.Join(n.config.TemplateDir, "nuclei")
+	bundle, useBundle, err := n.resolveBundle(job.Options["template_bundle"], job.Options["template_version"])
+	if err != nil {
+		return result, err
+	}
 
 	args := []string{
 		"-l", targetFile.Name(),
 		"-json-export", outputFile.Name(),
-		"-ud", templatesPath,
 		"-c", "8",
 		"-bs", "8",
 		"-rl", "100",
 	}
 
+	// Nuclei's signature check is enforced for every template we did not
+	// build ourselves, including the public templates fetched at Init.
+	if useBundle {
+		// Pinned bundles must run exactly as distributed: no template update
+		// check.
+		log.Printf("Using nuclei template bundle %s", bundleKey(bundle.Name, bundle.Version))
+		args = append(args, "-t", n.bundlePath(bundle.Name, bundle.Version), "-duc")
+		if !bundle.FirstParty {
+			args = append(args, "-dut")
+		}
+	} else {
+		args = append(args, "-ud", filepath.Join(n.config.TemplateDir, "nuclei"), "-dut")
+	}
+
 	if pt, ok := job.Options["protocol"]; ok && pt != "" {
 		args = append(args, "-pt", pt)
 	}
@@ -220,6 +255,76 @@ func (n *NucleiScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vuln
 	return result, nil
 }
 
+// maxEvidenceBytes caps the request/response excerpts forwarded from a nuclei
+// event. File-protocol responses are whole file contents and can be huge.
+const maxEvidenceBytes = 4096
+
+// stringList decodes nuclei fields that may be either a comma-separated string
+// or a JSON array (tags, references, cve-id and cwe-id all come in both forms
+// depending on how the template author wrote them).
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = compactList(list)
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		// null or an unexpected shape; the field is optional.
+		*l = nil
+		return nil
+	}
+	*l = compactList(strings.Split(single, ","))
+	return nil
+}
+
+func compactList(in []string) []string {
+	var out []string
+	for _, v := range in {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+type nucleiEvent struct {
+	TemplateID   string `json:"template-id"`
+	TemplatePath string `json:"template-path"`
+	Info         struct {
+		Name           string     `json:"name"`
+		Author         stringList `json:"author"`
+		Tags           stringList `json:"tags"`
+		Description    string     `json:"description"`
+		Reference      stringList `json:"reference"`
+		Severity       string     `json:"severity"`
+		Remediation    string     `json:"remediation"`
+		Classification struct {
+			CVEID          stringList `json:"cve-id"`
+			CWEID          stringList `json:"cwe-id"`
+			CVSSMetrics    string     `json:"cvss-metrics"`
+			CVSSScore      float64    `json:"cvss-score"`
+			EPSSScore      float64    `json:"epss-score"`
+			EPSSPercentile float64    `json:"epss-percentile"`
+			CPE            string     `json:"cpe"`
+		} `json:"classification"`
+	} `json:"info"`
+	Type             string   `json:"type"`
+	Host             string   `json:"host"`
+	Path             string   `json:"path"`
+	MatchedAt        string   `json:"matched-at"`
+	MatcherName      string   `json:"matcher-name"`
+	ExtractorName    string   `json:"extractor-name"`
+	ExtractedResults []string `json:"extracted-results"`
+	MatchedLine      []int    `json:"matched-line"`
+	IP               string   `json:"ip"`
+	Timestamp        string   `json:"timestamp"`
+	Request          string   `json:"request"`
+	Response         string   `json:"response"`
+}
+
 func (n *NucleiScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFinding, error) {
 	var findings []vulnscan.NormalizedFinding
 	if len(rawOutput) == 0 {
@@ -228,56 +333,150 @@ func (n *NucleiScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFindin
 
 	// Nuclei -json-export outputs an array of JSON objects if we use modern Nuclei, 
 	// or JSONL depending on the flag. -json-export usually creates an array.
-	var rawFindings []map[string]interface{}
-	if err := json.Unmarshal(rawOutput, &rawFindings); err != nil {
+	var events []nucleiEvent
+	if err := json.Unmarshal(rawOutput, &events); err != nil {
 		// If it's JSONL, we need to split by newline
 		lines := strings.Split(string(rawOutput), "\n")
 		for _, line := range lines {
 			if strings.TrimSpace(line) == "" {
 				continue
 			}
-			var singleFinding map[string]interface{}
-			if err := json.Unmarshal([]byte(line), &singleFinding); err != nil {
+			var event nucleiEvent
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
 				return nil, fmt.Errorf("failed to parse nuclei JSON line: %w", err)
 			}
-			rawFindings = append(rawFindings, singleFinding)
+			events = append(events, event)
 		}
 	}
 
-	for _, raw := range rawFindings {
-		info, _ := raw["info"].(map[string]interface{})
-		severity, _ := info["severity"].(string)
-		if severity == "" {
+	for _, ev := range events {
+		severity := strings.ToLower(ev.Info.Severity)
+		if severity == "" || severity == "unknown" {
 			severity = "info"
 		}
 		
-		name, _ := info["name"].(string)
-		desc, _ := info["description"].(string)
-		remediation, _ := info["remediation"].(string)
-		matchedAt, _ := raw["matched-at"].(string)
-		templateID, _ := raw["template-id"].(string)
+		title := ev.Info.Name
+		if title == "" {
+			title = ev.TemplateID
+		}
+
+		evidence := fmt.Sprintf("Matched at %s", ev.MatchedAt)
+		if len(ev.MatchedLine) > 0 {
+			evidence = fmt.Sprintf("Matched at %s (line %s)", ev.MatchedAt, joinInts(ev.MatchedLine))
+		}
+		if len(ev.ExtractedResults) > 0 {
+			evidence += fmt.Sprintf("; extracted: %s", strings.Join(ev.ExtractedResults, ", "))
+		}
+
+		category := "vulnerability"
+		for _, tag := range ev.Info.Tags {
+			switch strings.ToLower(tag) {
+			case "misconfig", "misconfiguration":
+				category = "misconfiguration"
+			case "exposure", "secrets", "token", "tokens", "keys":
+				if category == "vulnerability" {
+					category = "exposure"
+				}
+			}
+		}
 		
 		finding := vulnscan.NormalizedFinding{
-			FindingID:     fmt.Sprintf("nuclei-%v-%v", templateID, matchedAt),
+			FindingID:     fmt.Sprintf("nuclei-%v-%v", ev.TemplateID, ev.MatchedAt),
 			Scanner:       "nuclei",
-			Category:      "vulnerability",
-			Title:         name,
+			Category:      category,
+			Title:         title,
 			Severity:      severity,
-			Description:   desc,
-			Evidence:      fmt.Sprintf("Matched at %s", matchedAt),
-			Remediation:   remediation,
-			AffectedAsset: matchedAt,
+			Description:   ev.Info.Description,
+			Evidence:      evidence,
+			Remediation:   ev.Info.Remediation,
+			References:    []string(ev.Info.Reference),
+			CVEs:          upperAll(ev.Info.Classification.CVEID),
+			CWEs:          upperAll(ev.Info.Classification.CWEID),
+			CVSSScore:     ev.Info.Classification.CVSSScore,
+			CVSSVector:    ev.Info.Classification.CVSSMetrics,
+			AffectedAsset: ev.MatchedAt,
 			Metadata: map[string]interface{}{
-				"nuclei_template_id": templateID,
-				"raw": raw,
+				"nuclei_template_id": ev.TemplateID,
+				"template_path":      ev.TemplatePath,
+				"protocol":           ev.Type,
+				"host":               ev.Host,
 			},
 		}
+
+		if len(ev.Info.Tags) > 0 {
+			finding.Metadata["tags"] = []string(ev.Info.Tags)
+		}
+		if len(ev.Info.Author) > 0 {
+			finding.Metadata["authors"] = []string(ev.Info.Author)
+		}
+		if ev.MatcherName != "" {
+			finding.Metadata["matcher_name"] = ev.MatcherName
+			// Templates with several named matchers emit one event per matcher.
+			finding.FindingID += "-" + ev.MatcherName
+		}
+		if ev.ExtractorName != "" {
+			finding.Metadata["extractor_name"] = ev.ExtractorName
+		}
+		if len(ev.ExtractedResults) > 0 {
+			finding.Metadata["extracted_results"] = ev.ExtractedResults
+		}
+		if len(ev.MatchedLine) > 0 {
+			finding.Metadata["matched_lines"] = ev.MatchedLine
+		}
+		if ev.Path != "" {
+			finding.Metadata["file_path"] = ev.Path
+		}
+		if ev.IP != "" {
+			finding.Metadata["ip"] = ev.IP
+		}
+		if ev.Timestamp != "" {
+			finding.Metadata["timestamp"] = ev.Timestamp
+		}
+		if c := ev.Info.Classification; c.EPSSScore > 0 || c.EPSSPercentile > 0 {
+			finding.Metadata["epss_score"] = c.EPSSScore
+			finding.Metadata["epss_percentile"] = c.EPSSPercentile
+		}
+		if ev.Info.Classification.CPE != "" {
+			finding.Metadata["cpe"] = ev.Info.Classification.CPE
+		}
+		if ev.Request != "" {
+			finding.Metadata["request"] = truncate(ev.Request, maxEvidenceBytes)
+		}
+		if ev.Response != "" {
+			finding.Metadata["response"] = truncate(ev.Response, maxEvidenceBytes)
+		}
+
 		findings = append(findings, finding)
 	}
 
 	return findings, nil
 }
 
+// upperAll normalizes identifiers such as "cve-2021-44228" or "cwe-79" to
+// their canonical upper-case form.
+func upperAll(ids []string) []string {
+	var out []string
+	for _, id := range ids {
+		out = append(out, strings.ToUpper(id))
+	}
+	return out
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "...[truncated]"
+}
+
 func (n *NucleiScanner) Cleanup() error {
 	return nil
 }
@@ -0,0 +1,389 @@
+package nuclei
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"snapsec-agent/internal/vulnscan"
+)
+
+// Backend-managed template bundles are stored as
+//
+//	<TemplateDir>/bundles/<name>/<version>/       extracted templates
+//	<TemplateDir>/bundles/<name>/<version>.json   bundle metadata
+//
+// The metadata file is written only after the archive has been verified and
+// extracted, so its presence marks a version as usable.
+
+// bundleSegment restricts bundle names and versions to safe path segments.
+var bundleSegment = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+type storedBundle struct {
+	vulnscan.TemplateBundle
+	SyncedAt string `json:"synced_at"`
+}
+
+func (n *NucleiScanner) bundlesRoot() string {
+	return filepath.Join(n.config.TemplateDir, "bundles")
+}
+
+func (n *NucleiScanner) bundlePath(name, version string) string {
+	return filepath.Join(n.bundlesRoot(), name, version)
+}
+
+// loadBundles restores the set of stored bundles from disk so that pinned
+// templates survive agent restarts without another download.
+func (n *NucleiScanner) loadBundles() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.bundles = make(map[string]vulnscan.TemplateBundle)
+	markers, _ := filepath.Glob(filepath.Join(n.bundlesRoot(), "*", "*.json"))
+	for _, marker := range markers {
+		data, err := os.ReadFile(marker)
+		if err != nil {
+			continue
+		}
+		var stored storedBundle
+		if err := json.Unmarshal(data, &stored); err != nil {
+			continue
+		}
+		if _, err := os.Stat(n.bundlePath(stored.Name, stored.Version)); err != nil {
+			continue
+		}
+		n.bundles[bundleKey(stored.Name, stored.Version)] = stored.TemplateBundle
+	}
+}
+
+// SyncTemplates implements vulnscan.TemplateSyncer.
+func (n *NucleiScanner) SyncTemplates(bundles []vulnscan.TemplateBundle) error {
+	wanted := make(map[string]vulnscan.TemplateBundle)
+	var errs []string
+
+	for _, b := range bundles {
+		if !bundleSegment.MatchString(b.Name) || !bundleSegment.MatchString(b.Version) {
+			errs = append(errs, fmt.Sprintf("invalid bundle name or version %q@%q", b.Name, b.Version))
+			continue
+		}
+		key := bundleKey(b.Name, b.Version)
+
+		n.mu.Lock()
+		existing, have := n.bundles[key]
+		n.mu.Unlock()
+
+		if !have || !strings.EqualFold(existing.SHA256, b.SHA256) {
+			if err := n.installBundle(b); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+				// The installed copy is still verified; keep it until a
+				// reinstall succeeds.
+				if have {
+					wanted[key] = existing
+				}
+				continue
+			}
+			log.Printf("Installed nuclei template bundle %s", key)
+		}
+		wanted[key] = b
+	}
+
+	n.mu.Lock()
+	stale := make(map[string]vulnscan.TemplateBundle)
+	for key, b := range n.bundles {
+		if _, ok := wanted[key]; !ok {
+			stale[key] = b
+		}
+	}
+	n.bundles = wanted
+	n.mu.Unlock()
+
+	for key, b := range stale {
+		log.Printf("Removing nuclei template bundle %s", key)
+		os.Remove(n.bundlePath(b.Name, b.Version) + ".json")
+		os.RemoveAll(n.bundlePath(b.Name, b.Version))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("template bundle sync errors: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// resolveBundle picks the bundle a job asked for. An empty name selects the
+// bundle the backend marked as default; an empty version selects the only
+// version stored for that name. ok is false when no bundle applies and the
+// job should fall back to the public templates fetched at Init.
+func (n *NucleiScanner) resolveBundle(name, version string) (vulnscan.TemplateBundle, bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if name == "" {
+		for _, b := range n.bundles {
+			if b.Default {
+				return b, true, nil
+			}
+		}
+		return vulnscan.TemplateBundle{}, false, nil
+	}
+
+	if version != "" {
+		b, ok := n.bundles[bundleKey(name, version)]
+		if !ok {
+			return b, false, fmt.Errorf("template bundle %s is not installed", bundleKey(name, version))
+		}
+		return b, true, nil
+	}
+
+	var found []vulnscan.TemplateBundle
+	for _, b := range n.bundles {
+		if b.Name == name {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return vulnscan.TemplateBundle{}, false, fmt.Errorf("template bundle %s is not installed", name)
+	case 1:
+		return found[0], true, nil
+	}
+	for _, b := range found {
+		if b.Default {
+			return b, true, nil
+		}
+	}
+	return vulnscan.TemplateBundle{}, false, fmt.Errorf("template bundle %s has several versions installed; specify template_version", name)
+}
+
+func (n *NucleiScanner) installBundle(b vulnscan.TemplateBundle) error {
+	if b.URL == "" {
+		return fmt.Errorf("bundle has no download URL")
+	}
+	if b.SHA256 == "" {
+		return fmt.Errorf("bundle has no sha256 checksum")
+	}
+
+	archive, err := downloadToTemp(b.URL)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+
+	sum, err := fileSHA256(archive)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, b.SHA256) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", b.SHA256, sum)
+	}
+
+	parent := filepath.Join(n.bundlesRoot(), b.Name)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create bundle dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+b.Version+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging dir: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractTemplates(archive, staging); err != nil {
+		return err
+	}
+
+	if !b.FirstParty {
+		unsigned, err := unsignedTemplates(staging)
+		if err != nil {
+			return err
+		}
+		if len(unsigned) > 0 {
+			return fmt.Errorf("%d templates are not signed (e.g. %s)", len(unsigned), unsigned[0])
+		}
+	}
+
+	marker, err := json.MarshalIndent(storedBundle{TemplateBundle: b, SyncedAt: time.Now().Format(time.RFC3339)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(staging+".json", marker, 0644); err != nil {
+		return fmt.Errorf("failed to write bundle metadata: %w", err)
+	}
+	defer os.Remove(staging + ".json")
+
+	// A previous copy of this version stays usable until the new one is in
+	// place, and is restored if the swap fails.
+	dest := n.bundlePath(b.Name, b.Version)
+	previous := ""
+	if _, err := os.Stat(dest); err == nil {
+		previous = staging + "-previous"
+		if err := os.Rename(dest, previous); err != nil {
+			return fmt.Errorf("failed to move previous bundle aside: %w", err)
+		}
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		if previous != "" {
+			os.Rename(previous, dest)
+		}
+		return fmt.Errorf("failed to move bundle into place: %w", err)
+	}
+	if err := os.Rename(staging+".json", dest+".json"); err != nil {
+		return fmt.Errorf("failed to write bundle metadata: %w", err)
+	}
+	if previous != "" {
+		os.RemoveAll(previous)
+	}
+
+	n.mu.Lock()
+	n.bundles[bundleKey(b.Name, b.Version)] = b
+	n.mu.Unlock()
+	return nil
+}
+
+func bundleKey(name, version string) string {
+	return name + "@" + version
+}
+
+func downloadToTemp(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download bundle: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download bundle: HTTP %d", resp.StatusCode)
+	}
+
+	tmpFile, err := os.CreateTemp("", "nuclei-bundle-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	if _, err := io.Copy(tmpFile, resp.Body); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return tmpFile.Name(), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extractTemplates unpacks a .tar.gz bundle into dest, skipping anything that
+// is not a regular file or directory and rejecting entries that would escape
+// dest.
+func extractTemplates(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		target := filepath.Join(dest, filepath.Clean("/"+header.Name))
+		if !strings.HasPrefix(target, dest+string(os.PathSeparator)) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+	}
+}
+
+// unsignedTemplates lists templates without a nuclei "# digest:" signature
+// line. The signature itself is checked by nuclei at run time; this rejects
+// obviously unsigned bundles before they are ever stored.
+func unsignedTemplates(dir string) ([]string, error) {
+	var unsigned []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		signed, err := hasDigest(path)
+		if err != nil {
+			return err
+		}
+		if !signed {
+			rel, _ := filepath.Rel(dir, path)
+			unsigned = append(unsigned, rel)
+		}
+		return nil
+	})
+	return unsigned, err
+}
+
+func hasDigest(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "# digest: ") {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -7,10 +7,11 @@ import (
 type ScanType string
 
 type ScanJob struct {
-	ID      string            `json:"id"`
-	Tool    string            `json:"tool"`
-	Targets []string          `json:"targets"`
-	Options map[string]string `json:"options"`
+	ID       string            `json:"id"`
+	Tool     string            `json:"tool"`
+	ScanType ScanType          `json:"scan_type,omitempty"`
+	Targets  []string          `json:"targets"`
+	Options  map[string]string `json:"options"`
 }
 
 type ScanResult struct {
@@ -31,6 +32,8 @@ type NormalizedFinding struct {
 	References    []string               `json:"references"`
 	CVEs          []string               `json:"cves"`
 	CWEs          []string               `json:"cwes"`
+	CVSSScore     float64                `json:"cvss_score,omitempty"`
+	CVSSVector    string                 `json:"cvss_vector,omitempty"`
 	AffectedAsset string                 `json:"affected_asset"`
 	Metadata      map[string]interface{} `json:"metadata"`
 }
@@ -38,12 +41,64 @@ type NormalizedFinding struct {
 type PluginConfig struct {
 	BinDir      string `json:"bin_dir"`
 	TemplateDir string `json:"template_dir"`
+	CacheDir    string `json:"cache_dir"`
+}
+
+// OptionType is the value type of a ScanJob option. Every option travels as a
+// string; list values are comma-separated.
+type OptionType string
+
+const (
+	OptionString OptionType = "string"
+	OptionBool   OptionType = "bool"
+	OptionInt    OptionType = "int"
+	OptionEnum   OptionType = "enum"
+	OptionList   OptionType = "list"
+)
+
+// OptionSpec documents one ScanJob option a plugin understands.
+type OptionSpec struct {
+	Name        string     `json:"name"`
+	Type        OptionType `json:"type"`
+	Description string     `json:"description"`
+	Values      []string   `json:"values,omitempty"` // allowed values for enum options
+	Default     string     `json:"default,omitempty"`
+}
+
+// PluginSchema is the machine-readable description of a plugin that the
+// backend uses to build valid jobs.
+type PluginSchema struct {
+	Name      string       `json:"name"`
+	ScanTypes []ScanType   `json:"scan_types"`
+	Options   []OptionSpec `json:"options"`
 }
 
 type ScannerPlugin interface {
 	Init(config PluginConfig) error
 	Capabilities() []ScanType
+	OptionSchema() []OptionSpec
 	Execute(ctx context.Context, job ScanJob) (ScanResult, error)
 	Normalize(rawOutput []byte) ([]NormalizedFinding, error)
 	Cleanup() error
 }
+
+// TemplateBundle describes a versioned set of scanner templates distributed by
+// the backend. FirstParty bundles are built and shipped by us; anything else
+// must pass the scanner's own signature verification before it is run.
+type TemplateBundle struct {
+	Tool       string `json:"tool"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	URL        string `json:"url"`
+	SHA256     string `json:"sha256"`
+	FirstParty bool   `json:"first_party"`
+	Default    bool   `json:"default"`
+}
+
+// TemplateSyncer is implemented by plugins that can run backend-managed
+// template bundles. SyncTemplates receives every bundle addressed to the
+// plugin and is expected to download, verify and store missing versions and
+// drop versions that are no longer listed.
+type TemplateSyncer interface {
+	SyncTemplates(bundles []TemplateBundle) error
+}
@@ -0,0 +1,126 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"snapsec-agent/internal/config"
+	"snapsec-agent/internal/vulnscan"
+	"snapsec-agent/internal/vulnscan/eol"
+	"snapsec-agent/internal/vulnscan/java"
+	"snapsec-agent/internal/vulnscan/nuclei"
+	"snapsec-agent/internal/vulnscan/trivy"
+)
+
+// factories maps the plugin names used in config and scan jobs to their
+// constructors. New scanner plugins are added here.
+var factories = map[string]func() vulnscan.ScannerPlugin{
+	"eol":    func() vulnscan.ScannerPlugin { return &eol.EOLScanner{} },
+	"java":   func() vulnscan.ScannerPlugin { return &java.JavaScanner{} },
+	"nuclei": func() vulnscan.ScannerPlugin { return &nuclei.NucleiScanner{} },
+	"trivy":  func() vulnscan.ScannerPlugin { return &trivy.TrivyScanner{} },
+}
+
+// New returns a fresh, uninitialized instance of the named plugin.
+func New(name string) (vulnscan.ScannerPlugin, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scanner plugin %q", name)
+	}
+	return factory(), nil
+}
+
+// Names lists the available plugins in a stable order.
+func Names() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply brings the manager's plugins, plugin options and scan profiles in line
+// with the scanner config: enabled plugins are initialized, disabled or
+// removed ones are cleaned up. Options and profiles are validated against the
+// plugin's schema and invalid ones are skipped. Initialization failures are
+// recorded in the manager's plugin status; all problems are returned together
+// as one error.
+func Apply(m *vulnscan.ScanManager, scanners []config.ScannerConfig) error {
+	enabled := make(map[string]bool)
+	var profiles []vulnscan.ScanProfile
+	var errs []string
+
+	for _, sc := range scanners {
+		if !sc.Enabled {
+			continue
+		}
+		enabled[sc.Name] = true
+
+		if !m.HasPlugin(sc.Name) {
+			plugin, err := New(sc.Name)
+			if err == nil {
+				err = m.RegisterPlugin(sc.Name, plugin)
+			}
+			if err != nil {
+				log.Printf("Failed to initialize %s plugin: %v", sc.Name, err)
+				errs = append(errs, fmt.Sprintf("%s: %v", sc.Name, err))
+				continue
+			}
+		}
+
+		if err := m.ValidateJob(vulnscan.ScanJob{Tool: sc.Name, Options: sc.Options}); err != nil {
+			errs = append(errs, fmt.Sprintf("%s options: %v", sc.Name, err))
+			continue
+		}
+		m.SetPluginOptions(sc.Name, sc.Options)
+
+		for _, p := range sc.Profiles {
+			profile := toScanProfile(sc.Name, p)
+			if err := m.ValidateJob(vulnscan.ScanJob{Tool: sc.Name, Options: profile.Options}); err != nil {
+				log.Printf("Skipping scan profile %s: %v", p.Name, err)
+				errs = append(errs, fmt.Sprintf("%s profile %s: %v", sc.Name, p.Name, err))
+				continue
+			}
+			profiles = append(profiles, profile)
+		}
+	}
+
+	for _, s := range m.PluginStatuses() {
+		if s.Initialized && !enabled[s.Name] {
+			log.Printf("Disabling %s plugin", s.Name)
+			m.UnregisterPlugin(s.Name)
+		}
+	}
+
+	m.SetProfiles(profiles)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("scanner plugin configuration errors: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func toScanProfile(tool string, p config.ScanProfile) vulnscan.ScanProfile {
+	options := make(map[string]string)
+	for k, v := range p.Options {
+		options[k] = v
+	}
+	if p.Mode != "" {
+		options["mode"] = p.Mode
+	}
+	if p.Tags != "" {
+		options["tags"] = p.Tags
+	}
+
+	return vulnscan.ScanProfile{
+		Name:     p.Name,
+		Tool:     tool,
+		Targets:  p.Targets,
+		Interval: time.Duration(p.Interval) * time.Second,
+		Options:  options,
+	}
+}
@@ -0,0 +1,116 @@
+package vulnscan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SchemaOf builds the published schema of a plugin.
+func SchemaOf(name string, plugin ScannerPlugin) PluginSchema {
+	return PluginSchema{
+		Name:      name,
+		ScanTypes: plugin.Capabilities(),
+		Options:   plugin.OptionSchema(),
+	}
+}
+
+// HasOption reports whether the schema declares the named option.
+func (s PluginSchema) HasOption(name string) bool {
+	for _, o := range s.Options {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks a job against the schema: the scan type, when set, must be
+// one the plugin supports, and every option must be declared and hold a value
+// of the declared type.
+func (s PluginSchema) Validate(job ScanJob) error {
+	if job.ScanType != "" {
+		supported := false
+		for _, t := range s.ScanTypes {
+			if t == job.ScanType {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return fmt.Errorf("scan type %q is not supported by %s", job.ScanType, s.Name)
+		}
+	}
+
+	specs := make(map[string]OptionSpec, len(s.Options))
+	for _, o := range s.Options {
+		specs[o.Name] = o
+	}
+
+	var errs []string
+	for name, value := range job.Options {
+		spec, ok := specs[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown option %q", name))
+			continue
+		}
+		if err := checkValue(spec, value); err != nil {
+			errs = append(errs, fmt.Sprintf("option %q: %v", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid %s job: %s", s.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Normalize rewrites the options of a validated job to their canonical
+// form, so plugins can compare booleans with "true" whatever spelling
+// strconv.ParseBool accepted ("1", "t", "TRUE").
+func (s PluginSchema) Normalize(options map[string]string) map[string]string {
+	out := make(map[string]string, len(options))
+	for name, value := range options {
+		out[name] = value
+		for _, o := range s.Options {
+			if o.Name != name || o.Type != OptionBool {
+				continue
+			}
+			if b, err := strconv.ParseBool(value); err == nil {
+				out[name] = strconv.FormatBool(b)
+			}
+		}
+	}
+	return out
+}
+
+func checkValue(spec OptionSpec, value string) error {
+	switch spec.Type {
+	case OptionBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	case OptionInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case OptionEnum:
+		for _, v := range spec.Values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(spec.Values, ", "))
+	case OptionList:
+		// Lists of enum values are validated element by element.
+		if len(spec.Values) == 0 {
+			return nil
+		}
+		for _, item := range strings.Split(value, ",") {
+			if err := checkValue(OptionSpec{Type: OptionEnum, Values: spec.Values}, strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package trivy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"snapsec-agent/internal/modules/containers"
+	"snapsec-agent/internal/vulnscan"
+)
+
+// localImagesMode scans every image held by the local container runtimes
+// instead of job.Targets. Images are read from the runtime (trivy's
+// --image-src), never pulled from a registry.
+const localImagesMode = "local-images"
+
+// defaultImageCacheTTL bounds how long findings for an unchanged image are
+// reused. Images do not change, but the vulnerability database does.
+const defaultImageCacheTTL = 24 * time.Hour
+
+type imageCacheEntry struct {
+	ScannedAt time.Time                    `json:"scanned_at"`
+	Findings  []vulnscan.NormalizedFinding `json:"findings"`
+}
+
+// localImage is one image to scan together with the containers using it.
+type localImage struct {
+	containers.ImageInfo
+	Ref        string
+	Endpoint   string
+	Containers []string
+}
+
+func (t *TrivyScanner) imageCachePath() string {
+	return filepath.Join(t.config.CacheDir, "trivy-images.json")
+}
+
+func (t *TrivyScanner) loadImageCache() map[string]imageCacheEntry {
+	cache := make(map[string]imageCacheEntry)
+	data, err := os.ReadFile(t.imageCachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Printf("Ignoring unreadable trivy image cache: %v", err)
+		return make(map[string]imageCacheEntry)
+	}
+	return cache
+}
+
+func (t *TrivyScanner) saveImageCache(cache map[string]imageCacheEntry) error {
+	if err := os.MkdirAll(t.config.CacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.imageCachePath(), data, 0600)
+}
+
+// scanLocalImages scans each local image that has no fresh cache entry and
+// returns findings for all images, attributed to the image digest and the
+// containers currently using it.
+func (t *TrivyScanner) scanLocalImages(ctx context.Context, options map[string]string) ([]vulnscan.NormalizedFinding, error) {
+	gathered, err := (&containers.ContainersModule{}).Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list container images: %w", err)
+	}
+	images := localImages(gathered.(containers.ContainersData))
+	if len(images) == 0 {
+		log.Println("No local container images found")
+		return nil, nil
+	}
+
+	ttl := defaultImageCacheTTL
+	if v, ok := options["image_cache_ttl"]; ok && v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			ttl = time.Duration(secs) * time.Second
+		}
+	}
+
+	t.imageMu.Lock()
+	defer t.imageMu.Unlock()
+
+	oldCache := t.loadImageCache()
+	cache := make(map[string]imageCacheEntry)
+	var findings []vulnscan.NormalizedFinding
+
+	for _, img := range images {
+		entry, ok := oldCache[img.ID]
+		if !ok || time.Since(entry.ScannedAt) > ttl {
+			raw, err := t.scanImage(ctx, img, options)
+			if err == nil {
+				var fresh []vulnscan.NormalizedFinding
+				if fresh, err = t.Normalize(raw); err == nil {
+					entry = imageCacheEntry{ScannedAt: time.Now(), Findings: fresh}
+					ok = true
+				}
+			}
+			if err != nil {
+				log.Printf("Trivy scan of image %s failed: %v", img.Ref, err)
+				// Keep reporting the last known result for this image.
+				if !ok {
+					continue
+				}
+			}
+		}
+
+		// Only images still present are kept, which prunes removed ones.
+		cache[img.ID] = entry
+		findings = append(findings, attributeToImage(entry.Findings, img)...)
+	}
+
+	if err := t.saveImageCache(cache); err != nil {
+		log.Printf("Failed to save trivy image cache: %v", err)
+	}
+	return findings, nil
+}
+
+func (t *TrivyScanner) scanImage(ctx context.Context, img localImage, options map[string]string) ([]byte, error) {
+	args := []string{"image", "--image-src", img.Runtime}
+
+	var env []string
+	switch img.Runtime {
+	case "docker":
+		if img.Endpoint != "" {
+			env = append(env, "DOCKER_HOST=unix://"+img.Endpoint)
+		}
+	case "containerd":
+		env = append(env, "CONTAINERD_NAMESPACE="+img.Namespace)
+	}
+
+	log.Printf("Scanning container image %s (%s)", img.Ref, img.Runtime)
+	return t.run(ctx, args, img.Ref, env, options)
+}
+
+// localImages pairs each image with the containers that use it and picks the
+// reference trivy should resolve it by.
+func localImages(data containers.ContainersData) []localImage {
+	endpoints := make(map[string]string)
+	for _, rt := range data.Runtimes {
+		if rt.Error == "" {
+			endpoints[rt.Name] = rt.Endpoint
+		}
+	}
+
+	users := make(map[string][]string)
+	for _, c := range data.Containers {
+		key := c.ImageID
+		if key == "" {
+			key = c.ImageDigest
+		}
+		users[c.Runtime+"/"+key] = append(users[c.Runtime+"/"+key], c.Name)
+	}
+
+	var images []localImage
+	seen := make(map[string]bool)
+	for _, img := range data.Images {
+		// An image known to several runtimes is scanned once.
+		if seen[img.ID] {
+			continue
+		}
+		seen[img.ID] = true
+
+		li := localImage{ImageInfo: img, Ref: img.ID}
+		if len(img.RepoTags) > 0 {
+			li.Ref = img.RepoTags[0]
+		} else if len(img.RepoDigests) > 0 {
+			li.Ref = img.RepoDigests[0]
+		}
+		li.Endpoint = endpoints[img.Runtime]
+		li.Containers = users[img.Runtime+"/"+img.ID]
+		sort.Strings(li.Containers)
+		images = append(images, li)
+	}
+	return images
+}
+
+// attributeToImage rewrites findings from an image scan so the affected asset
+// is the image digest and the metadata names the containers running it.
+func attributeToImage(cached []vulnscan.NormalizedFinding, img localImage) []vulnscan.NormalizedFinding {
+	digest := img.ID
+	if len(img.RepoDigests) > 0 {
+		if i := strings.Index(img.RepoDigests[0], "@"); i >= 0 {
+			digest = img.RepoDigests[0][i+1:]
+		}
+	}
+	asset := img.Ref + "@" + digest
+	if strings.Contains(img.Ref, "@") {
+		asset = img.Ref
+	}
+
+	out := make([]vulnscan.NormalizedFinding, 0, len(cached))
+	for _, f := range cached {
+		metadata := make(map[string]interface{}, len(f.Metadata)+5)
+		for k, v := range f.Metadata {
+			metadata[k] = v
+		}
+		metadata["image_ref"] = img.Ref
+		metadata["image_id"] = img.ID
+		metadata["image_digest"] = digest
+		metadata["runtime"] = img.Runtime
+		metadata["containers"] = img.Containers
+		metadata["trivy_target"] = f.AffectedAsset
+
+		f.Metadata = metadata
+		f.FindingID = f.FindingID + "-" + shortID(img.ID)
+		f.AffectedAsset = asset
+		out = append(out, f)
+	}
+	return out
+}
+
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
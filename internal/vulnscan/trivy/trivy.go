@@ -3,6 +3,7 @@ package trivy
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
@@ -14,7 +15,9 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"snapsec-agent/internal/vulnscan"
 )
@@ -22,6 +25,7 @@ import (
 type TrivyScanner struct {
 	config  vulnscan.PluginConfig
 	binPath string
+	imageMu sync.Mutex // serializes local image scans and their cache file
 }
 
 func (t *TrivyScanner) Init(config vulnscan.PluginConfig) error {
@@ -193,60 +197,214 @@ func (t *TrivyScanner) Capabilities() []vulnscan.ScanType {
 	return []vulnscan.ScanType{"container", "fs", "repository"}
 }
 
+func (t *TrivyScanner) OptionSchema() []vulnscan.OptionSpec {
+	return []vulnscan.OptionSpec{
+		{Name: "mode", Type: vulnscan.OptionEnum, Description: "Trivy target type",
+			Values: []string{"fs", "rootfs", "image", "repo", localImagesMode}, Default: "fs"},
+		{Name: "scanners", Type: vulnscan.OptionList, Description: "Comma-separated trivy scanners to enable",
+			Values: []string{"vuln", "secret", "misconfig", "license"}, Default: defaultScanners},
+		{Name: "excludes", Type: vulnscan.OptionList, Description: "Comma-separated directories to skip"},
+		{Name: "image_cache_ttl", Type: vulnscan.OptionInt, Description: "Seconds to reuse findings for an unchanged local image (local-images mode)", Default: "86400"},
+		{Name: "verbose", Type: vulnscan.OptionBool, Description: "Stream trivy progress to the agent's stderr", Default: "false"},
+	}
+}
+
+// defaultScanners is used when a job does not set the "scanners" option.
+// License scanning is opt-in because it is slow on large filesystems.
+const defaultScanners = "vuln,secret,misconfig"
+
 func (t *TrivyScanner) Execute(ctx context.Context, job vulnscan.ScanJob) (vulnscan.ScanResult, error) {
 	result := vulnscan.ScanResult{JobID: job.ID}
 
+	mode := "fs"
+	if m, ok := job.Options["mode"]; ok && m != "" {
+		mode = m
+	}
+
+	if mode == localImagesMode {
+		findings, err := t.scanLocalImages(ctx, job.Options)
+		if err != nil {
+			return result, err
+		}
+		result.Findings = findings
+		return result, nil
+	}
+
+	// Local images are found on the host; every other mode needs targets.
 	if len(job.Targets) == 0 {
 		return result, fmt.Errorf("no targets specified for trivy scan")
 	}
 
-	mode := "fs"
-	if m, ok := job.Options["mode"]; ok && m != "" {
-		mode = m
+	// Trivy takes one target per invocation, so each target is scanned in turn
+	// and the findings are merged. A failing target does not abort the others.
+	var failed []string
+	for _, target := range job.Targets {
+		rawOutput, err := t.scanTarget(ctx, mode, target, job.Options)
+		if err != nil {
+			log.Printf("Trivy scan of %s failed: %v", target, err)
+			failed = append(failed, target)
+			continue
+		}
+
+		findings, err := t.Normalize(rawOutput)
+		if err != nil {
+			log.Printf("Failed to normalize trivy output for %s: %v", target, err)
+			failed = append(failed, target)
+			continue
+		}
+		result.Findings = append(result.Findings, findings...)
+	}
+
+	if len(failed) == len(job.Targets) {
+		return result, fmt.Errorf("trivy scan failed for all targets: %s", strings.Join(failed, ", "))
+	}
+	if len(failed) > 0 {
+		result.Error = fmt.Sprintf("trivy scan failed for targets: %s", strings.Join(failed, ", "))
 	}
 
-	target := job.Targets[0] // Trivy typically takes one target at a time.
+	return result, nil
+}
+
+// scanTarget runs trivy against a single target and returns its JSON report.
+func (t *TrivyScanner) scanTarget(ctx context.Context, mode, target string, options map[string]string) ([]byte, error) {
+	args := []string{mode}
+
+	if ex, ok := options["excludes"]; ok && ex != "" {
+		for _, e := range strings.Split(ex, ",") {
+			args = append(args, "--skip-dirs", strings.TrimSpace(e))
+		}
+	}
 
+	return t.run(ctx, args, target, nil, options)
+}
+
+// run executes trivy with the given subcommand and flags plus the common
+// output and scanner flags against target, and returns the JSON report.
+func (t *TrivyScanner) run(ctx context.Context, args []string, target string, env []string, options map[string]string) ([]byte, error) {
 	outputFile, err := os.CreateTemp("", "trivy-output-*.json")
 	if err != nil {
-		return result, fmt.Errorf("failed to create output file: %w", err)
+		return nil, fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer os.Remove(outputFile.Name())
 	outputFile.Close()
 
-	args := []string{
-		mode,
+	scanners := defaultScanners
+	if s, ok := options["scanners"]; ok && s != "" {
+		scanners = s
+	}
+
+	args = append(args,
 		"-f", "json",
 		"-o", outputFile.Name(),
-		"--quiet", // To prevent pollution in stderr
-	}
+		"--scanners", scanners,
+	)
 
-	if ex, ok := job.Options["excludes"]; ok && ex != "" {
-		for _, e := range strings.Split(ex, ",") {
-			args = append(args, "--skip-dirs", strings.TrimSpace(e))
-		}
+	isVerbose := options["verbose"] == "true"
+	if !isVerbose {
+		args = append(args, "--quiet") // To prevent pollution in stderr
 	}
 
 	args = append(args, target)
 
 	cmd := exec.CommandContext(ctx, t.binPath, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	if isVerbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
 
+	// Trivy exits zero when vulnerabilities are found (no --exit-code is
+	// passed), so a non-zero status means the scan itself failed.
 	if err := cmd.Run(); err != nil {
-		log.Printf("Trivy execution finished with error (expected if vulns found): %v", err)
+		return nil, fmt.Errorf("trivy %s failed: %w", target, err)
 	}
 
 	rawOutput, err := os.ReadFile(outputFile.Name())
 	if err != nil {
-		return result, fmt.Errorf("failed to read trivy output: %w", err)
+		return nil, fmt.Errorf("failed to read trivy output: %w", err)
 	}
-
-	findings, err := t.Normalize(rawOutput)
-	if err != nil {
-		return result, fmt.Errorf("failed to normalize trivy output: %w", err)
+	if len(bytes.TrimSpace(rawOutput)) == 0 {
+		return nil, fmt.Errorf("trivy %s produced no report", target)
 	}
+	return rawOutput, nil
+}
 
-	result.Findings = findings
-	return result, nil
+type trivyCVSS struct {
+	V2Vector string  `json:"V2Vector"`
+	V3Vector string  `json:"V3Vector"`
+	V2Score  float64 `json:"V2Score"`
+	V3Score  float64 `json:"V3Score"`
+}
+
+type trivyCauseMetadata struct {
+	Resource  string `json:"Resource"`
+	Provider  string `json:"Provider"`
+	Service   string `json:"Service"`
+	StartLine int    `json:"StartLine"`
+	EndLine   int    `json:"EndLine"`
+}
+
+type trivyReport struct {
+	Results []struct {
+		Target          string `json:"Target"`
+		Class           string `json:"Class"`
+		Type            string `json:"Type"`
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			PkgPath         string `json:"PkgPath"`
+			PkgIdentifier   struct {
+				PURL string `json:"PURL"`
+			} `json:"PkgIdentifier"`
+			InstalledVersion string               `json:"InstalledVersion"`
+			FixedVersion     string               `json:"FixedVersion"`
+			Title            string               `json:"Title"`
+			Description      string               `json:"Description"`
+			Severity         string               `json:"Severity"`
+			SeveritySource   string               `json:"SeveritySource"`
+			PrimaryURL       string               `json:"PrimaryURL"`
+			References       []string             `json:"References"`
+			CweIDs           []string             `json:"CweIDs"`
+			CVSS             map[string]trivyCVSS `json:"CVSS"`
+			PublishedDate    string               `json:"PublishedDate"`
+			LastModifiedDate string               `json:"LastModifiedDate"`
+		} `json:"Vulnerabilities"`
+		Misconfigurations []struct {
+			Type          string             `json:"Type"`
+			ID            string             `json:"ID"`
+			AVDID         string             `json:"AVDID"`
+			Title         string             `json:"Title"`
+			Description   string             `json:"Description"`
+			Message       string             `json:"Message"`
+			Resolution    string             `json:"Resolution"`
+			Severity      string             `json:"Severity"`
+			PrimaryURL    string             `json:"PrimaryURL"`
+			References    []string           `json:"References"`
+			Status        string             `json:"Status"`
+			CauseMetadata trivyCauseMetadata `json:"CauseMetadata"`
+		} `json:"Misconfigurations"`
+		Secrets []struct {
+			RuleID    string `json:"RuleID"`
+			Category  string `json:"Category"`
+			Severity  string `json:"Severity"`
+			Title     string `json:"Title"`
+			StartLine int    `json:"StartLine"`
+			EndLine   int    `json:"EndLine"`
+			Match     string `json:"Match"`
+		} `json:"Secrets"`
+		Licenses []struct {
+			Severity   string  `json:"Severity"`
+			Category   string  `json:"Category"`
+			PkgName    string  `json:"PkgName"`
+			FilePath   string  `json:"FilePath"`
+			Name       string  `json:"Name"`
+			Confidence float64 `json:"Confidence"`
+			Link       string  `json:"Link"`
+		} `json:"Licenses"`
+	} `json:"Results"`
 }
 
 func (t *TrivyScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFinding, error) {
@@ -255,71 +413,242 @@ func (t *TrivyScanner) Normalize(rawOutput []byte) ([]vulnscan.NormalizedFinding
 		return findings, nil
 	}
 
-	var trivyReport struct {
-		Results []struct {
-			Target          string `json:"Target"`
-			Class           string `json:"Class"`
-			Type            string `json:"Type"`
-			Vulnerabilities []struct {
-				VulnerabilityID  string   `json:"VulnerabilityID"`
-				PkgName          string   `json:"PkgName"`
-				InstalledVersion string   `json:"InstalledVersion"`
-				FixedVersion     string   `json:"FixedVersion"`
-				Title            string   `json:"Title"`
-				Description      string   `json:"Description"`
-				Severity         string   `json:"Severity"`
-				References       []string `json:"References"`
-			} `json:"Vulnerabilities"`
-		} `json:"Results"`
-	}
-
-	if err := json.Unmarshal(rawOutput, &trivyReport); err != nil {
+	var report trivyReport
+	if err := json.Unmarshal(rawOutput, &report); err != nil {
 		return nil, fmt.Errorf("failed to parse trivy JSON output: %w", err)
 	}
 
-	for _, res := range trivyReport.Results {
+	for _, res := range report.Results {
 		for _, vuln := range res.Vulnerabilities {
-			severity := strings.ToLower(vuln.Severity)
-			if severity == "" || severity == "unknown" {
-				severity = "info"
-			}
-
 			remediation := ""
 			if vuln.FixedVersion != "" {
 				remediation = fmt.Sprintf("Upgrade %s to %s", vuln.PkgName, vuln.FixedVersion)
 			}
 
+			var cves []string
+			if strings.HasPrefix(vuln.VulnerabilityID, "CVE-") {
+				cves = []string{vuln.VulnerabilityID}
+			}
+
 			finding := vulnscan.NormalizedFinding{
 				FindingID:     fmt.Sprintf("trivy-%s-%s", vuln.VulnerabilityID, vuln.PkgName),
 				Scanner:       "trivy",
-				Category:      res.Class,
+				Category:      "vulnerability",
 				Title:         vuln.Title,
-				Severity:      severity,
+				Severity:      normalizeSeverity(vuln.Severity),
 				Description:   vuln.Description,
 				Evidence:      fmt.Sprintf("Found %s (version %s) in %s", vuln.VulnerabilityID, vuln.InstalledVersion, res.Target),
 				Remediation:   remediation,
-				References:    vuln.References,
-				CVEs:          []string{vuln.VulnerabilityID},
+				References:    withPrimaryURL(vuln.PrimaryURL, vuln.References),
+				CVEs:          cves,
+				CWEs:          vuln.CweIDs,
 				AffectedAsset: res.Target,
 				Metadata: map[string]interface{}{
+					"vulnerability_id":  vuln.VulnerabilityID,
 					"pkg_name":          vuln.PkgName,
 					"installed_version": vuln.InstalledVersion,
 					"fixed_version":     vuln.FixedVersion,
+					"class":             res.Class,
 					"type":              res.Type,
 				},
 			}
-			
+
+			if source, cvss, ok := pickCVSS(vuln.CVSS, vuln.SeveritySource); ok {
+				if cvss.V3Vector != "" {
+					finding.CVSSVector = cvss.V3Vector
+					finding.CVSSScore = cvss.V3Score
+				} else {
+					finding.CVSSVector = cvss.V2Vector
+					finding.CVSSScore = cvss.V2Score
+				}
+				finding.Metadata["cvss_source"] = source
+			}
+			if vuln.PkgPath != "" {
+				finding.Metadata["file_path"] = vuln.PkgPath
+			}
+			if vuln.PkgIdentifier.PURL != "" {
+				finding.Metadata["purl"] = vuln.PkgIdentifier.PURL
+			}
+			if vuln.SeveritySource != "" {
+				finding.Metadata["severity_source"] = vuln.SeveritySource
+			}
+			if vuln.PublishedDate != "" {
+				finding.Metadata["published_date"] = vuln.PublishedDate
+			}
+			if vuln.LastModifiedDate != "" {
+				finding.Metadata["last_modified_date"] = vuln.LastModifiedDate
+			}
+
 			if finding.Title == "" {
 				finding.Title = vuln.VulnerabilityID
 			}
 
 			findings = append(findings, finding)
 		}
+
+		for _, mc := range res.Misconfigurations {
+			// Trivy also reports passing checks when asked to; only failures are findings.
+			if mc.Status != "" && mc.Status != "FAIL" {
+				continue
+			}
+
+			id := mc.AVDID
+			if id == "" {
+				id = mc.ID
+			}
+
+			evidence := mc.Message
+			if mc.CauseMetadata.StartLine > 0 {
+				evidence = fmt.Sprintf("%s (%s:%d-%d)", mc.Message, res.Target, mc.CauseMetadata.StartLine, mc.CauseMetadata.EndLine)
+			}
+
+			finding := vulnscan.NormalizedFinding{
+				FindingID:     fmt.Sprintf("trivy-%s-%s-%d", id, res.Target, mc.CauseMetadata.StartLine),
+				Scanner:       "trivy",
+				Category:      "misconfiguration",
+				Title:         mc.Title,
+				Severity:      normalizeSeverity(mc.Severity),
+				Description:   mc.Description,
+				Evidence:      evidence,
+				Remediation:   mc.Resolution,
+				References:    withPrimaryURL(mc.PrimaryURL, mc.References),
+				AffectedAsset: res.Target,
+				Metadata: map[string]interface{}{
+					"check_id":    mc.ID,
+					"avd_id":      mc.AVDID,
+					"config_type": mc.Type,
+					"file_path":   res.Target,
+					"start_line":  mc.CauseMetadata.StartLine,
+					"end_line":    mc.CauseMetadata.EndLine,
+				},
+			}
+			if mc.CauseMetadata.Resource != "" {
+				finding.Metadata["resource"] = mc.CauseMetadata.Resource
+			}
+			if mc.CauseMetadata.Provider != "" {
+				finding.Metadata["provider"] = mc.CauseMetadata.Provider
+				finding.Metadata["service"] = mc.CauseMetadata.Service
+			}
+			if finding.Title == "" {
+				finding.Title = id
+			}
+
+			findings = append(findings, finding)
+		}
+
+		for _, secret := range res.Secrets {
+			// Match is already redacted by trivy, so it is safe to forward as evidence.
+			finding := vulnscan.NormalizedFinding{
+				FindingID:     fmt.Sprintf("trivy-%s-%s-%d", secret.RuleID, res.Target, secret.StartLine),
+				Scanner:       "trivy",
+				Category:      "secret",
+				Title:         secret.Title,
+				Severity:      normalizeSeverity(secret.Severity),
+				Description:   fmt.Sprintf("%s secret detected in %s", secret.Category, res.Target),
+				Evidence:      secret.Match,
+				Remediation:   "Remove the secret from the file and rotate the exposed credential",
+				AffectedAsset: res.Target,
+				Metadata: map[string]interface{}{
+					"rule_id":         secret.RuleID,
+					"secret_category": secret.Category,
+					"file_path":       res.Target,
+					"start_line":      secret.StartLine,
+					"end_line":        secret.EndLine,
+				},
+			}
+			if finding.Title == "" {
+				finding.Title = secret.RuleID
+			}
+
+			findings = append(findings, finding)
+		}
+
+		for _, lic := range res.Licenses {
+			filePath := lic.FilePath
+			if filePath == "" {
+				filePath = res.Target
+			}
+
+			title := fmt.Sprintf("%s license", lic.Name)
+			if lic.PkgName != "" {
+				title = fmt.Sprintf("%s license in %s", lic.Name, lic.PkgName)
+			}
+
+			finding := vulnscan.NormalizedFinding{
+				FindingID:     fmt.Sprintf("trivy-license-%s-%s-%s", lic.Name, lic.PkgName, filePath),
+				Scanner:       "trivy",
+				Category:      "license",
+				Title:         title,
+				Severity:      normalizeSeverity(lic.Severity),
+				Description:   fmt.Sprintf("License %s (%s) detected", lic.Name, lic.Category),
+				Evidence:      fmt.Sprintf("Found %s license in %s", lic.Name, filePath),
+				AffectedAsset: res.Target,
+				Metadata: map[string]interface{}{
+					"license":          lic.Name,
+					"license_category": lic.Category,
+					"pkg_name":         lic.PkgName,
+					"file_path":        filePath,
+					"confidence":       lic.Confidence,
+				},
+			}
+			if lic.Link != "" {
+				finding.References = []string{lic.Link}
+			}
+
+			findings = append(findings, finding)
+		}
 	}
 
 	return findings, nil
 }
 
+// normalizeSeverity lowercases trivy's severity and maps UNKNOWN to info.
+func normalizeSeverity(severity string) string {
+	severity = strings.ToLower(severity)
+	if severity == "" || severity == "unknown" {
+		return "info"
+	}
+	return severity
+}
+
+// pickCVSS chooses the CVSS entry to report: the source trivy used for the
+// severity first, then NVD, then any other vendor in a stable order.
+func pickCVSS(cvss map[string]trivyCVSS, severitySource string) (string, trivyCVSS, bool) {
+	if len(cvss) == 0 {
+		return "", trivyCVSS{}, false
+	}
+	for _, source := range []string{severitySource, "nvd"} {
+		if c, ok := cvss[source]; ok && (c.V3Vector != "" || c.V2Vector != "") {
+			return source, c, true
+		}
+	}
+	sources := make([]string, 0, len(cvss))
+	for source := range cvss {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		if c := cvss[source]; c.V3Vector != "" || c.V2Vector != "" {
+			return source, c, true
+		}
+	}
+	return "", trivyCVSS{}, false
+}
+
+// withPrimaryURL puts the advisory's primary URL first in the reference list.
+func withPrimaryURL(primary string, refs []string) []string {
+	if primary == "" {
+		return refs
+	}
+	out := []string{primary}
+	for _, r := range refs {
+		if r != primary {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
 func (t *TrivyScanner) Cleanup() error {
 	return nil
 }
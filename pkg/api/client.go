@@ -33,8 +33,9 @@ type RegisterResponse struct {
 // Register registers the agent. When inventory is non-nil it is sent under the
 // "data" key (same shape as the results push) so the backend can create the
 // workstation/server assets immediately at registration, without waiting for
-// the first scheduled asset push.
-func (c *Client) Register(hostname, os, version, ipAddress, architecture, arch string, inventory interface{}) (string, error) {
+// the first scheduled asset push. scanners carries the capability and option
+// schema of each scanner plugin so the console can build valid scan jobs.
+func (c *Client) Register(hostname, os, version, ipAddress, architecture, arch string, inventory interface{}, scanners interface{}) (string, error) {
 	data := map[string]interface{}{
 		"hostname":     hostname,
 		"os":           os,
@@ -47,6 +48,9 @@ func (c *Client) Register(hostname, os, version, ipAddress, architecture, arch s
 	if inventory != nil {
 		data["data"] = inventory
 	}
+	if scanners != nil {
+		data["scanners"] = scanners
+	}
 
 	respBody, err := c.postWithResponse("/register", data)
 	if err != nil {
@@ -91,6 +95,10 @@ type AgentConfiguration struct {
 	AssetPushInterval int                    `json:"asset_push_interval"` // in seconds
 	VulnScanInterval  int                    `json:"vuln_scan_interval,omitempty"` // in seconds
 	ScanJobs          []interface{}          `json:"scan_jobs,omitempty"`
+	TemplateBundles   []interface{}          `json:"template_bundles,omitempty"`
+	Scanners          []interface{}          `json:"scanners,omitempty"`
+	CPEMappings       interface{}            `json:"cpe_mappings,omitempty"`
+	EOLDataset        interface{}            `json:"eol_dataset,omitempty"`
 	LatestVersion     string                 `json:"latest_version"`
 	DownloadURL       string                 `json:"download_url"`
 	ScanTargets       struct {
@@ -141,6 +149,29 @@ func (c *Client) SendVulnerabilities(agentID string, findings interface{}) (*Res
 	return &res, nil
 }
 
+// SendScanResult reports the outcome of a scan job the agent could not run
+// or that failed; the result's Error holds the reason.
+func (c *Client) SendScanResult(agentID string, result interface{}) error {
+	data := map[string]interface{}{
+		"agent_id": agentID,
+		"data":     result,
+	}
+	return c.post("/scan-results", data)
+}
+
+// SendEvents ships a batch of host events. dropped is the number of events
+// the agent discarded since the previous batch because its buffer was full.
+func (c *Client) SendEvents(agentID string, events interface{}, dropped uint64) error {
+	data := map[string]interface{}{
+		"agent_id": agentID,
+		"data":     events,
+	}
+	if dropped > 0 {
+		data["dropped"] = dropped
+	}
+	return c.post("/events", data)
+}
+
 func (c *Client) post(endpoint string, data interface{}) error {
 	_, err := c.postWithResponse(endpoint, data)
 	return err